@@ -10,15 +10,46 @@ import (
 )
 
 func TestAllSpecs(t *testing.T) {
+	nanospec.Run(t, ClockSpec)
 	nanospec.Run(t, ConcurrencySpec)
+	nanospec.Run(t, ConfigSpec)
 	nanospec.Run(t, ContextSpec)
+	nanospec.Run(t, CsvFormatSpec)
+	nanospec.Run(t, DeadlineSpec)
+	nanospec.Run(t, DeterministicOutputSpec)
+	nanospec.Run(t, DiffSpec)
 	nanospec.Run(t, ExecutionModelSpec)
+	nanospec.Run(t, ExecutionOrderSpec)
 	nanospec.Run(t, ExpectationsSpec)
+	nanospec.Run(t, FailureStreamSpec)
+	nanospec.Run(t, FixturesSpec)
+	nanospec.Run(t, FlakinessSpec)
+	nanospec.Run(t, FormatsSpec)
 	nanospec.Run(t, FuncNameSpec)
+	nanospec.Run(t, GithubActionsSpec)
+	nanospec.Run(t, JsonSchemaSpec)
 	nanospec.Run(t, LocationSpec)
+	nanospec.Run(t, MainSpec)
 	nanospec.Run(t, MatcherMessagesSpec)
 	nanospec.Run(t, MatchersSpec)
+	nanospec.Run(t, MaxFailuresSpec)
 	nanospec.Run(t, PrinterSpec)
+	nanospec.Run(t, ProgressSpec)
 	nanospec.Run(t, RecoverSpec)
+	nanospec.Run(t, RegistrySpec)
+	nanospec.Run(t, ReportFileSpec)
+	nanospec.Run(t, ReportStreamSpec)
+	nanospec.Run(t, RerunSpec)
+	nanospec.Run(t, ResultDiffSpec)
+	nanospec.Run(t, ResultSinkSpec)
 	nanospec.Run(t, ResultsSpec)
+	nanospec.Run(t, SchedulerSpec)
+	nanospec.Run(t, SerialExecutionSpec)
+	nanospec.Run(t, SerialSpecSpec)
+	nanospec.Run(t, SlogCaptureSpec)
+	nanospec.Run(t, SpySpec)
+	nanospec.Run(t, StuckTasksSpec)
+	nanospec.Run(t, SummarySpec)
+	nanospec.Run(t, ValidationSpec)
+	nanospec.Run(t, WatchFormatSpec)
 }
@@ -0,0 +1,83 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The time as seen by the code under test. GoSpec cannot change what the
+// functions of the time package return, so the time can be controlled by
+// a spec only if the code under test gets the time from a clock which is
+// given to it, instead of calling time.Now directly. The code does not need
+// to depend on this interface: any interface with the methods that it uses,
+// such as “interface{ Now() time.Time }”, accepts also a FakeClock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// The Clock of the time package, for the production code.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// A Clock whose time moves only when it is advanced, so that the code which
+// uses it behaves the same way every time. It is safe to use from many
+// goroutines. See Context.Clock.
+type FakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// The time at which the clocks of the specs start. Every leaf spec starts
+// from the same time, so that the specs are repeatable.
+var fakeClockStart = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+// Moves the time forward by 'd'.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+// Advances the clock by 'd' and returns immediately,
+// so that the specs do not need to wait.
+func (clock *FakeClock) Sleep(d time.Duration) {
+	if d > 0 {
+		clock.Advance(d)
+	}
+}
+
+type clockKey struct{}
+
+// Returns the clock of the spec whose Context.Context 'ctx' is or was
+// derived from, or SystemClock for other contexts. This is a hook for code
+// which already gets a context.Context, so that it can get the clock without
+// a separate parameter. Example:
+//    func expired(ctx context.Context, deadline time.Time) bool {
+//        return gospec.ClockFromContext(ctx).Now().After(deadline)
+//    }
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return SystemClock
+}
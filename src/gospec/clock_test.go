@@ -0,0 +1,77 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"context"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+func ClockSpec(c nanospec.Context) {
+
+	c.Specify("A fake clock", func() {
+		start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewFakeClock(start)
+
+		c.Specify("does not move by itself", func() {
+			c.Expect(clock.Now()).Equals(start)
+			time.Sleep(time.Millisecond)
+			c.Expect(clock.Now()).Equals(start)
+		})
+		c.Specify("moves when it is advanced", func() {
+			clock.Advance(time.Hour)
+			c.Expect(clock.Now()).Equals(start.Add(time.Hour))
+		})
+		c.Specify("moves immediately when sleeping, without waiting", func() {
+			before := time.Now()
+			clock.Sleep(time.Hour)
+			c.Expect(clock.Now()).Equals(start.Add(time.Hour))
+			c.Expect(time.Since(before) < time.Hour).IsTrue()
+		})
+		c.Specify("does not move backwards when sleeping a negative duration", func() {
+			clock.Sleep(-time.Hour)
+			c.Expect(clock.Now()).Equals(start)
+		})
+	})
+
+	c.Specify("Specs get a fake clock for the code under test", func() {
+		r := NewRunner()
+		clocks := make(chan *FakeClock, 10)
+		times := make(chan time.Time, 10)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			parent := c.Clock()
+			c.Specify("Child A", func() {
+				c.Expect(c.Clock() == parent, IsTrue)
+				c.Expect(ClockFromContext(c.Context()) == Clock(parent), IsTrue)
+				times <- c.Clock().Now()
+				c.Clock().Advance(time.Hour)
+				clocks <- c.Clock()
+			})
+			c.Specify("Child B", func() {
+				times <- c.Clock().Now()
+				clocks <- c.Clock()
+			})
+		})
+		r.Run()
+		close(clocks)
+		close(times)
+
+		c.Expect(r.Results()).Matches(ReportContains("3 specs, 0 failures"))
+		c.Specify("which is a different clock for every leaf spec", func() {
+			a, b := <-clocks, <-clocks
+			c.Expect(a != b).IsTrue()
+		})
+		c.Specify("which starts from the same time for every leaf spec", func() {
+			for t := range times {
+				c.Expect(t).Equals(fakeClockStart)
+			}
+		})
+	})
+
+	c.Specify("The clock of other contexts is the system clock", func() {
+		c.Expect(ClockFromContext(context.Background()) == SystemClock).IsTrue()
+	})
+}
@@ -14,7 +14,7 @@ func runSpec(spec func(Context)) *ResultCollector {
 func runSpecWithContext(closure func(Context), context *taskContext) *taskResult {
 	resetTestSpy()
 	r := NewRunner()
-	return r.execute("RootSpec", closure, context)
+	return r.execute("RootSpec", closure, functionLocation(closure), withRunnerState(r, context))
 }
 
 func countSpecNames(specs []*specRun) map[string]int {
@@ -92,3 +92,10 @@ func DummySpecWithMultipleNestedChildren(c Context) {
 		})
 	})
 }
+
+// Like Runner.startNextScheduledTask, which is bypassed by runSpecWithContext.
+func withRunnerState(r *Runner, context *taskContext) *taskContext {
+	context.fixtures = r.fixtures
+	context.executing = r.executing
+	return context
+}
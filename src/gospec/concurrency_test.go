@@ -7,6 +7,9 @@ package gospec
 import (
 	"math"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"math/rand"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -58,3 +61,247 @@ func VerySlowDummySpec(c Context) {
 		})
 	})
 }
+
+func SerialExecutionSpec(c nanospec.Context) {
+	r := NewRunner()
+	r.SetSerial(true)
+	r.AddSpec(ConcurrencyProbeSpec)
+	r.AddSpec(ConcurrencyProbeSpec)
+	resetConcurrencyProbe()
+	r.Run()
+
+	c.Expect(r.Results().TotalCount()).Equals(4)
+	c.Expect(maxConcurrentProbes).Equals(1)
+}
+
+func SerialSpecSpec(c nanospec.Context) {
+	r := NewRunner()
+	r.AddSpec(ConcurrencyProbeSpec)
+	r.AddSerialSpec("SerialProbeSpec", SerialProbeSpec)
+	r.AddNamedSpec("OtherProbeSpec", ConcurrencyProbeSpec)
+	resetConcurrencyProbe()
+	serialOverlaps = 0
+	r.Run()
+
+	c.Expect(r.Results().TotalCount()).Equals(11)
+	c.Expect(serialOverlaps).Equals(0)
+	// The other specs are still executed in parallel
+	c.Expect(maxConcurrentProbes > 1).IsTrue()
+}
+
+// How many times a serial spec was executing concurrently with other specs
+var serialOverlaps int
+
+func SerialProbeSpec(c Context) {
+	c.Specify("Child A", probeSerialConcurrency)
+	c.Specify("Child B", probeSerialConcurrency)
+}
+
+func probeSerialConcurrency() {
+	concurrencyProbeLock.Lock()
+	concurrentProbes++
+	if concurrentProbes > 1 {
+		serialOverlaps++
+	}
+	concurrencyProbeLock.Unlock()
+
+	time.Sleep(DELAY / 10)
+
+	concurrencyProbeLock.Lock()
+	if concurrentProbes > 1 {
+		serialOverlaps++
+	}
+	concurrentProbes--
+	concurrencyProbeLock.Unlock()
+}
+
+// Records how many specs are executing concurrently
+var concurrentProbes, maxConcurrentProbes int
+var concurrencyProbeLock sync.Mutex
+
+func resetConcurrencyProbe() {
+	concurrentProbes = 0
+	maxConcurrentProbes = 0
+}
+
+func ConcurrencyProbeSpec(c Context) {
+	c.Specify("Child A", probeConcurrency)
+	c.Specify("Child B", probeConcurrency)
+	c.Specify("Child C", probeConcurrency)
+}
+
+func probeConcurrency() {
+	concurrencyProbeLock.Lock()
+	concurrentProbes++
+	if concurrentProbes > maxConcurrentProbes {
+		maxConcurrentProbes = concurrentProbes
+	}
+	concurrencyProbeLock.Unlock()
+
+	time.Sleep(DELAY / 10)
+
+	concurrencyProbeLock.Lock()
+	concurrentProbes--
+	concurrencyProbeLock.Unlock()
+}
+
+// The report does not depend on the order in which the specs finished
+
+func DeterministicOutputSpec(c nanospec.Context) {
+	expected := `
+- RootSpec [FAIL]
+*** Expected: equals “”
+         got: “Child A”
+    at concurrency_test.go
+*** Expected: equals “”
+         got: “Child B”
+    at concurrency_test.go
+*** Expected: equals “”
+         got: “Child C”
+    at concurrency_test.go
+  - Child A
+  - Child B
+  - Child C
+
+4 specs, 1 failures
+`
+	for i := 0; i < 20; i++ {
+		r := NewRunner()
+		r.SetScheduler(randomDelayScheduler{})
+		r.AddNamedSpec("RootSpec", FailsDifferentlyInEveryTaskSpec)
+		r.Run()
+		c.Expect(r.Results()).Matches(ReportIs(expected))
+	}
+}
+
+func FailsDifferentlyInEveryTaskSpec(c Context) {
+	executed := ""
+	c.Specify("Child A", func() { executed = "Child A" })
+	c.Specify("Child B", func() { executed = "Child B" })
+	c.Specify("Child C", func() { executed = "Child C" })
+	c.Expect(executed, Equals, "")
+}
+
+// Executes the tasks in parallel, so that they finish in a random order.
+type randomDelayScheduler struct{}
+
+func (randomDelayScheduler) Schedule(task *SpecTask) {
+	delay := time.Duration(rand.Intn(1000)) * time.Microsecond
+	go func() {
+		time.Sleep(delay)
+		task.Run()
+	}()
+}
+
+func DeadlineSpec(c nanospec.Context) {
+	release := make(chan bool)
+	defer close(release)
+	r := NewRunner()
+	r.SetSerial(true)
+	r.SetDeadline(DELAY)
+	r.AddNamedSpec("RootSpec", func(c Context) {
+		c.Specify("Child A", func() {})
+		c.Specify("Child B hangs", func() { <-release })
+	})
+
+	start := time.Now()
+	r.Run()
+	totalTime := time.Since(start)
+	results := r.Results()
+
+	c.Specify("The run is stopped when the deadline is reached", func() {
+		if totalTime > 3*DELAY {
+			c.Errorf("Expected the run to stop after %v but it took %v", time.Duration(DELAY), totalTime)
+		}
+	})
+	c.Specify("The specs which finished before the deadline are reported", func() {
+		c.Expect(results).Matches(ReportContains("- RootSpec\n  - Child A\n- Run [FAIL]\n"))
+	})
+	c.Specify("The run fails and tells how many specs were not executed", func() {
+		c.Expect(results.hasFailures()).IsTrue()
+		c.Expect(len(results.RunErrors())).Equals(2)
+		message := results.RunErrors()[0].Message
+		c.Expect(message).Satisfies(strings.HasSuffix(message, "because of the deadline of 50ms, at least 1 specs were not executed"))
+	})
+	c.Specify("The run tells where the abandoned specs were stuck", func() {
+		message := results.RunErrors()[1].Message
+		c.Expect(message).Satisfies(strings.HasPrefix(message, "a spec of RootSpec was still executing when the run was stopped:\ngoroutine "))
+		c.Expect(message).Satisfies(strings.Contains(message, "[chan receive]:"))
+		c.Expect(message).Satisfies(strings.Contains(message, "concurrency_test.go:"))
+	})
+	c.Specify("The root specs which were not started are reported as skipped", func() {
+		release := make(chan bool)
+		defer close(release)
+		r := NewRunner()
+		r.SetSerial(true)
+		r.SetDeadline(DELAY)
+		r.AddNamedSpec("RootSpec A", func(c Context) {})
+		r.AddNamedSpec("RootSpec B hangs", func(c Context) { <-release })
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportContains("- RootSpec A [SKIPPED: not executed because of the deadline]\n"))
+	})
+	c.Specify("The fixtures of the abandoned specs are closed after they have finished", func() {
+		release := make(chan bool)
+		closed := make(chan bool, 1)
+		r := NewRunner()
+		r.SetDeadline(DELAY)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Once("fixture", func() interface{} { return closeNotifier(closed) })
+			<-release
+		})
+		r.Run()
+
+		select {
+		case <-closed:
+			c.Errorf("Expected the fixture to not be closed while the spec was executing")
+		case <-time.After(DELAY):
+		}
+		close(release)
+		select {
+		case <-closed:
+		case <-time.After(10 * DELAY):
+			c.Errorf("Expected the fixture to be closed after the spec finished")
+		}
+	})
+}
+
+type closeNotifier chan bool
+
+func (this closeNotifier) Close() error {
+	this <- true
+	return nil
+}
+
+func MaxFailuresSpec(c nanospec.Context) {
+	r := NewRunner()
+	r.SetSerial(true)
+	r.SetMaxFailures(2)
+	r.AddNamedSpec("RootSpec", func(c Context) {
+		c.Specify("Child A", func() { c.Expect(1, Equals, 2) })
+		c.Specify("Child B", func() { c.Expect(1, Equals, 2) })
+		c.Specify("Child C", func() { c.Expect(1, Equals, 2) })
+		c.Specify("Child D", func() { c.Expect(1, Equals, 2) })
+	})
+	r.Run()
+	results := r.Results()
+
+	c.Specify("No more specs are started after the limit of failures", func() {
+		report := resultToString(results)
+		c.Expect(strings.Count(report, "- Child")).Equals(2)
+	})
+	c.Specify("The run fails and tells how many specs were not executed", func() {
+		c.Expect(len(results.RunErrors())).Equals(1)
+		c.Expect(results.RunErrors()[0].Message).Equals("the run was aborted after 2 failures, at least 2 specs were not executed")
+	})
+	c.Specify("Nothing is aborted when the limit is not reached", func() {
+		r := NewRunner()
+		r.SetMaxFailures(2)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() { c.Expect(1, Equals, 2) })
+			c.Specify("Child B", func() {})
+		})
+		r.Run()
+		c.Expect(len(r.Results().RunErrors())).Equals(0)
+	})
+}
@@ -0,0 +1,30 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+)
+
+// One setting of the Runner, recorded in the results so that the report
+// can describe how the run was configured.
+type configEntry struct {
+	key   string
+	value interface{}
+}
+
+func (r *ResultCollector) recordConfig(config []configEntry) {
+	r.config = config
+}
+
+// Prints the configuration which was used when running the specs,
+// so that a failing run can be reproduced with the same settings.
+func (r *ResultCollector) PrintConfig(out io.Writer) {
+	fmt.Fprintf(out, "\nConfiguration:\n")
+	for _, entry := range r.config {
+		fmt.Fprintf(out, "  %v: %v\n", entry.key, entry.value)
+	}
+}
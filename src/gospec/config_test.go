@@ -0,0 +1,51 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"runtime"
+	"strings"
+)
+
+func ConfigSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+
+	c.Specify("The configuration is printed as a footer of the report", func() {
+		results := newResultCollector()
+		results.recordConfig([]configEntry{
+			{"first", 1},
+			{"second", "two"},
+		})
+		results.PrintConfig(out)
+		c.Expect(out.String()).Equals("" +
+			"\nConfiguration:\n" +
+			"  first: 1\n" +
+			"  second: two\n")
+	})
+	c.Specify("The results record the configuration of the runner", func() {
+		r := NewRunner()
+		r.AddSpec(DummySpecWithNoChildren)
+		r.Run()
+		r.Results().PrintConfig(out)
+		c.Expect(strings.Contains(out.String(), fmt.Sprintf("parallelism: %v\n", runtime.GOMAXPROCS(0)))).IsTrue()
+	})
+	c.Specify("The configuration tells which specs were allowed by the filter", func() {
+		r := NewRunner()
+		r.filter = newSpecFilter()
+		r.filter.allow([]string{"RootSpec", "Child A"})
+		r.filter.allow([]string{"RootSpec", "Child B"})
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {})
+			c.Specify("Child C", func() {})
+		})
+		r.Run()
+		r.Results().PrintConfig(out)
+		c.Expect(strings.Contains(out.String(), "filter: RootSpec > Child A, RootSpec > Child B\n")).IsTrue()
+	})
+}
@@ -6,6 +6,13 @@ package gospec
 
 import (
 	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
 )
 
 // Context controls the execution of the current spec. Child specs can be
@@ -13,20 +20,116 @@ import (
 type Context interface {
 
 	// Creates a child spec for the currently executing spec. Specs can be
-	// nested unlimitedly. The name should describe what is the behaviour being
-	// specified by this spec, and the closure should express the same
-	// specification as code.
+	// nested up to the limit set with Runner.SetMaxNestingDepth. The name
+	// should describe what is the behaviour being specified by this spec,
+	// and the closure should express the same specification as code.
 	Specify(name string, closure func())
 
+	// Creates a child spec the same way as Specify, but marks it and its
+	// children as quarantined. Quarantined specs are for tracking known
+	// bugs: their failures are reported separately and do not fail the run.
+	// If a quarantined spec passes, it is reported so that it can be
+	// changed back to a normal spec.
+	QuarantineSpecify(name string, closure func())
+
+	// Creates a child spec the same way as Specify when the condition is
+	// true. Otherwise the spec is not executed, but it is shown in the report
+	// as skipped, with the reason why. When the reason is empty, it is
+	// "condition not met". Example:
+	//    c.SpecifyIf(features.Enabled("search"), "search is disabled", "Finds the posts", func() { ... })
+	SpecifyIf(condition bool, reason string, name string, closure func())
+
 	// Makes an expectation. For example:
 	//    c.Expect(theAnswer, Equals, 42)
 	//    c.Expect(theAnswer, Not(Equals), 666)
 	//    c.Expect(thereIsASpoon, IsFalse)
 	Expect(actual interface{}, matcher Matcher, expected ...interface{})
 
+	// Expects that the current spec has declared exactly 'n' child specs,
+	// as a guard against bugs in specs which declare their children in
+	// a loop. Should be called at the end of the spec's body, after all
+	// child specs have been declared. Because the spec's body is executed
+	// once for every leaf spec, the children are counted again every time,
+	// but the failure is reported only once.
+	ExpectChildCount(n int)
+
 	// Makes an assumption. Otherwise the same as an expectation,
 	// but on failure will not continue executing the child specs.
 	Assume(actual interface{}, matcher Matcher, expected ...interface{})
+
+	// Source of random numbers for the spec. It is seeded from the random seed
+	// of the run and the path of the spec, so the specs which use only this
+	// source of randomness will behave the same way when the run is repeated
+	// with the same seed (see Runner.SetRandomSeed), regardless of which other
+	// specs are executed. Every child spec gets its own source. Other sources
+	// of randomness, such as the global functions of the math/rand package,
+	// are not seeded.
+	Rand() *rand.Rand
+
+	// Registers a function to be called after the current leaf spec and its
+	// parents have been executed, also when they failed or panicked. Unlike
+	// code at the end of a spec body, a cleanup can be registered right after
+	// acquiring a resource. The cleanups are called in the reverse order of
+	// their registration (last in, first out), so resources are released
+	// in the reverse order of acquiring them. Because every leaf spec is
+	// executed separately, the cleanups registered by a parent spec are
+	// called once for each of its leaf specs.
+	Cleanup(f func())
+
+	// Sets an environment variable for the rest of the current leaf spec,
+	// and restores its previous value with Cleanup. Because the environment
+	// is shared by the whole process, a spec which calls Setenv will wait
+	// until no other spec is using Setenv, so such specs are executed one at
+	// a time even when the other specs are executed in parallel. Specs which
+	// only read the environment variables are not serialized.
+	Setenv(key string, value string)
+
+	// Replaces the default slog.Logger for the rest of the current leaf spec
+	// with one which captures all log records, so that they can be checked
+	// with the LoggedRecord matcher, and restores the previous logger with
+	// Cleanup. Like with Setenv, specs which call CaptureSlog are executed
+	// one at a time, but the records logged by other specs executing at the
+	// same time are also captured.
+	CaptureSlog() *LogCapture
+
+	// Returns the fixture with the given key, which is built with 'build'
+	// only when it is needed for the first time during the run. The other
+	// specs, also those executed in parallel, get the same value, so the
+	// specs must not modify it. Fixtures which implement io.Closer are
+	// closed after all specs of the run have been executed. Example:
+	//    dataset := c.Once("dataset", loadDataset).(*Dataset)
+	Once(key string, build func() interface{}) interface{}
+
+	// Records metadata about the current spec, such as its owner or a link
+	// to a ticket, for custom reporters (see SpecDetails.Metadata). The
+	// metadata of a spec applies also to its children, unless they set
+	// a different value with the same key. The printed report does not
+	// show the metadata.
+	Meta(key string, value string)
+
+	// Returns a context.Context for passing to the code under test, so that
+	// it can be cancelled. Despite the name, it has nothing to do with this
+	// Context, which controls the execution of the specs. The same
+	// context.Context is returned for the current leaf spec and its parents,
+	// because they are executed together. It is cancelled when the deadline
+	// of the run is reached (see Runner.SetDeadline), and then its deadline
+	// is the deadline of the run, or at the latest after the leaf spec and
+	// its cleanups have been executed, so it must not be used after that.
+	Context() context.Context
+
+	// Returns a FakeClock for passing to the code under test, so that the
+	// spec can control the time which the code sees. Every leaf spec gets
+	// its own clock, which starts from the same time, so the same clock is
+	// returned for the leaf spec and its parents, and the results do not
+	// depend on when or in which order the specs are executed. The code
+	// under test must get the time from the clock which is given to it,
+	// instead of calling time.Now. The clock can also be obtained from
+	// Context() with ClockFromContext. Example:
+	//    clock := c.Clock()
+	//    session := NewSession(clock)
+	//    clock.Advance(time.Hour)
+	//    c.Expect(session.Expired(), IsTrue)
+	Clock() *FakeClock
 }
 
 type taskContext struct {
@@ -34,6 +137,43 @@ type taskContext struct {
 	currentSpec    *specRun
 	executedSpecs  *list.List
 	postponedSpecs *list.List
+	skippedSpecs   *list.List
+	rootName       string
+	randomSeed     int64
+	randomUsed     bool
+	filter         *specFilter
+	cleanups       []cleanup
+	holdsEnvLock   bool
+	holdsSlogLock  bool
+	fixtures       *fixtureCache
+	executing      *executingTasks
+	maxDepth       int
+	repetition     int
+
+	// Whether also the passing expectations are recorded.
+	recordExpectations bool
+
+	// Whether the records captured with CaptureSlog fail the leaf spec,
+	// when their level is at least 'failingLogLevel'.
+	failOnLog       bool
+	failingLogLevel slog.Level
+
+	// Whether MatchesGolden writes the golden files instead of comparing them.
+	updateGolden bool
+
+	// The context of the run, and the context of the task which is
+	// created from it when it is needed for the first time.
+	runContext      context.Context
+	goContext       context.Context
+	cancelGoContext context.CancelFunc
+
+	// The clock of the task, created when it is needed for the first time.
+	clock *FakeClock
+}
+
+type cleanup struct {
+	spec *specRun
+	f    func()
 }
 
 func newInitialContext() *taskContext {
@@ -46,23 +186,64 @@ func newExplicitContext(targetPath path) *taskContext {
 	c.currentSpec = nil
 	c.executedSpecs = list.New()
 	c.postponedSpecs = list.New()
+	c.skippedSpecs = list.New()
+	c.maxDepth = defaultMaxNestingDepth
 	return c
 }
 
 func (c *taskContext) Specify(name string, closure func()) {
-	c.enterSpec(name, closure)
+	c.specifyAt(callerLocation(), name, closure)
+}
+
+func (c *taskContext) QuarantineSpecify(name string, closure func()) {
+	c.enterSpec(callerLocation(), name, closure)
+	c.currentSpec.quarantined = true
 	c.processCurrentSpec()
 	c.exitSpec()
 }
 
-func (c *taskContext) enterSpec(name string, closure func()) {
+func (c *taskContext) SpecifyIf(condition bool, reason string, name string, closure func()) {
+	if condition {
+		c.specifyAt(callerLocation(), name, closure)
+		return
+	}
+	if reason == "" {
+		reason = "condition not met"
+	}
+	c.enterSpec(callerLocation(), name, func() {})
+	spec := c.currentSpec
+	spec.skipReason = reason
+	if c.shouldPostpone(spec) {
+		// There is nothing to execute, so the spec is recorded by
+		// this task instead of a new task which would execute its
+		// parents again.
+		if !spec.parent.hasFatalErrors {
+			c.skippedSpecs.PushBack(spec)
+		}
+	} else {
+		c.processCurrentSpec()
+	}
+	c.exitSpec()
+}
+
+func (c *taskContext) specifyAt(location *Location, name string, closure func()) {
+	c.enterSpec(location, name, closure)
+	c.processCurrentSpec()
+	c.exitSpec()
+}
+
+func (c *taskContext) enterSpec(location *Location, name string, closure func()) {
 	spec := newSpecRun(name, closure, c.currentSpec, c.targetPath)
+	spec.location = location
 	c.currentSpec = spec
 }
 
 func (c *taskContext) processCurrentSpec() {
 	spec := c.currentSpec
 	switch {
+	case c.shouldExecute(spec) && c.isTooDeep(spec):
+		c.executedSpecs.PushBack(spec)
+		spec.AddFatalError(c.tooDeepError(spec))
 	case c.shouldExecute(spec):
 		c.execute(spec)
 	case c.shouldPostpone(spec):
@@ -78,11 +259,27 @@ func (c *taskContext) shouldExecute(spec *specRun) bool {
 	if spec.parent != nil && spec.parent.hasFatalErrors {
 		return false
 	}
+	if !c.filter.allows(spec) {
+		return false
+	}
 	return spec.isOnTargetPath() || (spec.isUnseen() && spec.isFirstChild())
 }
 
 func (c *taskContext) shouldPostpone(spec *specRun) bool {
-	return spec.isUnseen() && !spec.isFirstChild()
+	return spec.isUnseen() && !spec.isFirstChild() && c.filter.allows(spec)
+}
+
+func (c *taskContext) isTooDeep(spec *specRun) bool {
+	return len(spec.path) > c.maxDepth
+}
+
+func (c *taskContext) tooDeepError(spec *specRun) *Error {
+	stacktrace := []*Location{}
+	if spec.location != nil {
+		stacktrace = append(stacktrace, spec.location)
+	}
+	message := fmt.Sprintf("spec nesting exceeds limit %v", c.maxDepth)
+	return newError(OtherError, message, "", stacktrace)
 }
 
 func (c *taskContext) execute(spec *specRun) {
@@ -97,17 +294,171 @@ func (c *taskContext) postpone(spec *specRun) {
 func (c *taskContext) Expect(actual interface{}, matcher Matcher, expected ...interface{}) {
 	location := callerLocation()
 	logger := expectationLogger{c.currentSpec}
-	m := newMatcherAdapter(location, logger, ExpectFailed)
+	m := c.newMatcherAdapter(location, logger, ExpectFailed)
 	m.Expect(actual, matcher, expected...)
 }
 
+func (c *taskContext) ExpectChildCount(n int) {
+	location := callerLocation()
+	logger := expectationLogger{c.currentSpec}
+	m := c.newMatcherAdapter(location, logger, ExpectFailed)
+	m.Expect(c.currentSpec.numberOfChildren, hasChildCount, n)
+}
+
+func hasChildCount(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+	match = actual == expected
+	pos = Messagef(actual, "has %v child specs", expected)
+	neg = Messagef(actual, "does NOT have %v child specs", expected)
+	return
+}
+
+func (c *taskContext) Rand() *rand.Rand {
+	spec := c.currentSpec
+	if spec.random == nil {
+		spec.random = rand.New(rand.NewSource(specSeed(c.randomSeed, c.rootName, spec.path)))
+		c.randomUsed = true
+	}
+	return spec.random
+}
+
+func (c *taskContext) Cleanup(f func()) {
+	c.cleanups = append(c.cleanups, cleanup{c.currentSpec, f})
+}
+
+// Held by the task which has changed the environment variables,
+// until it has restored them.
+var environmentLock sync.Mutex
+
+func (c *taskContext) Setenv(key string, value string) {
+	if !c.holdsEnvLock {
+		environmentLock.Lock()
+		c.holdsEnvLock = true
+		// Registered first, so that it is called after restoring the variables.
+		c.Cleanup(func() {
+			c.holdsEnvLock = false
+			environmentLock.Unlock()
+		})
+	}
+	previous, existed := os.LookupEnv(key)
+	c.Cleanup(func() {
+		if existed {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+	if err := os.Setenv(key, value); err != nil {
+		panic(err)
+	}
+}
+
+// Held by the task which has replaced the default slog.Logger,
+// until it has restored it.
+var slogLock sync.Mutex
+
+func (c *taskContext) CaptureSlog() *LogCapture {
+	if !c.holdsSlogLock {
+		slogLock.Lock()
+		c.holdsSlogLock = true
+		// Registered first, so that it is called after restoring the logger.
+		c.Cleanup(func() {
+			c.holdsSlogLock = false
+			slogLock.Unlock()
+		})
+	}
+	// Setting the default slog.Logger redirects also the log package to it,
+	// so also the output and the flags of the log package are restored.
+	previous := slog.Default()
+	writer, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	c.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	})
+	capture := new(LogCapture)
+	slog.SetDefault(slog.New(&captureHandler{capture: capture}))
+	if c.failOnLog {
+		// Registered last, so that also the records logged by
+		// the cleanups which were registered later are checked.
+		c.Cleanup(func() {
+			leaf := c.executedSpecs.Back().Value.(*specRun)
+			for _, error := range capture.errorsAtLevel(c.failingLogLevel) {
+				leaf.AddError(error)
+			}
+		})
+	}
+	return capture
+}
+
+func (c *taskContext) Meta(key string, value string) {
+	c.currentSpec.setMeta(key, value)
+}
+
+func (c *taskContext) Context() context.Context {
+	if c.goContext == nil {
+		parent := c.runContext
+		if parent == nil {
+			parent = context.Background()
+		}
+		c.goContext, c.cancelGoContext = context.WithCancel(context.WithValue(parent, clockKey{}, c.Clock()))
+	}
+	return c.goContext
+}
+
+func (c *taskContext) Clock() *FakeClock {
+	if c.clock == nil {
+		c.clock = NewFakeClock(fakeClockStart)
+	}
+	return c.clock
+}
+
+// Called after the cleanups, so that they can still use the context.
+func (c *taskContext) cancel() {
+	if c.cancelGoContext != nil {
+		c.cancelGoContext()
+	}
+}
+
+func (c *taskContext) Once(key string, build func() interface{}) interface{} {
+	return c.fixtures.get(key, build)
+}
+
+// Calls the registered cleanups in the reverse order of their registration.
+// A panicking cleanup is reported as an error of the spec which registered it,
+// and it does not prevent the rest of the cleanups from being called.
+func (c *taskContext) runCleanups() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		cleanup := c.cleanups[i]
+		if exception := recoverOnPanic(cleanup.f); exception != nil {
+			cleanup.spec.AddError(exception.ToError())
+		}
+	}
+	c.cleanups = nil
+}
+
+func (c *taskContext) usedRandom() bool {
+	return c.randomUsed
+}
+
 func (c *taskContext) Assume(actual interface{}, matcher Matcher, expected ...interface{}) {
 	location := callerLocation()
 	logger := assumptionLogger{c.currentSpec}
-	m := newMatcherAdapter(location, logger, AssumeFailed)
+	m := c.newMatcherAdapter(location, logger, AssumeFailed)
 	m.Expect(actual, matcher, expected...)
 }
 
+func (c *taskContext) newMatcherAdapter(location *Location, log errorLogger, matcherType ErrorType) *matcherAdapter {
+	m := newMatcherAdapter(location, log, matcherType)
+	// Every expectation and assumption creates its own adapter.
+	c.currentSpec.evaluated++
+	if c.recordExpectations {
+		m.recorder = c.currentSpec
+	}
+	m.updateGolden = c.updateGolden
+	return m
+}
+
 type expectationLogger struct {
 	log ratedErrorLogger
 }
@@ -5,7 +5,13 @@
 package gospec
 
 import (
+	"context"
+	"fmt"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 func ContextSpec(c nanospec.Context) {
@@ -62,4 +68,276 @@ func ContextSpec(c nanospec.Context) {
 		c.Expect(runCounts["gospec.DummySpecWithOneChild"]).Equals(1)
 		c.Expect(runCounts["gospec.DummySpecWithTwoChildren"]).Equals(2)
 	})
+
+	c.Specify("Specs get random sources which are seeded from the run's seed", func() {
+		randomNumbers := func(seed int64, filter *specFilter, parentUsesRandom bool) map[string]int64 {
+			var lock sync.Mutex
+			numbers := make(map[string]int64)
+			record := func(name string, n int64) {
+				lock.Lock()
+				defer lock.Unlock()
+				numbers[name] = n
+			}
+			r := NewRunner()
+			r.SetRandomSeed(seed)
+			r.filter = filter
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				if parentUsesRandom {
+					c.Rand().Int63()
+				}
+				c.Specify("Child A", func() { record("Child A", c.Rand().Int63()) })
+				c.Specify("Child B", func() { record("Child B", c.Rand().Int63()) })
+			})
+			r.Run()
+			return numbers
+		}
+		first := randomNumbers(1, nil, false)
+		second := randomNumbers(1, nil, false)
+		other := randomNumbers(2, nil, false)
+
+		c.Expect(second["Child A"]).Equals(first["Child A"])
+		c.Expect(second["Child B"]).Equals(first["Child B"])
+		c.Expect(first["Child A"]).NotEquals(first["Child B"])
+		c.Expect(other["Child A"]).NotEquals(first["Child A"])
+
+		c.Specify("regardless of which other specs are executed", func() {
+			onlyB := newSpecFilter()
+			onlyB.allow([]string{"RootSpec", "Child B"})
+			filtered := randomNumbers(1, onlyB, false)
+
+			c.Expect(len(filtered)).Equals(1)
+			c.Expect(filtered["Child B"]).Equals(first["Child B"])
+		})
+		c.Specify("regardless of how the parent specs use randomness", func() {
+			withParent := randomNumbers(1, nil, true)
+
+			c.Expect(withParent["Child A"]).Equals(first["Child A"])
+			c.Expect(withParent["Child B"]).Equals(first["Child B"])
+		})
+	})
+
+	c.Specify("The seed is recorded in the results only when the specs used randomness", func() {
+		r := NewRunner()
+		r.SetRandomSeed(42)
+		r.AddNamedSpec("RandomSpec", func(c Context) { c.Rand() })
+		r.Run()
+		seed, used := r.Results().RandomSeed()
+		c.Expect(used).IsTrue()
+		c.Expect(seed).Equals(int64(42))
+
+		r = NewRunner()
+		r.AddSpec(DummySpecWithOneChild)
+		r.Run()
+		_, used = r.Results().RandomSeed()
+		c.Expect(used).IsFalse()
+	})
+
+	c.Specify("Cleanups are called after every leaf spec in reverse order of registration", func() {
+		var lock sync.Mutex
+		calls := make([]string, 0)
+		record := func(call string) {
+			lock.Lock()
+			defer lock.Unlock()
+			calls = append(calls, call)
+		}
+		r := NewRunner()
+		r.SetSerial(true)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Cleanup(func() { record("root") })
+			c.Specify("Child A", func() {
+				c.Cleanup(func() { record("a1") })
+				c.Cleanup(func() { record("a2") })
+				record("body a")
+			})
+			c.Specify("Child B", func() {
+				c.Cleanup(func() { record("b") })
+				panic("boom")
+			})
+		})
+		r.Run()
+
+		c.Expect(strings.Join(calls, ",")).Equals("body a,a2,a1,root,b,root")
+	})
+
+	c.Specify("A panicking cleanup is reported as an error of the spec which registered it", func() {
+		called := false
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Cleanup(func() { called = true })
+			c.Specify("Child A", func() {
+				c.Cleanup(func() { panic("cleanup failed") })
+			})
+		})
+		r.Run()
+
+		c.Expect(called).IsTrue()
+		c.Expect(r.Results()).Matches(ReportContains("- Child A [FAIL]\n*** panic: cleanup failed"))
+	})
+
+	c.Specify("Environment variables set with Setenv are restored after every leaf spec", func() {
+		os.Setenv("GOSPEC_TEST_EXISTING", "original")
+		defer os.Unsetenv("GOSPEC_TEST_EXISTING")
+		seen := make(map[string]string)
+		newSeenInB := false
+		var lock sync.Mutex
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Setenv("GOSPEC_TEST_EXISTING", "changed")
+			c.Specify("Child A", func() {
+				c.Setenv("GOSPEC_TEST_NEW", "a")
+			})
+			c.Specify("Child B", func() {
+				lock.Lock()
+				defer lock.Unlock()
+				_, newSeenInB = os.LookupEnv("GOSPEC_TEST_NEW")
+				seen["existing in B"] = os.Getenv("GOSPEC_TEST_EXISTING")
+			})
+		})
+		r.Run()
+
+		_, newExists := os.LookupEnv("GOSPEC_TEST_NEW")
+		c.Expect(newExists).IsFalse()
+		c.Expect(os.Getenv("GOSPEC_TEST_EXISTING")).Equals("original")
+		c.Expect(seen["existing in B"]).Equals("changed")
+		c.Expect(newSeenInB).IsFalse()
+	})
+
+	c.Specify("Specs which use Setenv are executed one at a time", func() {
+		var lock sync.Mutex
+		running, maxRunning := 0, 0
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			for _, name := range []string{"A", "B", "C", "D"} {
+				name := name
+				c.Specify(name, func() {
+					c.Setenv("GOSPEC_TEST_SERIAL", name)
+					lock.Lock()
+					running++
+					if running > maxRunning {
+						maxRunning = running
+					}
+					lock.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					lock.Lock()
+					running--
+					lock.Unlock()
+				})
+			}
+		})
+		r.Run()
+		c.Expect(maxRunning).Equals(1)
+	})
+
+	c.Specify("The number of declared child specs can be checked", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Generated", func() {
+				for _, name := range []string{"a", "b"} {
+					c.Specify(name, func() {})
+				}
+				c.ExpectChildCount(3)
+			})
+			c.Specify("Child B", func() {})
+			c.ExpectChildCount(2)
+		})
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Generated [FAIL]
+*** Expected: has 3 child specs
+         got: “2”
+    at context_test.go
+    - a
+    - b
+  - Child B
+
+5 specs, 1 failures
+`))
+	})
+
+	c.Specify("Specs which are nested too deeply fail without being executed", func() {
+		r := NewRunner()
+		r.SetMaxNestingDepth(2)
+		var nest func(c Context, depth int)
+		nest = func(c Context, depth int) {
+			c.Specify(fmt.Sprintf("Level %v", depth), func() {
+				nest(c, depth+1)
+			})
+		}
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			nest(c, 1)
+		})
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Level 1
+    - Level 2
+      - Level 3 [FAIL]
+*** spec nesting exceeds limit 2
+    at context_test.go
+
+4 specs, 1 failures
+`))
+	})
+
+	c.Specify("Specs get a context.Context for the code under test", func() {
+		r := NewRunner()
+		contexts := make(chan context.Context, 10)
+		var errInCleanup error
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			parent := c.Context()
+			c.Specify("Child A", func() {
+				c.Expect(c.Context() == parent, IsTrue)
+				c.Expect(c.Context().Err(), IsNil)
+				c.Cleanup(func() {
+					errInCleanup = c.Context().Err()
+				})
+				contexts <- c.Context()
+			})
+			c.Specify("Child B", func() {
+				contexts <- c.Context()
+			})
+		})
+		r.Run()
+		close(contexts)
+
+		c.Specify("which is a different context for every leaf spec", func() {
+			a, b := <-contexts, <-contexts
+			c.Expect(a != b).IsTrue()
+		})
+		c.Specify("which is cancelled after the leaf spec and its cleanups", func() {
+			c.Expect(r.Results()).Matches(ReportContains("3 specs, 0 failures"))
+			c.Expect(errInCleanup).Equals(nil)
+			for ctx := range contexts {
+				c.Expect(ctx.Err()).Equals(context.Canceled)
+			}
+		})
+	})
+
+	c.Specify("The context.Context of a spec is cancelled at the deadline of the run", func() {
+		r := NewRunner()
+		r.SetDeadline(DELAY)
+		done := make(chan bool, 1)
+		var deadline time.Time
+		var hasDeadline bool
+		var cancelled error
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			deadline, hasDeadline = c.Context().Deadline()
+			select {
+			case <-c.Context().Done():
+				cancelled = c.Context().Err()
+			case <-time.After(10 * DELAY):
+			}
+			done <- true
+		})
+		start := time.Now()
+		r.Run()
+		<-done
+
+		c.Expect(hasDeadline).IsTrue()
+		c.Expect(deadline.Sub(start) > DELAY/2 && deadline.Sub(start) <= 2*DELAY).IsTrue()
+		c.Expect(cancelled).Equals(context.DeadlineExceeded)
+	})
 }
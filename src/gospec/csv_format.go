@@ -0,0 +1,106 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes the results of the leaf specs as CSV, for example for importing them
+// to a spreadsheet. Unlike the other formats this is a ResultVisitor, which is
+// used without a Printer, because it needs the details of every spec:
+//    csv := CsvPrintFormat(out)
+//    results.Visit(csv)
+//    if err := csv.Err(); err != nil { ... }
+//
+// The first row is a header, and then there is one row for every leaf spec,
+// with its full name from the root spec to the leaf joined with " > ", its
+// status ("passed", "failed" or "skipped"), its duration in seconds, the first
+// line of its first error message, and where it was declared. A leaf fails
+// also when one of its parents failed, because they were executed together.
+// Every error of the whole run is on its own row named "Run". The fields are
+// quoted as specified in RFC 4180, when they contain commas, quotes or newlines.
+func CsvPrintFormat(out io.Writer) CsvResultVisitor {
+	w := csv.NewWriter(out)
+	w.UseCRLF = true
+	w.Write([]string{"spec", "status", "duration", "message", "location"})
+	return &csvPrintFormat{out: w}
+}
+
+// The ResultVisitor of CsvPrintFormat.
+type CsvResultVisitor interface {
+	DetailedResultVisitor
+	RunErrorVisitor
+
+	// The first error of writing the CSV, or nil if there was none. The rows
+	// are buffered, so all errors are known only after VisitEnd.
+	Err() error
+}
+
+type csvPrintFormat struct {
+	out *csv.Writer
+
+	// The names and the errors of the parents of the next spec.
+	names  []string
+	errors [][]*Error
+}
+
+func (this *csvPrintFormat) VisitSpec(nestingLevel int, name string, errors []*Error) {
+	this.VisitSpecDetails(nestingLevel, &SpecDetails{Name: name, Errors: errors})
+}
+
+func (this *csvPrintFormat) VisitSpecDetails(nestingLevel int, spec *SpecDetails) {
+	this.names = append(this.names[:nestingLevel], spec.Name)
+	this.errors = append(this.errors[:nestingLevel], spec.Errors)
+	if spec.ChildCount > 0 {
+		return
+	}
+
+	status, message := "passed", ""
+	for i := len(this.errors) - 1; i >= 0; i-- {
+		if len(this.errors[i]) > 0 {
+			status, message = "failed", firstLine(this.errors[i][0].Message)
+			break
+		}
+	}
+	if spec.SkipReason != "" {
+		status, message = "skipped", spec.SkipReason
+	}
+	location := ""
+	if spec.Location != nil {
+		location = fmt.Sprintf("%v:%v", spec.Location.File(), spec.Location.Line())
+	}
+	this.out.Write([]string{
+		strings.Join(this.names, " > "),
+		status,
+		fmt.Sprintf("%.6f", spec.Duration.Seconds()),
+		message,
+		location,
+	})
+}
+
+func (this *csvPrintFormat) VisitRunErrors(errors []*Error) {
+	for _, error := range errors {
+		this.out.Write([]string{"Run", "failed", "", firstLine(error.Message), ""})
+	}
+}
+
+func (this *csvPrintFormat) VisitEnd(passCount int, failCount int) {
+	this.out.Flush()
+}
+
+func (this *csvPrintFormat) Err() error {
+	return this.out.Error()
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
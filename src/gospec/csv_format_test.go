@@ -0,0 +1,83 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func CsvFormatSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	format := CsvPrintFormat(out).(DetailedResultVisitor)
+	location := &Location{"gospec.SomeSpec", "/work/some_test.go", 12}
+	noErrors := []*Error{}
+
+	c.Specify("There is a header and a row for every leaf spec", func() {
+		format.VisitSpecDetails(0, &SpecDetails{Name: "RootSpec", Errors: noErrors, ChildCount: 2})
+		format.VisitSpecDetails(1, &SpecDetails{Name: "Child A", Errors: noErrors, Location: location, Duration: 1500 * time.Microsecond})
+		format.VisitSpecDetails(1, &SpecDetails{Name: "Child B", Errors: noErrors, SkipReason: "no network"})
+		format.VisitEnd(2, 0)
+		c.Expect(out.String()).Equals("" +
+			"spec,status,duration,message,location\r\n" +
+			"RootSpec > Child A,passed,0.001500,,/work/some_test.go:12\r\n" +
+			"RootSpec > Child B,skipped,0.000000,no network,\r\n")
+	})
+	c.Specify("A leaf fails with the first line of the first error of it or its parents", func() {
+		failure := newError(ExpectFailed, "equals “2”\nand more", "1", []*Location{})
+		format.VisitSpecDetails(0, &SpecDetails{Name: "RootSpec", Errors: []*Error{failure}, ChildCount: 1})
+		format.VisitSpecDetails(1, &SpecDetails{Name: "Child", Errors: noErrors})
+		format.VisitEnd(0, 1)
+		c.Expect(out.String()).Satisfies(strings.HasSuffix(out.String(), "RootSpec > Child,failed,0.000000,equals “2”,\r\n"))
+	})
+	c.Specify("Commas, quotes and newlines are quoted", func() {
+		format.VisitSpecDetails(0, &SpecDetails{Name: "a, \"b\"\nc", Errors: noErrors})
+		format.VisitEnd(1, 0)
+		c.Expect(out.String()).Satisfies(strings.HasSuffix(out.String(), "\"a, \"\"b\"\"\r\nc\",passed,0.000000,,\r\n"))
+	})
+	c.Specify("Every error of the run is on its own row", func() {
+		format.(RunErrorVisitor).VisitRunErrors([]*Error{
+			newError(OtherError, "the run was stopped", "", []*Location{}),
+			newError(OtherError, "cannot close fixture 'db'", "", []*Location{}),
+		})
+		format.VisitEnd(0, 0)
+		c.Expect(out.String()).Satisfies(strings.HasSuffix(out.String(), ""+
+			"Run,failed,,the run was stopped,\r\n"+
+			"Run,failed,,cannot close fixture 'db',\r\n"))
+	})
+	c.Specify("The errors of writing are told after the end", func() {
+		failing := CsvPrintFormat(failingWriter{})
+		c.Expect(failing.Err()).Equals(nil)
+		failing.VisitEnd(0, 0)
+		c.Expect(failing.Err()).Equals(errDiskFull)
+	})
+	c.Specify("The duration of the leaf specs is measured", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child", func() { time.Sleep(time.Millisecond) })
+		})
+		r.Run()
+		r.Results().Visit(format)
+
+		rows, err := csv.NewReader(out).ReadAll()
+		c.Expect(err).Equals(nil)
+		c.Expect(len(rows)).Equals(2)
+		seconds, _ := strconv.ParseFloat(rows[1][2], 64)
+		c.Expect(seconds >= 0.001).IsTrue()
+	})
+}
+
+var errDiskFull = errors.New("disk full")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errDiskFull
+}
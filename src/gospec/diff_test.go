@@ -0,0 +1,32 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func DiffSpec(c nanospec.Context) {
+
+	c.Specify("Identical texts have only common lines", func() {
+		c.Expect(lineDiff("a\nb", "a\nb")).Equals("  a\n  b")
+	})
+	c.Specify("Lines only in the expected text are marked with -", func() {
+		c.Expect(lineDiff("a\nb\nc", "a\nc")).Equals("  a\n- b\n  c")
+	})
+	c.Specify("Lines only in the actual text are marked with +", func() {
+		c.Expect(lineDiff("a\nc", "a\nb\nc")).Equals("  a\n+ b\n  c")
+	})
+	c.Specify("Changed lines are shown as removed and added", func() {
+		c.Expect(lineDiff("a\nb\nc", "a\nx\nc")).Equals("  a\n- b\n+ x\n  c")
+	})
+	c.Specify("Case: completely different texts", func() {
+		c.Expect(lineDiff("a\nb", "c")).Equals("- a\n- b\n+ c")
+	})
+	c.Specify("The diff of a message is computed when the message is formatted", func() {
+		message := Messagef("b", "diff:\n%v", lazyLineDiff{"a", "b"})
+		c.Expect(message.Expectation()).Equals("diff:\n- a\n+ b")
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// One leaf spec in the order in which the leaf specs finished executing.
+type executedLeaf struct {
+	names    []string
+	duration time.Duration
+}
+
+// Records the leaf specs as their tasks finish. Every task executes
+// exactly one leaf spec, which is the last spec that it executed.
+type executionOrderRecorder struct {
+	leaves []executedLeaf
+}
+
+func (this *executionOrderRecorder) taskFinished(result *taskResult, finished int, total int) {
+	if n := len(result.executedSpecs); n > 0 {
+		leaf := result.executedSpecs[n-1]
+		this.leaves = append(this.leaves, executedLeaf{leaf.namePath(), result.duration})
+	}
+}
+
+func (r *ResultCollector) recordExecutionOrder(leaves []executedLeaf) {
+	r.executionOrder = leaves
+}
+
+// Prints the leaf specs in the order in which they finished executing,
+// together with how long it took to execute them and their parents.
+// Unlike the report, which is always in the same order, this shows how
+// the specs were actually scheduled, which helps to debug specs whose
+// results depend on the order in which they are executed.
+func (r *ResultCollector) PrintExecutionOrder(out io.Writer) {
+	fmt.Fprintf(out, "\nExecution order:\n")
+	for i, leaf := range r.executionOrder {
+		fmt.Fprintf(out, "  %v. %v (%v)\n", i+1, strings.Join(r.formatNames(leaf.names), " > "), leaf.duration)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sort"
+	"strings"
+	"time"
+)
+
+func ExecutionOrderSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+
+	c.Specify("The leaf specs are printed in the order in which they were executed", func() {
+		results := newResultCollector()
+		results.recordExecutionOrder([]executedLeaf{
+			{[]string{"RootSpec", "Child B"}, 2 * time.Millisecond},
+			{[]string{"RootSpec", "Child A"}, 1 * time.Millisecond},
+		})
+		results.PrintExecutionOrder(out)
+		c.Expect(out.String()).Equals("" +
+			"\nExecution order:\n" +
+			"  1. RootSpec > Child B (2ms)\n" +
+			"  2. RootSpec > Child A (1ms)\n")
+	})
+	c.Specify("The runner records every executed leaf spec", func() {
+		r := NewRunner()
+		r.SetSerial(true)
+		r.RecordExecutionOrder()
+		r.AddNamedSpec("RootSpec", DummySpecWithMultipleNestedChildren)
+		r.Run()
+		r.Results().PrintExecutionOrder(out)
+
+		c.Expect(len(r.order.leaves)).Equals(5)
+		names := make([]string, 0)
+		for _, leaf := range r.order.leaves {
+			names = append(names, leaf.names[len(leaf.names)-1])
+		}
+		sort.Strings(names)
+		c.Expect(strings.Join(names, ",")).Equals("Child AA,Child AB,Child BA,Child BB,Child BC")
+		c.Expect(bytes.Contains(out.Bytes(), []byte("RootSpec > Child A > Child AA ("))).IsTrue()
+	})
+	c.Specify("The execution order is not recorded unless requested", func() {
+		r := NewRunner()
+		r.AddSpec(DummySpecWithNoChildren)
+		r.Run()
+		r.Results().PrintExecutionOrder(out)
+		c.Expect(len(r.listeners)).Equals(0)
+		c.Expect(out.String()).Equals("\nExecution order:\n")
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes the failures as soon as the specs which have them have been executed.
+// Parent specs are executed once for each of their leaf specs, so each failure
+// is written only the first time that it happens.
+type failureStreamer struct {
+	format  PrintFormat
+	written map[string]bool
+}
+
+func newFailureStreamer(out io.Writer) *failureStreamer {
+	return &failureStreamer{DefaultPrintFormat(out), make(map[string]bool)}
+}
+
+func (this *failureStreamer) taskFinished(result *taskResult, finished int, total int) {
+	for _, spec := range result.executedSpecs {
+		if spec.isQuarantined() {
+			// Known failures, which will be reported as such at the end.
+			continue
+		}
+		if errors := this.newErrors(spec); len(errors) > 0 {
+			this.format.PrintFailing(0, strings.Join(spec.namePath(), " > "), errors)
+		}
+	}
+}
+
+func (this *failureStreamer) newErrors(spec *specRun) []*Error {
+	errors := make([]*Error, 0)
+	for _, error := range listToErrorArray(spec.errors) {
+		key := fmt.Sprint(spec.path, error.Actual, error)
+		if !this.written[key] {
+			this.written[key] = true
+			errors = append(errors, error)
+		}
+	}
+	return errors
+}
@@ -0,0 +1,52 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func FailureStreamSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	r := NewRunner()
+	r.SetFailureStream(out)
+
+	c.Specify("Failures are written with the names of the spec and its parents", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		r.Run()
+		c.Expect(strings.HasPrefix(out.String(), "- RootSpec > Child A [FAIL]\n\n*** Expected: equals “2”\n         got: “1”\n")).IsTrue()
+	})
+	c.Specify("Failures in parent specs are written only once", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {})
+		})
+		r.Run()
+		c.Expect(strings.Count(out.String(), "[FAIL]")).Equals(1)
+		c.Expect(strings.Count(out.String(), "- RootSpec [FAIL]")).Equals(1)
+	})
+	c.Specify("Passing specs are not written", func() {
+		r.AddSpec(DummySpecWithMultipleNestedChildren)
+		r.Run()
+		c.Expect(out.String()).Equals("")
+	})
+
+	c.Specify("Failures in quarantined specs are not written", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.QuarantineSpecify("Known bug", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		r.Run()
+		c.Expect(out.String()).Equals("")
+	})
+}
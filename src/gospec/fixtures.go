@@ -0,0 +1,81 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// The fixtures which have been built with Context.Once during one run.
+// They are shared by all goroutines which execute the specs.
+type fixtureCache struct {
+	lock     sync.Mutex
+	fixtures map[string]*fixture
+}
+
+type fixture struct {
+	once     sync.Once
+	value    interface{}
+	failure  interface{}
+	panicked bool
+}
+
+func newFixtureCache() *fixtureCache {
+	return &fixtureCache{fixtures: make(map[string]*fixture)}
+}
+
+// Returns the fixture with the given key, building it first if no spec has
+// built it yet. If building the fixture panics, every spec which asks for
+// it will panic the same way, instead of building it again.
+func (this *fixtureCache) get(key string, build func() interface{}) interface{} {
+	f := this.fixture(key)
+	f.once.Do(func() {
+		f.failure, f.panicked = recoverPanic(func() {
+			f.value = build()
+		})
+	})
+	if f.panicked {
+		panic(f.failure)
+	}
+	return f.value
+}
+
+func (this *fixtureCache) fixture(key string) *fixture {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	f, found := this.fixtures[key]
+	if !found {
+		f = new(fixture)
+		this.fixtures[key] = f
+	}
+	return f
+}
+
+// Closes the fixtures which implement io.Closer, in the order of their keys,
+// and returns the errors of the fixtures which could not be closed.
+func (this *fixtureCache) close() []*Error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	keys := make([]string, 0, len(this.fixtures))
+	for key := range this.fixtures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errors := make([]*Error, 0)
+	for _, key := range keys {
+		if closer, ok := this.fixtures[key].value.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				message := fmt.Sprintf("cannot close fixture '%v': %v", key, err)
+				errors = append(errors, newError(OtherError, message, "", []*Location{}))
+			}
+		}
+	}
+	this.fixtures = make(map[string]*fixture)
+	return errors
+}
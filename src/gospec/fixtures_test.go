@@ -0,0 +1,90 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"errors"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sync"
+)
+
+func FixturesSpec(c nanospec.Context) {
+	var lock sync.Mutex
+	builds := 0
+	build := func() interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+		builds++
+		return []string{"shared"}
+	}
+
+	c.Specify("A fixture is built only once for all specs of the run", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			fixture := c.Once("fixture", build).([]string)
+			c.Specify("Child A", func() { c.Expect(fixture, ContainsExactly, Values("shared")) })
+			c.Specify("Child B", func() { c.Expect(fixture, ContainsExactly, Values("shared")) })
+			c.Specify("Child C", func() { c.Once("other", build) })
+		})
+		r.Run()
+
+		c.Expect(builds).Equals(2)
+		c.Expect(r.Results().FailCount()).Equals(0)
+	})
+	c.Specify("When building a fixture panics, all specs which use it fail", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() { c.Once("broken", func() interface{} { builds++; panic("boom") }) })
+			c.Specify("Child B", func() { c.Once("broken", func() interface{} { builds++; panic("boom") }) })
+		})
+		r.SetSerial(true)
+		r.Run()
+
+		c.Expect(builds).Equals(1)
+		c.Expect(r.Results().FailCount()).Equals(2)
+	})
+	c.Specify("Fixtures are closed after the run", func() {
+		closer := &fixtureCloser{}
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Once("closer", func() interface{} { return closer })
+			c.Specify("Child A", func() { c.Expect(closer.closed, IsFalse) })
+		})
+		r.Run()
+
+		c.Expect(closer.closed).IsTrue()
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A
+
+2 specs, 0 failures
+`))
+	})
+	c.Specify("Errors in closing the fixtures fail the run", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Once("closer", func() interface{} { return &fixtureCloser{err: errors.New("disk full")} })
+		})
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+- Run [FAIL]
+*** cannot close fixture 'closer': disk full
+
+1 specs, 0 failures, 1 run errors
+`))
+	})
+}
+
+type fixtureCloser struct {
+	closed bool
+	err    error
+}
+
+func (this *fixtureCloser) Close() error {
+	this.closed = true
+	return this.err
+}
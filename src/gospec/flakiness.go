@@ -0,0 +1,94 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// How many times one leaf spec failed when the suite was run many times.
+type leafRuns struct {
+	names    []string
+	runs     int
+	failures int
+}
+
+func (leaf *leafRuns) isFlaky() bool {
+	return leaf.failures > 0 && leaf.failures < leaf.runs
+}
+
+// Records for every leaf spec whether its task failed. A task fails when
+// the leaf spec or any of its parents fails, because they are executed
+// together. Failures of quarantined specs are not counted.
+type flakinessRecorder struct {
+	leaves map[string]*leafRuns
+}
+
+func newFlakinessRecorder() *flakinessRecorder {
+	return &flakinessRecorder{make(map[string]*leafRuns)}
+}
+
+func (this *flakinessRecorder) taskFinished(result *taskResult, finished int, total int) {
+	n := len(result.executedSpecs)
+	if n == 0 {
+		return
+	}
+	names := result.executedSpecs[n-1].namePath()
+	key := strings.Join(names, "\x00")
+	leaf, found := this.leaves[key]
+	if !found {
+		leaf = &leafRuns{names: names}
+		this.leaves[key] = leaf
+	}
+	leaf.runs++
+	if result.failed() {
+		leaf.failures++
+	}
+}
+
+// The leaf specs sorted by their names, because the order
+// in which they are executed differs from run to run.
+func (this *flakinessRecorder) sortedLeaves() []leafRuns {
+	keys := make([]string, 0, len(this.leaves))
+	for key := range this.leaves {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	leaves := make([]leafRuns, len(keys))
+	for i, key := range keys {
+		leaves[i] = *this.leaves[key]
+	}
+	return leaves
+}
+
+func (r *ResultCollector) recordFlakiness(runCount int, leaves []leafRuns) {
+	r.runCount = runCount
+	r.leafRuns = leaves
+}
+
+// Prints how many of the runs failed for every leaf spec which failed
+// at least once, when the specs were run many times with Runner.SetRunCount,
+// and how many of the leaf specs were flaky: they failed in some runs and
+// passed in others.
+func (r *ResultCollector) PrintFlakiness(out io.Writer) {
+	fmt.Fprintf(out, "\nFlakiness over %v runs:\n", r.runCount)
+	flaky, failing := 0, 0
+	for _, leaf := range r.leafRuns {
+		if leaf.failures == 0 {
+			continue
+		}
+		if leaf.isFlaky() {
+			flaky++
+		} else {
+			failing++
+		}
+		fmt.Fprintf(out, "  %v: failed %v of %v runs (%.0f%%)\n",
+			strings.Join(r.formatNames(leaf.names), " > "), leaf.failures, leaf.runs, 100*float64(leaf.failures)/float64(leaf.runs))
+	}
+	fmt.Fprintf(out, "%v of %v leaf specs were flaky, %v failed in every run\n", flaky, len(r.leafRuns), failing)
+}
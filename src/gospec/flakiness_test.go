@@ -0,0 +1,108 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sync"
+)
+
+func FlakinessSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+
+	c.Specify("The failing leaf specs are printed with how often they failed", func() {
+		results := newResultCollector()
+		results.recordFlakiness(4, []leafRuns{
+			{[]string{"RootSpec", "Child A"}, 4, 1},
+			{[]string{"RootSpec", "Child B"}, 4, 0},
+			{[]string{"RootSpec", "Child C"}, 4, 4},
+		})
+		results.PrintFlakiness(out)
+		c.Expect(out.String()).Equals("" +
+			"\nFlakiness over 4 runs:\n" +
+			"  RootSpec > Child A: failed 1 of 4 runs (25%)\n" +
+			"  RootSpec > Child C: failed 4 of 4 runs (100%)\n" +
+			"1 of 3 leaf specs were flaky, 1 failed in every run\n")
+	})
+	c.Specify("All specs are executed the given number of times", func() {
+		var lock sync.Mutex
+		executions := 0
+		r := NewRunner()
+		r.SetRunCount(3)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				lock.Lock()
+				executions++
+				lock.Unlock()
+			})
+			c.Specify("Child B", func() {})
+		})
+		r.Run()
+
+		c.Expect(executions).Equals(3)
+		c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A
+  - Child B
+
+3 specs, 0 failures
+`))
+	})
+	c.Specify("The runs of every leaf spec are recorded", func() {
+		var lock sync.Mutex
+		executions := 0
+		r := NewRunner()
+		r.SetRunCount(4)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Flaky", func() {
+				lock.Lock()
+				executions++
+				first := executions == 1
+				lock.Unlock()
+				if first {
+					c.Expect(1, Equals, 2)
+				}
+			})
+			c.Specify("Stable", func() {})
+		})
+		r.Run()
+		r.Results().PrintFlakiness(out)
+
+		c.Expect(out.String()).Equals("" +
+			"\nFlakiness over 4 runs:\n" +
+			"  RootSpec > Flaky: failed 1 of 4 runs (25%)\n" +
+			"1 of 2 leaf specs were flaky, 0 failed in every run\n")
+		c.Expect(r.Results().FailCount()).Equals(1)
+	})
+	c.Specify("Every run gets a different random seed", func() {
+		var lock sync.Mutex
+		seen := make(map[int64]bool)
+		r := NewRunner()
+		r.SetRunCount(3)
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			lock.Lock()
+			seen[c.Rand().Int63()] = true
+			lock.Unlock()
+		})
+		r.Run()
+
+		c.Expect(len(seen)).Equals(3)
+	})
+	c.Specify("A run count less than 1 is an error, and the specs are run once", func() {
+		r := NewRunner()
+		r.SetRunCount(0)
+		r.AddSpec(DummySpecWithNoChildren)
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportContains("the run count must be at least 1, but was 0"))
+		c.Expect(r.Results()).Matches(ReportContains("1 specs, 0 failures"))
+	})
+	c.Specify("The runs are not recorded unless requested", func() {
+		r := NewRunner()
+		r.SetRunCount(1)
+		c.Expect(len(r.listeners)).Equals(0)
+	})
+}
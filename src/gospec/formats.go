@@ -0,0 +1,58 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// The formats which are known by the IsValid matcher.
+var formats = struct {
+	sync.RWMutex
+	validators map[string]func(string) bool
+}{validators: map[string]func(string) bool{
+	"email": isEmail,
+	"url":   isUrl,
+	"uuid":  isUuid,
+}}
+
+// Makes a format known by the IsValid matcher, or replaces a known format.
+// The validator must return true when the string is valid. Formats are
+// shared by all specs, so they should be registered before the specs are run,
+// for example in the same place where the specs are added to the Runner.
+func RegisterFormat(name string, validator func(string) bool) {
+	formats.Lock()
+	defer formats.Unlock()
+	formats.validators[name] = validator
+}
+
+func formatValidator(name string) (validator func(string) bool, found bool) {
+	formats.RLock()
+	defer formats.RUnlock()
+	validator, found = formats.validators[name]
+	return
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isUuid(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// Only a plain address, such as "john@example.com", is valid.
+// Addresses with a display name, such as "John <john@example.com>", are not.
+func isEmail(s string) bool {
+	address, err := mail.ParseAddress(s)
+	return err == nil && address.Address == s
+}
+
+// Only absolute URLs, such as "http://example.com/", are valid.
+func isUrl(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+}
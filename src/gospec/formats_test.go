@@ -0,0 +1,36 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func FormatsSpec(c nanospec.Context) {
+	c.Specify("UUIDs", func() {
+		c.Expect(isUuid("123e4567-e89b-12d3-a456-426614174000")).IsTrue()
+		c.Expect(isUuid("123E4567-E89B-12D3-A456-426614174000")).IsTrue()
+		c.Expect(isUuid("123e4567e89b12d3a456426614174000")).IsFalse()
+		c.Expect(isUuid("123e4567-e89b-12d3-a456-42661417400")).IsFalse()
+		c.Expect(isUuid("g23e4567-e89b-12d3-a456-426614174000")).IsFalse()
+	})
+	c.Specify("Email addresses", func() {
+		c.Expect(isEmail("john@example.com")).IsTrue()
+		c.Expect(isEmail("john.doe+spam@mail.example.com")).IsTrue()
+		c.Expect(isEmail("John <john@example.com>")).IsFalse()
+		c.Expect(isEmail("john")).IsFalse()
+		c.Expect(isEmail("john@")).IsFalse()
+		c.Expect(isEmail("")).IsFalse()
+	})
+	c.Specify("URLs", func() {
+		c.Expect(isUrl("http://example.com")).IsTrue()
+		c.Expect(isUrl("https://example.com/path?query=1#fragment")).IsTrue()
+		c.Expect(isUrl("mailto:john@example.com")).IsTrue()
+		c.Expect(isUrl("/relative/path")).IsFalse()
+		c.Expect(isUrl("example.com")).IsFalse()
+		c.Expect(isUrl("http://exa mple.com")).IsFalse()
+		c.Expect(isUrl("")).IsFalse()
+	})
+}
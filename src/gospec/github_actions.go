@@ -0,0 +1,121 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PrintFormat for GitHub Actions. Prints the same report as DefaultPrintFormat
+// inside a collapsible group, and after the summary an error annotation for
+// every failure, so that the failures are shown at the failing lines in
+// the pull request. The file paths of the annotations are relative to the
+// GITHUB_WORKSPACE directory, when the files are inside it.
+func GithubActionsPrintFormat(out io.Writer) PrintFormat {
+	return newGithubActionsPrintFormat(out, out)
+}
+
+// Prints the annotations to 'annotationOut', so that they are shown
+// also when the report is not printed.
+func newGithubActionsPrintFormat(out io.Writer, annotationOut io.Writer) *githubActionsPrintFormat {
+	return &githubActionsPrintFormat{
+		report:        DefaultPrintFormat(out),
+		out:           out,
+		annotationOut: annotationOut,
+		workspace:     os.Getenv("GITHUB_WORKSPACE"),
+	}
+}
+
+type githubActionsPrintFormat struct {
+	report        PrintFormat
+	out           io.Writer
+	annotationOut io.Writer
+	workspace     string
+	groupOpen     bool
+	annotations   []string
+}
+
+func (this *githubActionsPrintFormat) PrintPassing(nestingLevel int, name string) {
+	this.openGroup()
+	this.report.PrintPassing(nestingLevel, name)
+}
+
+func (this *githubActionsPrintFormat) PrintFailing(nestingLevel int, name string, errors []*Error) {
+	this.openGroup()
+	this.report.PrintFailing(nestingLevel, name, errors)
+	for _, error := range errors {
+		this.annotations = append(this.annotations, this.annotation(name, error))
+	}
+}
+
+func (this *githubActionsPrintFormat) PrintSummary(passCount int, failCount int) {
+	this.openGroup()
+	this.report.PrintSummary(passCount, failCount)
+	this.endReport()
+}
+
+func (this *githubActionsPrintFormat) PrintSummaryWithRunErrors(passCount int, failCount int, runErrorCount int) {
+	this.openGroup()
+	this.report.(RunErrorSummaryFormat).PrintSummaryWithRunErrors(passCount, failCount, runErrorCount)
+	this.endReport()
+}
+
+func (this *githubActionsPrintFormat) endReport() {
+	fmt.Fprint(this.out, "::endgroup::\n")
+	this.groupOpen = false
+	for _, annotation := range this.annotations {
+		fmt.Fprint(this.annotationOut, annotation)
+	}
+	this.annotations = nil
+}
+
+func (this *githubActionsPrintFormat) openGroup() {
+	if !this.groupOpen {
+		fmt.Fprint(this.out, "::group::GoSpec report\n")
+		this.groupOpen = true
+	}
+}
+
+func (this *githubActionsPrintFormat) annotation(name string, error *Error) string {
+	properties := "title=" + escapeGithubProperty(name)
+	if len(error.StackTrace) > 0 {
+		loc := error.StackTrace[0]
+		properties = fmt.Sprintf("file=%v,line=%v,%v", escapeGithubProperty(this.relativePath(loc.File())), loc.Line(), properties)
+	}
+	message := strings.TrimSuffix(formatErrorMessage(error), "\n")
+	return fmt.Sprintf("::error %v::%v\n", properties, escapeGithubData(message))
+}
+
+func (this *githubActionsPrintFormat) relativePath(file string) string {
+	if this.workspace == "" {
+		return file
+	}
+	rel, err := filepath.Rel(this.workspace, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Escapes the message of a workflow command.
+func escapeGithubData(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	s = strings.Replace(s, "\r", "%0D", -1)
+	s = strings.Replace(s, "\n", "%0A", -1)
+	return s
+}
+
+// Escapes the value of a workflow command property, which
+// additionally must not contain the separators of the properties.
+func escapeGithubProperty(s string) string {
+	s = escapeGithubData(s)
+	s = strings.Replace(s, ":", "%3A", -1)
+	s = strings.Replace(s, ",", "%2C", -1)
+	return s
+}
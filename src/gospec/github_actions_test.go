@@ -0,0 +1,55 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"io/ioutil"
+)
+
+func GithubActionsSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	format := GithubActionsPrintFormat(out).(*githubActionsPrintFormat)
+	format.workspace = "/work"
+	location := &Location{"gospec.SomeSpec", "/work/src/some_test.go", 12}
+	failure := newError(ExpectFailed, "equals “2”", "1", []*Location{location})
+
+	c.Specify("The report is printed in a group and the failures are annotated after it", func() {
+		format.PrintPassing(0, "RootSpec")
+		format.PrintFailing(1, "Child A", []*Error{failure})
+		format.PrintSummary(1, 1)
+		c.Expect(out.String()).Equals("" +
+			"::group::GoSpec report\n" +
+			"\nRootSpec\n" +
+			"  - Child A [FAIL]\n\n" +
+			"*** Expected: equals “2”\n" +
+			"         got: “1”\n" +
+			"    gospec.SomeSpec()\n" +
+			"        at /work/src/some_test.go:12\n\n\n" +
+			"\n2 specs, 1 failures\n" +
+			"::endgroup::\n" +
+			"::error file=src/some_test.go,line=12,title=Child A::*** Expected: equals “2”%0A         got: “1”\n")
+	})
+	c.Specify("The annotations can be printed without the report", func() {
+		annotations := new(bytes.Buffer)
+		format := newGithubActionsPrintFormat(ioutil.Discard, annotations)
+		format.workspace = "/work"
+		format.PrintFailing(1, "Child A", []*Error{failure})
+		format.PrintSummary(0, 1)
+		c.Expect(annotations.String()).Equals(
+			"::error file=src/some_test.go,line=12,title=Child A::*** Expected: equals “2”%0A         got: “1”\n")
+	})
+	c.Specify("Files outside the workspace keep their full path", func() {
+		c.Expect(format.relativePath("/other/some_test.go")).Equals("/other/some_test.go")
+	})
+	c.Specify("Errors without a stack trace are annotated without a location", func() {
+		c.Expect(format.annotation("Run", newError(OtherError, "100% broken", "", []*Location{}))).Equals(
+			"::error title=Run::*** 100%25 broken\n")
+	})
+	c.Specify("The separators of the properties are escaped", func() {
+		c.Expect(escapeGithubProperty("a, b: c\nd")).Equals("a%2C b%3A c%0Ad")
+	})
+}
@@ -0,0 +1,413 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The actual value must be valid according to the JSON Schema.
+// The actual value may be a string or []byte of JSON, or any other value,
+// which is then marshaled to JSON with the encoding/json package.
+// Every validation error is reported with the JSON path of the invalid
+// value. Example:
+//    c.Expect(payload, MatchesSchema(`{"type": "object", "required": ["id"]}`))
+//
+// Only a subset of JSON Schema (draft 2020-12) is supported: the keywords
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, format, minimum,
+// maximum, exclusiveMinimum and exclusiveMaximum. The annotations, such as
+// title and description, are allowed, but all other keywords, such as $ref
+// and anyOf, give an error instead of being ignored, so that a schema is
+// never more permissive than it was written. The supported keywords differ
+// from the specification in these ways:
+//   - items must be a single schema for all items, not an array of schemas
+//   - exclusiveMinimum and exclusiveMaximum must be numbers, not booleans
+//   - pattern is a regular expression of the regexp package (RE2 syntax),
+//     not of ECMA-262
+//   - format is always validated, instead of being only an annotation, and
+//     the formats are those known by the IsValid matcher (see RegisterFormat),
+//     so that an unknown format is an error instead of being ignored
+// The schema is parsed once, when the matcher is created.
+func MatchesSchema(schema string) Matcher {
+	s, schemaErr := parseJsonSchema(schema)
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		if schemaErr != nil {
+			err = schemaErr
+			return
+		}
+		value, err := toJsonValue(actual)
+		if err != nil {
+			return
+		}
+
+		problems := s.validate(value, "$")
+		match = len(problems) == 0
+		if match {
+			pos = Messagef(actual, "matches the JSON schema")
+		} else {
+			pos = Messagef(actual, "matches the JSON schema, but:\n  %v", strings.Join(problems, "\n  "))
+		}
+		neg = Messagef(actual, "does NOT match the JSON schema")
+		return
+	}
+}
+
+func toJsonValue(value interface{}) (result interface{}, err error) {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		if data, err = json.Marshal(value); err != nil {
+			return nil, Errorf("cannot marshal “%v” to JSON: %v", value, err)
+		}
+	}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, Errorf("cannot unmarshal JSON “%v”: %v", string(data), err)
+	}
+	return
+}
+
+type jsonSchema struct {
+	types                []string
+	enum                 []interface{}
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+	items                *jsonSchema
+	minItems, maxItems   *float64
+	minLength, maxLength *float64
+	pattern              *regexp.Regexp
+	format               string
+	minimum, maximum     *float64
+	exclusiveMin         *float64
+	exclusiveMax         *float64
+}
+
+func parseJsonSchema(schema string) (*jsonSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, Errorf("invalid JSON schema: %v", err)
+	}
+	return compileJsonSchema(raw, "#")
+}
+
+func compileJsonSchema(raw interface{}, at string) (*jsonSchema, error) {
+	if b, ok := raw.(bool); ok {
+		// "true" allows everything and "false" allows nothing.
+		if b {
+			return &jsonSchema{}, nil
+		}
+		return &jsonSchema{enum: []interface{}{}}, nil
+	}
+	keywords, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, Errorf("invalid JSON schema: %v must be an object, but was “%v”", at, raw)
+	}
+	for _, keyword := range sortedJsonKeys(keywords) {
+		if !supportedJsonKeywords[keyword] {
+			return nil, Errorf("invalid JSON schema: unsupported keyword %q at %v", keyword, at)
+		}
+	}
+	s := new(jsonSchema)
+	invalid := func(keyword string, expected string) error {
+		return Errorf("invalid JSON schema: %q at %v must be %v, but was “%v”", keyword, at, expected, keywords[keyword])
+	}
+
+	switch t := keywords["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, element := range t {
+			name, ok := element.(string)
+			if !ok {
+				return nil, invalid("type", "a string or an array of strings")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, invalid("type", "a string or an array of strings")
+	}
+	for _, name := range s.types {
+		if !isJsonType(name) {
+			return nil, invalid("type", "a JSON type")
+		}
+	}
+
+	if enum, found := keywords["enum"]; found {
+		if s.enum, ok = enum.([]interface{}); !ok {
+			return nil, invalid("enum", "an array")
+		}
+	}
+	if constant, found := keywords["const"]; found {
+		// With both, the value must be the constant and one of the enum.
+		if s.enum == nil || containsJsonValue(s.enum, constant) {
+			s.enum = []interface{}{constant}
+		} else {
+			s.enum = []interface{}{}
+		}
+	}
+
+	if properties, found := keywords["properties"]; found {
+		m, ok := properties.(map[string]interface{})
+		if !ok {
+			return nil, invalid("properties", "an object")
+		}
+		s.properties = make(map[string]*jsonSchema)
+		for name, property := range m {
+			compiled, err := compileJsonSchema(property, at+"/properties/"+name)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = compiled
+		}
+	}
+	if required, found := keywords["required"]; found {
+		names, ok := required.([]interface{})
+		if !ok {
+			return nil, invalid("required", "an array of strings")
+		}
+		for _, element := range names {
+			name, ok := element.(string)
+			if !ok {
+				return nil, invalid("required", "an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	switch additional := keywords["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.noAdditional = !additional
+	default:
+		compiled, err := compileJsonSchema(additional, at+"/additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		s.additionalProperties = compiled
+	}
+	if items, found := keywords["items"]; found {
+		compiled, err := compileJsonSchema(items, at+"/items")
+		if err != nil {
+			return nil, err
+		}
+		s.items = compiled
+	}
+
+	numbers := []struct {
+		keyword string
+		target  **float64
+	}{
+		{"minItems", &s.minItems},
+		{"maxItems", &s.maxItems},
+		{"minLength", &s.minLength},
+		{"maxLength", &s.maxLength},
+		{"minimum", &s.minimum},
+		{"maximum", &s.maximum},
+		{"exclusiveMinimum", &s.exclusiveMin},
+		{"exclusiveMaximum", &s.exclusiveMax},
+	}
+	for _, n := range numbers {
+		if value, found := keywords[n.keyword]; found {
+			number, ok := value.(float64)
+			if !ok {
+				return nil, invalid(n.keyword, "a number")
+			}
+			*n.target = &number
+		}
+	}
+
+	if pattern, found := keywords["pattern"]; found {
+		expr, ok := pattern.(string)
+		if !ok {
+			return nil, invalid("pattern", "a string")
+		}
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, invalid("pattern", "a regular expression")
+		}
+		s.pattern = compiled
+	}
+	if format, found := keywords["format"]; found {
+		name, ok := format.(string)
+		if !ok {
+			return nil, invalid("format", "a string")
+		}
+		if _, known := formatValidator(name); !known {
+			return nil, invalid("format", "a known format")
+		}
+		s.format = name
+	}
+	return s, nil
+}
+
+// The keywords which are validated, and the annotations
+// which do not affect the validation.
+var supportedJsonKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "format": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+func isJsonType(name string) bool {
+	switch name {
+	case "null", "boolean", "number", "integer", "string", "array", "object":
+		return true
+	}
+	return false
+}
+
+// The type of a value decoded by encoding/json. Numbers
+// are "number", even when they could also be "integer".
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// Returns the validation errors, each prefixed with
+// the JSON path of the invalid value.
+func (s *jsonSchema) validate(value interface{}, path string) []string {
+	problems := make([]string, 0)
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.types) > 0 && !s.allowsType(value) {
+		report("expected type %v, but was %v", strings.Join(s.types, " or "), jsonTypeOf(value))
+		return problems
+	}
+	if s.enum != nil && !containsJsonValue(s.enum, value) {
+		report("expected one of %v, but was %v", toJsonString(s.enum), toJsonString(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, found := v[name]; !found {
+				report("missing required property %q", name)
+			}
+		}
+		for _, name := range sortedJsonKeys(v) {
+			property := path + "." + name
+			if schema, found := s.properties[name]; found {
+				problems = append(problems, schema.validate(v[name], property)...)
+			} else if s.additionalProperties != nil {
+				problems = append(problems, s.additionalProperties.validate(v[name], property)...)
+			} else if s.noAdditional {
+				report("unexpected property %q", name)
+			}
+		}
+
+	case []interface{}:
+		if s.minItems != nil && float64(len(v)) < *s.minItems {
+			report("expected at least %v items, but had %v", *s.minItems, len(v))
+		}
+		if s.maxItems != nil && float64(len(v)) > *s.maxItems {
+			report("expected at most %v items, but had %v", *s.maxItems, len(v))
+		}
+		if s.items != nil {
+			for i, item := range v {
+				problems = append(problems, s.items.validate(item, fmt.Sprintf("%v[%v]", path, i))...)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if s.minLength != nil && length < *s.minLength {
+			report("expected at least %v characters, but had %v", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("expected at most %v characters, but had %v", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("expected to match pattern %q, but was %q", s.pattern, v)
+		}
+		if s.format != "" {
+			if validator, _ := formatValidator(s.format); !validator(v) {
+				report("expected format %v, but was %q", s.format, v)
+			}
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			report("expected at least %v, but was %v", *s.minimum, v)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			report("expected at most %v, but was %v", *s.maximum, v)
+		}
+		if s.exclusiveMin != nil && v <= *s.exclusiveMin {
+			report("expected more than %v, but was %v", *s.exclusiveMin, v)
+		}
+		if s.exclusiveMax != nil && v >= *s.exclusiveMax {
+			report("expected less than %v, but was %v", *s.exclusiveMax, v)
+		}
+	}
+	return problems
+}
+
+func (s *jsonSchema) allowsType(value interface{}) bool {
+	actual := jsonTypeOf(value)
+	for _, allowed := range s.types {
+		if allowed == actual {
+			return true
+		}
+		if number, ok := value.(float64); ok && allowed == "integer" && number == math.Trunc(number) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsJsonValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func toJsonString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func sortedJsonKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
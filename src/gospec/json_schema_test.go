@@ -0,0 +1,98 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func JsonSchemaSpec(c nanospec.Context) {
+	problems := func(schema string, value string) string {
+		s, err := parseJsonSchema(schema)
+		if err != nil {
+			return err.Error()
+		}
+		v, err := toJsonValue(value)
+		if err != nil {
+			return err.Error()
+		}
+		return strings.Join(s.validate(v, "$"), "\n")
+	}
+
+	c.Specify("Types", func() {
+		c.Expect(problems(`{"type": "string"}`, `"x"`)).Equals("")
+		c.Expect(problems(`{"type": "string"}`, `1`)).Equals("$: expected type string, but was number")
+		c.Expect(problems(`{"type": ["string", "null"]}`, `null`)).Equals("")
+		c.Expect(problems(`{"type": "integer"}`, `3`)).Equals("")
+		c.Expect(problems(`{"type": "integer"}`, `3.5`)).Equals("$: expected type integer, but was number")
+		c.Expect(problems(`{"type": "object"}`, `[]`)).Equals("$: expected type object, but was array")
+	})
+	c.Specify("Enums and constants", func() {
+		c.Expect(problems(`{"enum": ["a", 1]}`, `1`)).Equals("")
+		c.Expect(problems(`{"enum": ["a", 1]}`, `"b"`)).Equals(`$: expected one of ["a",1], but was "b"`)
+		c.Expect(problems(`{"const": {"a": 1}}`, `{"a": 2}`)).Equals(`$: expected one of [{"a":1}], but was {"a":2}`)
+		c.Expect(problems(`{"enum": ["a", "b"], "const": "b"}`, `"a"`)).Equals(`$: expected one of ["b"], but was "a"`)
+		c.Expect(problems(`{"enum": ["a"], "const": "b"}`, `"b"`)).Equals(`$: expected one of [], but was "b"`)
+	})
+	c.Specify("Objects", func() {
+		schema := `{
+			"type": "object",
+			"required": ["id", "name"],
+			"properties": {"id": {"type": "integer"}, "name": {"type": "string"}},
+			"additionalProperties": false
+		}`
+		c.Expect(problems(schema, `{"id": 1, "name": "x"}`)).Equals("")
+		c.Expect(problems(schema, `{"id": "1", "extra": true}`)).Equals(
+			"$: missing required property \"name\"\n" +
+				"$: unexpected property \"extra\"\n" +
+				"$.id: expected type integer, but was string")
+		c.Expect(problems(`{"additionalProperties": {"type": "number"}}`, `{"a": 1, "b": "2"}`)).Equals(
+			"$.b: expected type number, but was string")
+	})
+	c.Specify("Arrays", func() {
+		schema := `{"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string"}}`
+		c.Expect(problems(schema, `["a", "b"]`)).Equals("")
+		c.Expect(problems(schema, `[]`)).Equals("$: expected at least 1 items, but had 0")
+		c.Expect(problems(schema, `["a", 2, 3]`)).Equals(
+			"$: expected at most 2 items, but had 3\n" +
+				"$[1]: expected type string, but was number\n" +
+				"$[2]: expected type string, but was number")
+	})
+	c.Specify("Strings", func() {
+		c.Expect(problems(`{"minLength": 2, "maxLength": 3}`, `"äö"`)).Equals("")
+		c.Expect(problems(`{"minLength": 2}`, `"a"`)).Equals("$: expected at least 2 characters, but had 1")
+		c.Expect(problems(`{"maxLength": 2}`, `"abc"`)).Equals("$: expected at most 2 characters, but had 3")
+		c.Expect(problems(`{"pattern": "^[a-z]+$"}`, `"abc1"`)).Equals(`$: expected to match pattern "^[a-z]+$", but was "abc1"`)
+		c.Expect(problems(`{"format": "email"}`, `"john"`)).Equals(`$: expected format email, but was "john"`)
+	})
+	c.Specify("Numbers", func() {
+		c.Expect(problems(`{"minimum": 1, "maximum": 3}`, `3`)).Equals("")
+		c.Expect(problems(`{"minimum": 1}`, `0`)).Equals("$: expected at least 1, but was 0")
+		c.Expect(problems(`{"maximum": 3}`, `4`)).Equals("$: expected at most 3, but was 4")
+		c.Expect(problems(`{"exclusiveMinimum": 1}`, `1`)).Equals("$: expected more than 1, but was 1")
+		c.Expect(problems(`{"exclusiveMaximum": 3}`, `3`)).Equals("$: expected less than 3, but was 3")
+	})
+	c.Specify("Boolean schemas", func() {
+		c.Expect(problems(`{"properties": {"a": true, "b": false}}`, `{"a": 1}`)).Equals("")
+		c.Expect(problems(`{"properties": {"a": true, "b": false}}`, `{"b": 1}`)).Equals("$.b: expected one of [], but was 1")
+	})
+	c.Specify("Invalid schemas", func() {
+		c.Expect(problems(`{"type": }`, `1`)).Equals("invalid JSON schema: invalid character '}' looking for beginning of value")
+		c.Expect(problems(`[]`, `1`)).Equals("invalid JSON schema: # must be an object, but was “[]”")
+		c.Expect(problems(`{"type": "text"}`, `1`)).Equals(`invalid JSON schema: "type" at # must be a JSON type, but was “text”`)
+		c.Expect(problems(`{"properties": {"a": {"minimum": "1"}}}`, `1`)).Equals(`invalid JSON schema: "minimum" at #/properties/a must be a number, but was “1”`)
+		c.Expect(problems(`{"pattern": "("}`, `1`)).Equals(`invalid JSON schema: "pattern" at # must be a regular expression, but was “(”`)
+		c.Expect(problems(`{"format": "nonexistent"}`, `1`)).Equals(`invalid JSON schema: "format" at # must be a known format, but was “nonexistent”`)
+		c.Expect(problems(`{"exclusiveMinimum": true}`, `1`)).Equals(`invalid JSON schema: "exclusiveMinimum" at # must be a number, but was “true”`)
+		c.Expect(problems(`{"items": [{"type": "string"}]}`, `[]`)).Equals("invalid JSON schema: #/items must be an object, but was “[map[type:string]]”")
+	})
+	c.Specify("Unsupported keywords are errors, instead of being ignored", func() {
+		c.Expect(problems(`{"anyOf": [{"type": "string"}]}`, `1`)).Equals(`invalid JSON schema: unsupported keyword "anyOf" at #`)
+		c.Expect(problems(`{"items": {"$ref": "#/$defs/item"}}`, `[]`)).Equals(`invalid JSON schema: unsupported keyword "$ref" at #/items`)
+		c.Expect(problems(`{"uniqueItems": true, "multipleOf": 2}`, `[]`)).Equals(`invalid JSON schema: unsupported keyword "multipleOf" at #`)
+		c.Expect(problems(`{"title": "Point", "description": "A point", "type": "object"}`, `{}`)).Equals("")
+	})
+}
@@ -39,6 +39,16 @@ func locationForPC(pc uintptr) *Location {
 	return &Location{name, file, line}
 }
 
+// Location where the function was declared.
+func functionLocation(function interface{}) *Location {
+	f := functionToFunc(function)
+	if f == nil {
+		return nil
+	}
+	file, line := f.FileLine(f.Entry())
+	return &Location{f.Name(), file, line}
+}
+
 // Quoted from http://code.google.com/p/go/issues/detail?id=1100
 //   "It's a subtle thing, but runtime.Callers returns the return PCs
 //   going up the stack.  The return PCs are the PCs of the instruction
@@ -5,13 +5,42 @@
 package gospec
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"testing"
 )
 
 var (
-	printAll = flag.Bool("print-all", false, "print also passing specs and not only failing (GoSpec)")
+	allocs            = flag.Bool("allocs", false, "measure and print the heap allocations of every spec (GoSpec)")
+	collapse          = flag.Bool("collapse", false, "print chains of specs which have only one child on one line (GoSpec)")
+	deadline          = flag.Duration("deadline", 0, "stop the run if it takes longer than this, e.g. 10m (GoSpec)")
+	declarationOrder  = flag.Bool("declaration-order", false, "print the root specs in the order they were added instead of alphabetically (GoSpec)")
+	deepest           = flag.Int("deepest", 0, "print this many of the most deeply nested leaf specs, to find specs which are nested too deeply (GoSpec)")
+	durations         = flag.Bool("durations", false, "print how long every leaf spec took, and for the other specs the total of their leaf specs (GoSpec)")
+	executionOrder    = flag.Bool("execution-order", false, "print the leaf specs in the order in which they were executed, with their durations (GoSpec)")
+	expectations      = flag.Bool("expectations", false, "print under every spec the expectations which it evaluated, also the passing ones with -print-all (GoSpec)")
+	failOnErrorLog    = flag.Bool("fail-on-error-log", false, "fail the specs which captured the slog records with c.CaptureSlog() if they logged an error (GoSpec)")
+	failuresOnly      = flag.Bool("failures-only", false, "report only the failing specs and the number of failures in the test log, use -v to print also the report (GoSpec)")
+	githubActions     = flag.Bool("github-actions", false, "print the report in a group and annotate the failures for GitHub Actions (GoSpec)")
+	groupFailures     = flag.Bool("group-failures", false, "print identical failures of many specs only once, with the names of the failing specs (GoSpec)")
+	locations         = flag.Bool("locations", false, "print where every spec was declared (GoSpec)")
+	maxFailures       = flag.Int("max-failures", 0, "stop starting new specs after this many specs have failed (GoSpec)")
+	printAll          = flag.Bool("print-all", false, "print also passing specs and not only failing (GoSpec)")
+	printConfig       = flag.Bool("print-config", false, "print the configuration which was used to run the specs (GoSpec)")
+	rerunFailed       = flag.String("rerun-failed", "", "execute only the specs which failed in the previous run with this failures file, then update the file (GoSpec)")
+	runs              = flag.Int("runs", 1, "execute all specs this many times and print how often each failing spec failed, to find flaky specs (GoSpec)")
+	serial            = flag.Bool("serial", false, "execute only one spec at a time (GoSpec)")
+	seed              = flag.Int64("seed", 0, "seed for the random sources of the specs, by default based on the current time (GoSpec)")
+	stream            = flag.Bool("stream", false, "print the results of every root spec as soon as it has been executed, instead of after the run (GoSpec)")
+	summaryLine       = flag.Bool("summary-line", false, "print after the report the numbers of the specs on one line of key=value pairs, for log processors (GoSpec)")
+	progress          = flag.Duration("progress", 0, "print the progress to stderr at this interval, e.g. 10s (GoSpec)")
+	updateGoldenFiles = flag.Bool("update-golden", false, "write the actual values to golden files instead of comparing them (GoSpec)")
+	validate          = flag.Bool("validate", false, "fail the run if the specs have mistakes such as sibling specs with the same name or specs without expectations (GoSpec)")
+	watch             = flag.Bool("watch", false, "print instead of the report only the number of failures and the first failure, for file watchers (GoSpec)")
 )
 
 // Executes the specs which have been added to the Runner
@@ -20,8 +49,8 @@ var (
 // depending on whether any specs failed.
 func Main(runner *Runner) {
 	flag.Parse()
-	results := runAndPrint(runner)
-	if results.FailCount() > 0 {
+	results := runAndPrint(runner, os.Stdout, os.Stdout)
+	if results.hasFailures() {
 		os.Exit(1)
 	} else {
 		os.Exit(0)
@@ -30,29 +59,175 @@ func Main(runner *Runner) {
 
 // Executes the specs which have been added to the Runner
 // and prints the results to stdout. Fails the surrounding
-// test if any of the specs fails.
+// test if any of the specs fails. With the -failures-only
+// parameter, only the failing specs and the number of failures
+// are reported, in the log of the test, and the report is printed
+// to stdout only when the test is run with -v. The annotations of
+// -github-actions are printed to stdout also without -v.
 func MainGoTest(runner *Runner, t *testing.T) {
 	// Assume that this method will then be executed by gotest and
 	// flag.Parse() has already been called in testing.Main() so
 	// we don't need to call it here.
 
-	results := runAndPrint(runner)
-	if results.FailCount() > 0 {
-		t.Fail()
+	out := io.Writer(os.Stdout)
+	if *failuresOnly && !testing.Verbose() {
+		out = ioutil.Discard
 	}
+	// The annotations of GitHub Actions are needed also without the report.
+	results := runAndPrint(runner, out, os.Stdout)
+	if results.hasFailures() {
+		if *failuresOnly {
+			t.Error(failuresReport(results))
+		} else {
+			t.Fail()
+		}
+	}
+}
+
+// The failing specs and the summary, without the passing specs,
+// printed with the same options as the report.
+func failuresReport(results *ResultCollector) string {
+	out := new(bytes.Buffer)
+	printer := newConfiguredPrinter(reportFormat(out))
+	printer.ShowOnlyFailing()
+	results.Visit(printer)
+	printRandomSeed(out, results)
+	return out.String()
 }
 
-func runAndPrint(runner *Runner) *ResultCollector {
-	printer := NewPrinter(DefaultPrintFormat(os.Stdout))
+func printRandomSeed(out io.Writer, results *ResultCollector) {
+	if seed, used := results.RandomSeed(); used && results.hasFailures() {
+		fmt.Fprintf(out, "\nRandom seed: %v (use -seed=%v to repeat the run)\n", seed, seed)
+	}
+}
+
+// The format of the report, without the annotations of GitHub Actions.
+func reportFormat(out io.Writer) PrintFormat {
+	if *watch {
+		return WatchPrintFormat(out)
+	}
+	return DefaultPrintFormat(out)
+}
+
+// A printer with the options which are given with the flags.
+func newConfiguredPrinter(format PrintFormat) *Printer {
+	printer := NewPrinter(format)
 	if *printAll {
 		printer.ShowAll()
 	} else {
 		printer.ShowOnlyFailing()
 	}
 	printer.ShowSummary()
+	if *collapse {
+		printer.CollapseSingleChildChains()
+	}
+	if *locations {
+		printer.ShowDeclarationLocations()
+	}
+	if *durations {
+		printer.ShowDurations()
+	}
+	if *groupFailures {
+		printer.GroupIdenticalFailures()
+	}
+	if *allocs {
+		printer.ShowAllocations()
+	}
+	if *expectations {
+		printer.ShowExpectations()
+	}
+	return printer
+}
+
+func runAndPrint(runner *Runner, out io.Writer, annotationOut io.Writer) *ResultCollector {
+	format := reportFormat(out)
+	if *githubActions && !*watch {
+		format = newGithubActionsPrintFormat(out, annotationOut)
+	}
+	printer := newConfiguredPrinter(format)
+	if *progress > 0 {
+		runner.ShowProgress(os.Stderr, *progress)
+	}
+	if *serial {
+		runner.SetSerial(true)
+	}
+	if *deadline > 0 {
+		runner.SetDeadline(*deadline)
+	}
+	if *maxFailures > 0 {
+		runner.SetMaxFailures(*maxFailures)
+	}
+	if *allocs {
+		runner.SetMeasureAllocations(true)
+	}
+	if *declarationOrder {
+		runner.SetRootOrder(DeclarationOrder)
+	}
+	if *rerunFailed != "" {
+		if err := runner.SetRerunFailedFrom(*rerunFailed); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Cannot read the failures file: %v\n", err)
+		}
+	}
+	if *executionOrder {
+		runner.RecordExecutionOrder()
+	}
+	if *failOnErrorLog {
+		runner.SetFailOnErrorLog(true)
+	}
+	if *expectations {
+		runner.RecordExpectations()
+	}
+	if *runs > 1 {
+		runner.SetRunCount(*runs)
+	}
+	if isFlagSet("seed") {
+		runner.SetRandomSeed(*seed)
+	}
+	if *updateGoldenFiles {
+		runner.SetUpdateGolden(true)
+	}
+	if *validate {
+		runner.SetFailOnValidationIssues(true)
+	}
+	if *stream {
+		runner.StreamReport(printer)
+	}
 
 	runner.Run()
+	if *rerunFailed != "" {
+		if err := runner.WriteFailuresFile(*rerunFailed); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot write the failures file: %v\n", err)
+		}
+	}
 	results := runner.Results()
-	results.Visit(printer)
+	if !*stream {
+		results.Visit(printer)
+	}
+	printRandomSeed(out, results)
+	if *executionOrder {
+		results.PrintExecutionOrder(out)
+	}
+	if *runs > 1 {
+		results.PrintFlakiness(out)
+	}
+	if *deepest > 0 {
+		results.PrintDeepest(out, *deepest)
+	}
+	if *printConfig {
+		results.PrintConfig(out)
+	}
+	if *summaryLine {
+		results.PrintSummaryLine(out)
+	}
 	return results
 }
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
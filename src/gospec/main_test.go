@@ -0,0 +1,34 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func MainSpec(c nanospec.Context) {
+	r := NewRunner()
+	r.AddNamedSpec("RootSpec", func(c Context) {
+		c.Specify("Passing", func() {})
+		c.Specify("Failing", func() {
+			c.Expect(1, Equals, 2)
+		})
+	})
+	r.Run()
+
+	c.Specify("The failures report shows only the failing specs", func() {
+		report := failuresReport(r.Results())
+		c.Expect(strings.Contains(report, "Failing [FAIL]")).IsTrue()
+		c.Expect(strings.Contains(report, "Passing")).IsFalse()
+		c.Expect(strings.Contains(report, "3 specs, 1 failures")).IsTrue()
+	})
+	c.Specify("The failures report is printed with the same options as the report", func() {
+		*locations = true
+		defer func() { *locations = false }()
+		report := failuresReport(r.Results())
+		c.Expect(strings.Contains(report, "main_test.go:16) [FAIL]")).IsTrue()
+	})
+}
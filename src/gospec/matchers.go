@@ -5,29 +5,71 @@
 package gospec
 
 import (
+	"bytes"
 	"container/list"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
 	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type matcherAdapter struct {
 	location    *Location
 	log         errorLogger
 	matcherType ErrorType
+	recorder    expectationRecorder
+
+	// Whether the golden files of MatchesGolden are written instead of
+	// compared, see Runner.SetUpdateGolden.
+	updateGolden bool
+}
+
+// Records also the passing expectations, see Runner.RecordExpectations.
+type expectationRecorder interface {
+	recordExpectation(expectation *Expectation)
 }
 
 func newMatcherAdapter(location *Location, log errorLogger, matcherType ErrorType) *matcherAdapter {
-	return &matcherAdapter{location, log, matcherType}
+	return &matcherAdapter{location: location, log: log, matcherType: matcherType}
 }
 
 func (this *matcherAdapter) Expect(actual interface{}, matcher Matcher, expected ...interface{}) {
 	match, pos, _, err := matcher.Match(actual, expected...)
+	if this.updateGolden && !match {
+		if golden := goldenFileOf(pos, err); golden != nil {
+			match, err = true, golden.update()
+			pos = Messagef(actual, "was written to golden file “%v”", golden.path)
+		}
+	}
 	if err != nil {
 		this.addError(err, actual)
 	} else if !match {
 		this.addFailure(pos)
 	}
+	if this.recorder != nil {
+		description := ""
+		if err != nil {
+			description = err.Error()
+		} else if pos == nil {
+			// The matchers need to give the message only when they fail.
+			description = fmt.Sprintf("“%v” passed", actual)
+		} else {
+			description = fmt.Sprintf("“%v” %v", pos.Actual(), pos.Expectation())
+		}
+		this.recorder.recordExpectation(&Expectation{err == nil && match, description, this.location})
+	}
 }
 
 func (this *matcherAdapter) addFailure(message Message) {
@@ -94,6 +136,16 @@ func (this *message) Expectation() string {
 	return this.expectation.Error()
 }
 
+// The expectation of the message of a matcher, or 'fallback' if there is no
+// message, because the matchers may leave out the messages which they know
+// not to be needed.
+func expectationOr(message Message, fallback string) string {
+	if message == nil {
+		return fallback
+	}
+	return message.Expectation()
+}
+
 // Constructs an error message the same way as fmt.Sprintf(), but the string is
 // created lazily when it is used, if it is used at all. This avoids unnecessary
 // string parsing in matchers, because most of the time there are no failures
@@ -146,6 +198,434 @@ type Equality interface {
 	Equals(other interface{}) bool
 }
 
+// The actual value must marshal to the same JSON as the expected value,
+// when both are marshaled with the encoding/json package. The order of
+// the keys in JSON objects is not significant.
+func EqualsJson(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toNormalizedJson(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toNormalizedJson(expected_)
+	if err != nil {
+		return
+	}
+
+	match = actual == expected
+	pos = Messagef(actual, "equals JSON “%v”, diff:\n%v", expected, lazyLineDiff{expected, actual})
+	neg = Messagef(actual, "does NOT equal JSON “%v”", expected)
+	return
+}
+
+func toNormalizedJson(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", Errorf("cannot marshal “%v” to JSON: %v", value, err)
+	}
+	// encoding/json marshals the keys of maps in sorted order,
+	// so after this also the struct fields will be sorted. The numbers
+	// are kept as they are, because as float64 the large integers would
+	// lose their precision and different integers could become equal.
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return "", Errorf("cannot unmarshal JSON “%v”: %v", string(data), err)
+	}
+	data, err = json.MarshalIndent(generic, "", "  ")
+	if err != nil {
+		return "", Errorf("cannot marshal “%v” to JSON: %v", value, err)
+	}
+	return string(data), nil
+}
+
+// The actual value must be marshalable to JSON with the encoding/json
+// package, so it must not contain for example channels, functions or cyclic
+// references. When the expectations are printed, the JSON is shown after
+// the passing expectation. Example:
+//    c.Expect(payload, IsJsonSerializable)
+func IsJsonSerializable(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	data, marshalErr := json.Marshal(actual)
+	if marshalErr != nil {
+		pos = Messagef(actual, "is serializable to JSON, but: %v", marshalErr)
+		neg = Messagef(actual, "is NOT serializable to JSON")
+		return
+	}
+	match = true
+	pos = Messagef(actual, "is serializable to JSON as “%s”", data)
+	neg = Messagef(actual, "is NOT serializable to JSON, but it was serialized as “%s”", data)
+	return
+}
+
+// The actual string or []byte must equal the contents of the golden file at
+// the given path. When the golden files are being updated, the actual value
+// is written to the file (and its directory is created if necessary) instead.
+// See Runner.SetUpdateGolden.
+func MatchesGolden(path string) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toText(actual_)
+		if err != nil {
+			return
+		}
+		golden := &goldenFile{path, actual}
+
+		data, e := ioutil.ReadFile(path)
+		if e != nil {
+			err = &goldenFileError{Errorf("cannot read golden file: %v", e), golden}
+			return
+		}
+		expected := string(data)
+
+		match = actual == expected
+		pos = &goldenFileMessage{Messagef(actual, "matches golden file “%v”, diff:\n%v", path, lazyLineDiff{expected, actual}), golden}
+		neg = Messagef(actual, "does NOT match golden file “%v”", path)
+		return
+	}
+}
+
+// The golden file which a failed MatchesGolden would be updated with, when
+// the matcherAdapter of the spec is updating the golden files. The matchers
+// have no access to the spec, so the file is passed with the message or the
+// error of the failure.
+type goldenFile struct {
+	path    string
+	content string
+}
+
+type goldenFileMessage struct {
+	Message
+	golden *goldenFile
+}
+
+type goldenFileError struct {
+	error
+	golden *goldenFile
+}
+
+func goldenFileOf(pos Message, err error) *goldenFile {
+	if e, ok := err.(*goldenFileError); ok {
+		return e.golden
+	}
+	if m, ok := pos.(*goldenFileMessage); ok && err == nil {
+		return m.golden
+	}
+	return nil
+}
+
+func (this *goldenFile) update() error {
+	if e := os.MkdirAll(filepath.Dir(this.path), 0755); e != nil {
+		return Errorf("cannot create directory for golden file: %v", e)
+	}
+	if e := ioutil.WriteFile(this.path, []byte(this.content), 0644); e != nil {
+		return Errorf("cannot write golden file: %v", e)
+	}
+	return nil
+}
+
+func toText(value interface{}) (result string, err error) {
+	switch v := value.(type) {
+	case string:
+		result = v
+	case []byte:
+		result = string(v)
+	default:
+		err = Errorf("type error: expected a string or []byte, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+// The bytes read from the actual io.Reader must equal the expected string
+// or []byte. The reader is read until EOF, so the bytes cannot be read from
+// it again, except from a *bytes.Buffer, whose unread bytes are compared
+// without reading them. Example:
+//    c.Expect(out, ReadsAs, "Hello, world!\n")
+func ReadsAs(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	expected, err := toText(expected_)
+	if err != nil {
+		return
+	}
+	var actual string
+	switch r := actual_.(type) {
+	case *bytes.Buffer:
+		actual = r.String()
+	case io.Reader:
+		data, e := ioutil.ReadAll(r)
+		if e != nil {
+			err = Errorf("cannot read from the reader: %v", e)
+			return
+		}
+		actual = string(data)
+	default:
+		err = Errorf("type error: expected an io.Reader, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+
+	match = actual == expected
+	pos = Messagef(actual, "reads as “%v”, diff:\n%v", expected, lazyLineDiff{expected, actual})
+	neg = Messagef(actual, "does NOT read as “%v”", expected)
+	return
+}
+
+const (
+	readerChunkSize = 32 * 1024
+
+	// How many bytes are shown before and after the first difference.
+	readerContextSize = 8
+)
+
+// The actual io.Reader must produce the same bytes as the other io.Reader.
+// The readers are compared one chunk at a time, so that even huge streams
+// need not fit in memory, and the comparison stops at the first difference.
+// Both readers are consumed. Example:
+//    c.Expect(output, ReadsSameAs(golden))
+func ReadsSameAs(other io.Reader) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, ok := actual_.(io.Reader)
+		if !ok {
+			err = Errorf("type error: expected an io.Reader, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+
+		diff, err := firstStreamDifference(actual, other)
+		if err != nil {
+			return
+		}
+		neg = Messagef(actual_, "does NOT read the same bytes as the other reader")
+		switch {
+		case diff == nil:
+			match = true
+			pos = Messagef(actual_, "reads the same bytes as the other reader")
+		case diff.actualEnded:
+			pos = Messagef(actual_, "reads the same bytes as the other reader, but it ended at offset %v, where the other reader had [% x]", diff.offset, diff.other)
+		case diff.otherEnded:
+			pos = Messagef(actual_, "reads the same bytes as the other reader, but the other reader ended at offset %v, where it had [% x]", diff.offset, diff.actual)
+		default:
+			pos = Messagef(actual_, "reads the same bytes as the other reader, but they differed at offset %v, where it had [% x] instead of [% x]", diff.offset, diff.actual, diff.other)
+		}
+		return
+	}
+}
+
+// The first difference of two streams, with the bytes around it.
+type streamDifference struct {
+	offset      int64
+	actual      []byte
+	other       []byte
+	actualEnded bool
+	otherEnded  bool
+}
+
+// Returns nil if the streams had the same bytes.
+func firstStreamDifference(actual io.Reader, other io.Reader) (*streamDifference, error) {
+	a := make([]byte, readerChunkSize)
+	b := make([]byte, readerChunkSize)
+	var offset int64
+	for {
+		na, err := io.ReadFull(actual, a)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, Errorf("cannot read from the reader: %v", err)
+		}
+		nb, err := io.ReadFull(other, b)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, Errorf("cannot read from the other reader: %v", err)
+		}
+
+		i := 0
+		for i < na && i < nb && a[i] == b[i] {
+			i++
+		}
+		if i < na || i < nb {
+			start := i - readerContextSize
+			if start < 0 {
+				start = 0
+			}
+			return &streamDifference{offset + int64(i), window(a[:na], start, i), window(b[:nb], start, i), i == na, i == nb}, nil
+		}
+		if na < len(a) {
+			return nil, nil
+		}
+		offset += int64(na)
+	}
+}
+
+// The bytes from 'start' until a few bytes after 'i'.
+func window(chunk []byte, start int, i int) []byte {
+	end := i + readerContextSize
+	if end > len(chunk) {
+		end = len(chunk)
+	}
+	return chunk[start:end]
+}
+
+// The actual image.Image must have the same size as the expected image, and
+// every channel of every pixel must differ from the expected image by at most
+// 'maxPerPixelDiff'. The channels are compared as 8-bit alpha-premultiplied
+// RGBA values, and the images may have different bounds, because the pixels
+// are compared by their offsets from the top-left corners. Example:
+//    c.Expect(rendered, ImageMatches(golden, 2))
+func ImageMatches(expected image.Image, maxPerPixelDiff uint8) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		if expected == nil || isNilPointerInsideInterfaceValue(expected) {
+			err = Errorf("the expected image must not be nil")
+			return
+		}
+		actual, ok := actual_.(image.Image)
+		if !ok || isNilPointerInsideInterfaceValue(actual) {
+			err = Errorf("type error: expected an image.Image, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+
+		// Printing all the pixels would make the messages unreadable.
+		summary := fmt.Sprintf("%T %v", actual, actual.Bounds())
+		description := fmt.Sprintf("matches the expected image within %v per channel", maxPerPixelDiff)
+		neg = Messagef(summary, "does NOT match the expected image within %v per channel", maxPerPixelDiff)
+
+		a, b := actual.Bounds(), expected.Bounds()
+		if a.Dx() != b.Dx() || a.Dy() != b.Dy() {
+			pos = Messagef(summary, "%v, but its size was %vx%v instead of %vx%v", description, a.Dx(), a.Dy(), b.Dx(), b.Dy())
+			return
+		}
+		for y := 0; y < a.Dy(); y++ {
+			for x := 0; x < a.Dx(); x++ {
+				ac := rgba8(actual.At(a.Min.X+x, a.Min.Y+y))
+				bc := rgba8(expected.At(b.Min.X+x, b.Min.Y+y))
+				if diff := channelDifference(ac, bc); diff > maxPerPixelDiff {
+					pos = Messagef(summary, "%v, but the pixel at (%v, %v) was %v instead of %v, a difference of %v",
+						description, a.Min.X+x, a.Min.Y+y, ac, bc, diff)
+					return
+				}
+			}
+		}
+		match = true
+		pos = Messagef(summary, "%v", description)
+		return
+	}
+}
+
+type rgba8Color [4]uint8
+
+func (c rgba8Color) String() string {
+	return fmt.Sprintf("RGBA(%v, %v, %v, %v)", c[0], c[1], c[2], c[3])
+}
+
+func rgba8(c color.Color) rgba8Color {
+	r, g, b, a := c.RGBA()
+	return rgba8Color{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+// The largest difference of the channels.
+func channelDifference(a rgba8Color, b rgba8Color) uint8 {
+	var max uint8
+	for i := range a {
+		diff := a[i] - b[i]
+		if a[i] < b[i] {
+			diff = b[i] - a[i]
+		}
+		if diff > max {
+			max = diff
+		}
+	}
+	return max
+}
+
+// The String method of the actual fmt.Stringer must return the expected
+// string. Example:
+//    c.Expect(price, StringsAs, "$4.99")
+func StringsAs(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, ok := actual_.(fmt.Stringer)
+	if !ok {
+		err = Errorf("type error: expected a fmt.Stringer, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+	expected, ok := expected_.(string)
+	if !ok {
+		err = Errorf("type error: expected a string, but was “%v” of type “%T”", expected_, expected_)
+		return
+	}
+
+	s := actual.String()
+	match = s == expected
+	pos = Messagef(s, "is a %T whose String() is “%v”", actual_, expected)
+	neg = Messagef(s, "is a %T whose String() is NOT “%v”", actual_, expected)
+	return
+}
+
+// The actual string must be parseable as a number of the same type as the
+// expected number, and the parsed number must equal the expected number.
+// For example when the expected number is an int, the string is parsed with
+// strconv.ParseInt, so "42.0" is not accepted. Example:
+//    c.Expect(fields[2], ParsesAsNumber, 42)
+func ParsesAsNumber(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, ok := actual_.(string)
+	if !ok {
+		err = Errorf("type error: expected a string, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+
+	var parsed interface{}
+	var parseErr error
+	switch v := reflect.ValueOf(expected); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, parseErr = strconv.ParseInt(actual, 10, v.Type().Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		parsed, parseErr = strconv.ParseUint(actual, 10, v.Type().Bits())
+	case reflect.Float32, reflect.Float64:
+		parsed, parseErr = strconv.ParseFloat(actual, v.Type().Bits())
+	default:
+		err = Errorf("type error: expected a number, but was “%v” of type “%T”", expected, expected)
+		return
+	}
+
+	neg = Messagef(actual, "does NOT parse as the %T “%v”", expected, expected)
+	if parseErr != nil {
+		pos = Messagef(actual, "parses as the %T “%v”, but it could not be parsed: %v", expected, expected, parseErr)
+		return
+	}
+	number := reflect.ValueOf(parsed).Convert(reflect.TypeOf(expected)).Interface()
+	match = number == expected
+	if match {
+		pos = Messagef(actual, "parses as the %T “%v”", expected, expected)
+	} else {
+		pos = Messagef(actual, "parses as the %T “%v”, but it was parsed as “%v”", expected, expected, number)
+	}
+	return
+}
+
+// The actual string or []byte must be valid UTF-8. The failure message
+// tells the byte offset of the first invalid sequence, and the bytes
+// starting from it.
+func IsValidUTF8(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toText(actual_)
+	if err != nil {
+		return
+	}
+
+	match = utf8.ValidString(actual)
+	if match {
+		pos = Messagef(actual_, "is valid UTF-8")
+	} else {
+		offset := firstInvalidUTF8(actual)
+		end := offset + utf8.UTFMax
+		if end > len(actual) {
+			end = len(actual)
+		}
+		pos = Messagef(actual_, "is valid UTF-8, but the bytes at offset %v were invalid: [% x]", offset, actual[offset:end])
+	}
+	neg = Messagef(actual_, "is NOT valid UTF-8")
+	return
+}
+
+func firstInvalidUTF8(s string) int {
+	for offset := 0; offset < len(s); {
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			return offset
+		}
+		offset += size
+	}
+	return len(s)
+}
+
 // The actual value must be a pointer to the same object as the expected value.
 func IsSame(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
 	ptr1, err := pointerOf(actual)
@@ -206,6 +686,40 @@ func IsFalse(actual interface{}, _ interface{}) (match bool, pos Message, neg Me
 	return
 }
 
+// The actual value must be the zero value of its type, for example 0, "",
+// a nil pointer or a struct whose every field is the zero value. For structs
+// the failure message lists the fields which are not zero. Both a nil
+// interface value and a typed nil, such as (*T)(nil), are zero values.
+func IsZero(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	if actual == nil {
+		match = true
+		pos = Messagef(actual, "is the zero value")
+		neg = Messagef(actual, "is NOT the zero value")
+		return
+	}
+	value := reflect.ValueOf(actual)
+	match = value.IsZero()
+	pos = Messagef(actual, "is the zero value of type “%T”", actual)
+	if fields := nonZeroFields(value); !match && len(fields) > 0 {
+		pos = Messagef(actual, "is the zero value of type “%T”, but the fields %v were not zero", actual, strings.Join(fields, ", "))
+	}
+	neg = Messagef(actual, "is NOT the zero value of type “%T”", actual)
+	return
+}
+
+func nonZeroFields(value reflect.Value) []string {
+	fields := make([]string, 0)
+	if value.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Field(i).IsZero() {
+			fields = append(fields, value.Type().Field(i).Name)
+		}
+	}
+	return fields
+}
+
 // The actual value must satisfy the given criteria.
 func Satisfies(actual interface{}, criteria interface{}) (match bool, pos Message, neg Message, err error) {
 	match = criteria.(bool) == true
@@ -233,6 +747,92 @@ func IsWithin(delta float64) Matcher {
 	}
 }
 
+// The actual value must differ from the expected value by at most 'percent'
+// percent of the expected value. When the expected value is 0, the deviation
+// can not be relative to it, so then the actual value must instead be within
+// percent/100 from 0, for example within 0.01 when percent is 1. Example:
+//    c.Expect(measured, IsWithinPercent(1), 1000.0)
+func IsWithinPercent(percent float64) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toFloat64(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toFloat64(expected_)
+		if err != nil {
+			return
+		}
+
+		if expected == 0 {
+			epsilon := percent / 100
+			match = math.Abs(actual) <= epsilon
+			pos = Messagef(actual, "is within %v of 0", epsilon)
+			neg = Messagef(actual, "is NOT within %v of 0", epsilon)
+			return
+		}
+		deviation := math.Abs(actual-expected) / math.Abs(expected) * 100
+		match = deviation <= percent
+		pos = Messagef(actual, "is within %v%% of %v, but deviated by %.3g%%", percent, expected, deviation)
+		neg = Messagef(actual, "is NOT within %v%% of %v, but deviated by %.3g%%", percent, expected, deviation)
+		return
+	}
+}
+
+// The actual collection must contain numbers whose mean differs from the
+// expected mean by at most 'tolerance'. Example:
+//    c.Expect(samples, HasMeanWithin(100, 0.5))
+func HasMeanWithin(expected float64, tolerance float64) Matcher {
+	return hasStatisticWithin("mean", mean, expected, tolerance)
+}
+
+// The actual collection must contain numbers whose population standard
+// deviation differs from the expected standard deviation by at most
+// 'tolerance'. Example:
+//    c.Expect(samples, HasStdDevWithin(15, 0.5))
+func HasStdDevWithin(expected float64, tolerance float64) Matcher {
+	return hasStatisticWithin("standard deviation", stdDev, expected, tolerance)
+}
+
+func hasStatisticWithin(name string, statistic func([]float64) float64, expected float64, tolerance float64) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+		numbers, err := toNumbers(actual)
+		if err != nil {
+			return
+		}
+		if len(numbers) == 0 {
+			err = Errorf("cannot compute the %v of an empty collection", name)
+			return
+		}
+
+		value := statistic(numbers)
+		match = math.Abs(value-expected) <= tolerance
+		pos = Messagef(actual, "has a %v within %v ± %v, but the %v was %v", name, expected, tolerance, name, value)
+		neg = Messagef(actual, "does NOT have a %v within %v ± %v, but the %v was %v", name, expected, tolerance, name, value)
+		return
+	}
+}
+
+func mean(numbers []float64) float64 {
+	sum := 0.0
+	for _, number := range numbers {
+		sum += number
+	}
+	return sum / float64(len(numbers))
+}
+
+func stdDev(numbers []float64) float64 {
+	m := mean(numbers)
+	sum := 0.0
+	for _, number := range numbers {
+		sum += (number - m) * (number - m)
+	}
+	return math.Sqrt(sum / float64(len(numbers)))
+}
+
 func toFloat64(actual interface{}) (result float64, err error) {
 	switch v := actual.(type) {
 	case float32:
@@ -245,34 +845,447 @@ func toFloat64(actual interface{}) (result float64, err error) {
 	return
 }
 
-// The actual collection must contain the expected value.
-func Contains(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
-	actual, err := toArray(actual_)
+// The actual complex number must be within delta from the expected complex
+// number, when the distance is the magnitude of their difference. Example:
+//    c.Expect(fft(signal)[1], IsWithinComplex(1e-9), complex(0, -2))
+func IsWithinComplex(delta float64) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toComplex128(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toComplex128(expected_)
+		if err != nil {
+			return
+		}
+
+		distance := cmplx.Abs(expected - actual)
+		match = distance < delta
+		pos = Messagef(actual, "is within %v ± %v, but the distance was %v", expected, delta, distance)
+		neg = Messagef(actual, "is NOT within %v ± %v, but the distance was %v", expected, delta, distance)
+		return
+	}
+}
+
+func toComplex128(actual interface{}) (result complex128, err error) {
+	switch v := actual.(type) {
+	case complex64:
+		result = complex128(v)
+	case complex128:
+		result = v
+	default:
+		err = Errorf("type error: expected a complex number, but was “%v” of type “%T”", actual, actual)
+	}
+	return
+}
+
+// The actual float must have no fractional part. Negative numbers and
+// zero (also negative zero) behave the same way as positive numbers.
+// NaN and infinities are not whole numbers.
+func IsWholeNumber(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toFloat64(actual_)
 	if err != nil {
 		return
 	}
 
-	match = arrayContains(actual, expected)
-	pos = Messagef(actual, "contains “%v”", expected)
-	neg = Messagef(actual, "does NOT contain “%v”", expected)
+	_, fraction := math.Modf(actual)
+	match = !math.IsInf(actual, 0) && fraction == 0
+	pos = Messagef(actual, "is a whole number, but its fractional part was %v", fraction)
+	neg = Messagef(actual, "is NOT a whole number")
 	return
 }
 
-func toArray(values interface{}) ([]interface{}, error) {
-	result := make([]interface{}, 0)
+// The actual float must have exactly the same bits as the expected float.
+// Unlike with ==, positive and negative zero are different, and NaN is equal
+// to a NaN which has the same sign and payload. A float32 compared to a
+// float32 is compared as 32 bits; otherwise both are converted to float64,
+// which preserves the value exactly. Example:
+//    c.Expect(sum(values), IsBitEqual, 0.30000000000000004)
+func IsBitEqual(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toFloat64(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toFloat64(expected_)
+	if err != nil {
+		return
+	}
 
-	// list to array
-	if list, ok := values.(*list.List); ok {
-		for e := list.Front(); e != nil; e = e.Next() {
-			result = append(result, e.Value)
-		}
-		return result, nil
+	actualBits, expectedBits := fmt.Sprintf("%016x", math.Float64bits(actual)), fmt.Sprintf("%016x", math.Float64bits(expected))
+	a32, actualIs32 := actual_.(float32)
+	e32, expectedIs32 := expected_.(float32)
+	if actualIs32 && expectedIs32 {
+		actualBits, expectedBits = fmt.Sprintf("%08x", math.Float32bits(a32)), fmt.Sprintf("%08x", math.Float32bits(e32))
 	}
 
-	switch v := reflect.ValueOf(values); v.Kind() {
+	match = actualBits == expectedBits
+	pos = Messagef(actual_, "is bit-equal to “%v” (0x%v), but its bits were 0x%v", expected_, expectedBits, actualBits)
+	neg = Messagef(actual_, "is NOT bit-equal to “%v” (0x%v)", expected_, expectedBits)
+	return
+}
 
-	// array to array (copy)
-	case reflect.Array, reflect.Slice:
+// The actual duration must be within tolerance from the expected duration.
+// Both ends of the allowed range are inclusive.
+func IsApproxDuration(tolerance time.Duration) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toDuration(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toDuration(expected_)
+		if err != nil {
+			return
+		}
+
+		min, max := expected-tolerance, expected+tolerance
+		match = min <= actual && actual <= max
+		pos = Messagef(actual, "is within %v ± %v (%v to %v)", expected, tolerance, min, max)
+		neg = Messagef(actual, "is NOT within %v ± %v (%v to %v)", expected, tolerance, min, max)
+		return
+	}
+}
+
+func toDuration(value interface{}) (result time.Duration, err error) {
+	switch v := value.(type) {
+	case time.Duration:
+		result = v
+	default:
+		err = Errorf("type error: expected a time.Duration, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+// The actual time must be at most 'maxAge' before the current time of
+// the clock, and not after it. Example:
+//    clock := c.Clock()
+//    c.Expect(order.CreatedAt, IsRecent(clock, time.Second))
+func IsRecent(clock Clock, maxAge time.Duration) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, ok := actual_.(time.Time)
+		if !ok {
+			err = Errorf("type error: expected a time.Time, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+
+		now := clock.Now()
+		match = !actual.Before(now.Add(-maxAge)) && !actual.After(now)
+		pos = Messagef(actual, "is at most %v before “%v”", maxAge, now)
+		neg = Messagef(actual, "is NOT at most %v before “%v”", maxAge, now)
+		return
+	}
+}
+
+// The actual time must be on the given day of the week. The weekday is
+// determined in the time's own location, so the same instant may be on
+// a different weekday in another location. Example:
+//    c.Expect(deadline, IsWeekday(time.Friday))
+func IsWeekday(day time.Weekday) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toTime(actual_)
+		if err != nil {
+			return
+		}
+
+		match = actual.Weekday() == day
+		pos = Messagef(actual, "is on a %v, but was on a %v", day, actual.Weekday())
+		neg = Messagef(actual, "is NOT on a %v", day)
+		return
+	}
+}
+
+// The actual time must be in the given month of any year. The month is
+// determined in the time's own location, the same way as with IsWeekday.
+func IsInMonth(month time.Month) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toTime(actual_)
+		if err != nil {
+			return
+		}
+
+		match = actual.Month() == month
+		pos = Messagef(actual, "is in %v, but was in %v", month, actual.Month())
+		neg = Messagef(actual, "is NOT in %v", month)
+		return
+	}
+}
+
+// The actual time must be between start and end. Both ends of the range
+// are inclusive. The times are compared as instants, so their locations
+// do not matter. Example:
+//    c.Expect(invoice.Date, IsDateBetween(quarterStart, quarterEnd))
+func IsDateBetween(start time.Time, end time.Time) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toTime(actual_)
+		if err != nil {
+			return
+		}
+
+		match = !actual.Before(start) && !actual.After(end)
+		pos = Messagef(actual, "is between %v and %v", start, end)
+		neg = Messagef(actual, "is NOT between %v and %v", start, end)
+		return
+	}
+}
+
+func toTime(value interface{}) (result time.Time, err error) {
+	switch v := value.(type) {
+	case time.Time:
+		result = v
+	default:
+		err = Errorf("type error: expected a time.Time, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+// The actual map must have the same keys as the expected map, and the values
+// of each key must be within delta from the expected values. The keys must be
+// strings and the values floats.
+func IsWithinMap(delta float64) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toFloat64Map(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toFloat64Map(expected_)
+		if err != nil {
+			return
+		}
+
+		missing := make([]string, 0)
+		extra := make([]string, 0)
+		differing := make([]string, 0)
+		for key, e := range expected {
+			if a, found := actual[key]; !found {
+				missing = append(missing, key)
+			} else if !(math.Abs(e-a) < delta) {
+				differing = append(differing, key)
+			}
+		}
+		for key := range actual {
+			if _, found := expected[key]; !found {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(missing)
+		sort.Strings(extra)
+		sort.Strings(differing)
+
+		match = len(missing) == 0 && len(extra) == 0 && len(differing) == 0
+		pos = Messagef(actual_, "is within %v ± %v, but was missing keys “%v”, had extra keys “%v” and differing values at keys “%v”",
+			expected_, delta, missing, extra, differing)
+		neg = Messagef(actual_, "is NOT within %v ± %v", expected_, delta)
+		return
+	}
+}
+
+// The actual map must have an entry with the given key, and the value of
+// the entry must match the given matcher. The expected value is passed on
+// to the matcher. For example:
+//    c.Expect(config, HasEntry("timeout", IsWithin(0.5)), 30.0)
+func HasEntry(key interface{}, matcher Matcher) Matcher {
+	return HasEntryAtPath([]interface{}{key}, matcher)
+}
+
+// The same as HasEntry, but for nested maps: the keys are looked up one
+// after another, each from the value of the previous key. For example:
+//    c.Expect(config, HasEntryAtPath([]interface{}{"server", "port"}, Equals), 8080)
+func HasEntryAtPath(keys []interface{}, matcher Matcher) Matcher {
+	return func(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		path := formatEntryPath(keys)
+		value, missing, err := lookupEntry(actual, keys)
+		if err != nil {
+			return
+		}
+		if missing != nil {
+			pos = Messagef(actual, "has an entry at “%v”, but the key “%v” was missing", path, missing)
+			neg = Messagef(actual, "does NOT have an entry at “%v”", path)
+			return
+		}
+
+		match, entryPos, entryNeg, e := matcher.Match(value, expected)
+		if e != nil {
+			err = Errorf("entry at “%v”: %v", path, e)
+			return
+		}
+		pos = Messagef(value, "has an entry at “%v” which %v", path, expectationOr(entryPos, "matches"))
+		neg = Messagef(value, "has an entry at “%v” which %v", path, expectationOr(entryNeg, "does NOT match"))
+		return
+	}
+}
+
+// Returns the value at the end of the keys, or the first key which is missing.
+func lookupEntry(values interface{}, keys []interface{}) (value interface{}, missing interface{}, err error) {
+	value = values
+	for _, key := range keys {
+		m := reflect.ValueOf(value)
+		if m.Kind() != reflect.Map {
+			return nil, nil, Errorf("type error: expected a map, but was “%v” of type “%T”", value, value)
+		}
+		k := reflect.ValueOf(key)
+		if !k.IsValid() || !k.Type().AssignableTo(m.Type().Key()) {
+			return nil, nil, Errorf("type error: expected a key of type “%v”, but was “%v” of type “%T”", m.Type().Key(), key, key)
+		}
+		entry := m.MapIndex(k)
+		if !entry.IsValid() {
+			return nil, key, nil
+		}
+		value = entry.Interface()
+	}
+	return value, nil, nil
+}
+
+func formatEntryPath(keys []interface{}) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprint(key)
+	}
+	return strings.Join(parts, ".")
+}
+
+// The keys of the actual map must be exactly the given keys, no more and
+// no less. The keys are compared with the same equality as Equals. Example:
+//    c.Expect(config, HasExactlyKeys("host", "port", "timeout"))
+func HasExactlyKeys(keys ...interface{}) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		m := reflect.ValueOf(actual)
+		if m.Kind() != reflect.Map {
+			err = Errorf("type error: expected a map, but was “%v” of type “%T”", actual, actual)
+			return
+		}
+		actualKeys := make([]interface{}, 0, m.Len())
+		for _, key := range m.MapKeys() {
+			actualKeys = append(actualKeys, key.Interface())
+		}
+		// The order of the map keys is random, but the messages should not be.
+		sort.Slice(actualKeys, func(i, j int) bool {
+			return fmt.Sprint(actualKeys[i]) < fmt.Sprint(actualKeys[j])
+		})
+
+		missing := elementsNotIn(keys, actualKeys)
+		unexpected := elementsNotIn(actualKeys, keys)
+
+		match = len(missing) == 0 && len(unexpected) == 0
+		switch {
+		case len(missing) > 0 && len(unexpected) > 0:
+			pos = Messagef(actual, "has exactly the keys “%v”, but it was missing “%v” and had also “%v”", keys, missing, unexpected)
+		case len(missing) > 0:
+			pos = Messagef(actual, "has exactly the keys “%v”, but it was missing “%v”", keys, missing)
+		case len(unexpected) > 0:
+			pos = Messagef(actual, "has exactly the keys “%v”, but it had also “%v”", keys, unexpected)
+		default:
+			pos = Messagef(actual, "has exactly the keys “%v”", keys)
+		}
+		neg = Messagef(actual, "does NOT have exactly the keys “%v”", keys)
+		return
+	}
+}
+
+func toFloat64Map(values interface{}) (map[string]float64, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, Errorf("type error: expected a map with string keys, but was “%v” of type “%T”", values, values)
+	}
+	result := make(map[string]float64)
+	for _, key := range v.MapKeys() {
+		value, err := toFloat64(v.MapIndex(key).Interface())
+		if err != nil {
+			return nil, err
+		}
+		result[key.String()] = value
+	}
+	return result, nil
+}
+
+// The actual map must have the same keys as the expected map, and the values
+// of each key must contain the same elements as the expected values, in any
+// order. The keys must be strings and the values collections. Useful for
+// comparing for example HTTP headers of the type map[string][]string.
+func EqualsMapUnordered(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toCollectionMap(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toCollectionMap(expected_)
+	if err != nil {
+		return
+	}
+
+	missing := make([]string, 0)
+	extra := make([]string, 0)
+	differing := make([]string, 0)
+	for key, e := range expected {
+		a, found := actual[key]
+		if !found {
+			missing = append(missing, key)
+			continue
+		}
+		if missingValues, extraValues := differenceBy(a, e, areEqual); len(missingValues) > 0 || len(extraValues) > 0 {
+			differing = append(differing, fmt.Sprintf("at key “%v” was missing “%v” and had extra “%v”", key, missingValues, extraValues))
+		}
+	}
+	for key := range actual {
+		if _, found := expected[key]; !found {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	sort.Strings(differing)
+
+	match = len(missing) == 0 && len(extra) == 0 && len(differing) == 0
+	details := fmt.Sprintf("was missing keys “%v” and had extra keys “%v”", missing, extra)
+	if len(differing) > 0 {
+		details += ", and " + strings.Join(differing, ", ")
+	}
+	pos = Messagef(actual_, "equals “%v” ignoring the order of the values, but %v", expected_, details)
+	neg = Messagef(actual_, "does NOT equal “%v” ignoring the order of the values", expected_)
+	return
+}
+
+func toCollectionMap(values interface{}) (map[string][]interface{}, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, Errorf("type error: expected a map with string keys, but was “%v” of type “%T”", values, values)
+	}
+	result := make(map[string][]interface{})
+	for _, key := range v.MapKeys() {
+		elements, err := toArray(v.MapIndex(key).Interface())
+		if err != nil {
+			return nil, err
+		}
+		result[key.String()] = elements
+	}
+	return result, nil
+}
+
+// The actual collection must contain the expected value.
+func Contains(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+
+	match = arrayContains(actual, expected)
+	pos = Messagef(actual, "contains “%v”", expected)
+	neg = Messagef(actual, "does NOT contain “%v”", expected)
+	return
+}
+
+func toArray(values interface{}) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+
+	// list to array
+	if list, ok := values.(*list.List); ok {
+		for e := list.Front(); e != nil; e = e.Next() {
+			result = append(result, e.Value)
+		}
+		return result, nil
+	}
+
+	switch v := reflect.ValueOf(values); v.Kind() {
+
+	// array to array (copy)
+	case reflect.Array, reflect.Slice:
 		arr := v
 		for i := 0; i < arr.Len(); i++ {
 			obj := arr.Index(i).Interface()
@@ -304,14 +1317,80 @@ func arrayContains(haystack []interface{}, needle interface{}) bool {
 }
 
 func findIndex(haystack []interface{}, needle interface{}) (idx int, found bool) {
+	return findIndexBy(haystack, needle, areEqual)
+}
+
+func findIndexBy(haystack []interface{}, needle interface{}, eq func(a, b interface{}) bool) (idx int, found bool) {
 	for i := 0; i < len(haystack); i++ {
-		if areEqual(haystack[i], needle) {
+		if eq(haystack[i], needle) {
 			return i, true
 		}
 	}
 	return -1, false
 }
 
+// The actual collection must contain the expected value, when the elements
+// are compared using the given equality function. The function is called
+// with an element of the actual collection and the expected value.
+func ContainsBy(eq func(a, b interface{}) bool) Matcher {
+	return func(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+
+		_, match = findIndexBy(actual, expected, eq)
+		pos = Messagef(actual, "contains “%v”", expected)
+		neg = Messagef(actual, "does NOT contain “%v”", expected)
+		return
+	}
+}
+
+// The keys of the elements of the actual collection must all be different,
+// when the key of an element is derived with the given function. For example:
+//    c.Expect(users, IsUniqueBy(func(u interface{}) interface{} { return u.(User).ID }))
+func IsUniqueBy(key func(element interface{}) interface{}) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+
+		keys := make([]interface{}, len(actual))
+		for i, element := range actual {
+			keys[i] = key(element)
+		}
+		duplicate, indices := firstDuplicateKey(keys)
+
+		match = indices == nil
+		if match {
+			pos = Messagef(actual, "has unique keys")
+		} else {
+			pos = Messagef(actual, "has unique keys, but the key “%v” was at indices %v", duplicate, indices)
+		}
+		neg = Messagef(actual, "does NOT have unique keys")
+		return
+	}
+}
+
+// Returns the first key which is equal to some earlier key, and the indices
+// of all the keys which equal it, or nil indices if all keys are different.
+func firstDuplicateKey(keys []interface{}) (duplicate interface{}, indices []int) {
+	for j := range keys {
+		for i := 0; i < j; i++ {
+			if areEqual(keys[i], keys[j]) {
+				for k := range keys {
+					if areEqual(keys[k], keys[j]) {
+						indices = append(indices, k)
+					}
+				}
+				return keys[j], indices
+			}
+		}
+	}
+	return nil, nil
+}
+
 // The actual collection must contain all expected elements,
 // but it may contain also other non-expected elements.
 // The order of elements is not significant.
@@ -364,6 +1443,72 @@ func ContainsAny(actual_ interface{}, expected_ interface{}) (match bool, pos Me
 	return
 }
 
+// The actual string must contain all of the expected substrings.
+// Example:
+//    c.Expect(log, ContainsAllSubstrings, Values("started", "stopped"))
+func ContainsAllSubstrings(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, expected, err := toStringAndSubstrings(actual_, expected_)
+	if err != nil {
+		return
+	}
+
+	missing := make([]string, 0)
+	for _, substring := range expected {
+		if !strings.Contains(actual, substring) {
+			missing = append(missing, substring)
+		}
+	}
+
+	match = len(missing) == 0
+	pos = Messagef(actual, "contains all of the substrings “%v”, but was missing “%v”", expected, missing)
+	neg = Messagef(actual, "does NOT contain all of the substrings “%v”", expected)
+	return
+}
+
+// The actual string must contain at least one of the expected substrings.
+// Example:
+//    c.Expect(log, ContainsAnySubstring, Values("error", "warning"))
+func ContainsAnySubstring(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, expected, err := toStringAndSubstrings(actual_, expected_)
+	if err != nil {
+		return
+	}
+
+	found := make([]string, 0)
+	for _, substring := range expected {
+		if strings.Contains(actual, substring) {
+			found = append(found, substring)
+		}
+	}
+
+	match = len(found) > 0
+	pos = Messagef(actual, "contains any of the substrings “%v”", expected)
+	neg = Messagef(actual, "does NOT contain any of the substrings “%v”, but contained “%v”", expected, found)
+	return
+}
+
+func toStringAndSubstrings(actual_ interface{}, expected_ interface{}) (actual string, expected []string, err error) {
+	actual, ok := actual_.(string)
+	if !ok {
+		err = Errorf("type error: expected a string, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+	elements, err := toArray(expected_)
+	if err != nil {
+		return
+	}
+	expected = make([]string, len(elements))
+	for i, element := range elements {
+		substring, ok := element.(string)
+		if !ok {
+			err = Errorf("type error: expected the substrings to be strings, but was “%v” of type “%T”", element, element)
+			return
+		}
+		expected[i] = substring
+	}
+	return
+}
+
 // The actual collection must contain all expected elements and nothing else.
 // The order of elements is not significant.
 func ContainsExactly(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
@@ -394,6 +1539,46 @@ func ContainsExactly(actual_ interface{}, expected_ interface{}) (match bool, po
 	return
 }
 
+// The actual collection must contain all expected elements and nothing else,
+// when the elements are compared using the given equality function. The function
+// is called with an element of the actual collection and an expected element.
+// The order of elements is not significant.
+func ContainsExactlyBy(eq func(a, b interface{}) bool) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toArray(expected_)
+		if err != nil {
+			return
+		}
+
+		missing, extra := differenceBy(actual, expected, eq)
+
+		match = len(missing) == 0 && len(extra) == 0
+		pos = Messagef(actual, "contains exactly “%v”, but was missing “%v” and had extra “%v”", expected, missing, extra)
+		neg = Messagef(actual, "does NOT contain exactly “%v”", expected)
+		return
+	}
+}
+
+// Returns the expected elements which are not in the actual collection,
+// and the actual elements which were not expected.
+func differenceBy(actual []interface{}, expected []interface{}, eq func(a, b interface{}) bool) (missing []interface{}, extra []interface{}) {
+	missing = make([]interface{}, 0)
+	extra = make([]interface{}, 0)
+	extra = append(extra, actual...)
+	for i := 0; i < len(expected); i++ {
+		if idx, found := findIndexBy(extra, expected[i], eq); found {
+			extra = append(extra[:idx], extra[idx+1:]...)
+		} else {
+			missing = append(missing, expected[i])
+		}
+	}
+	return
+}
+
 // The actual collection must contain all expected elements, in the same order, and nothing else.
 func ContainsInOrder(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
 	actual, err := toArray(actual_)
@@ -451,3 +1636,1320 @@ func ContainsInPartialOrder(actual_ interface{}, expected_ interface{}) (match b
 	neg = Messagef(actual, "does NOT contain in partial order “%v”", expected)
 	return
 }
+
+// The actual value must be a channel which has been closed and from which
+// all buffered values have been received. An open channel with no buffered
+// values is checked with a non-blocking receive, so if some goroutine is
+// blocked sending to an unbuffered channel, that value will be consumed.
+func IsClosed(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	ch, err := toReceivableChannel(actual)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case ch.Len() > 0:
+		pos = Messagef(actual, "is closed, but it has %v buffered values", ch.Len())
+	case isClosedAndDrained(ch):
+		match = true
+		pos = Messagef(actual, "is closed")
+	default:
+		pos = Messagef(actual, "is closed, but it is still open")
+	}
+	neg = Messagef(actual, "is NOT closed")
+	return
+}
+
+func toReceivableChannel(value interface{}) (ch reflect.Value, err error) {
+	ch = reflect.ValueOf(value)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		err = Errorf("type error: expected a receivable channel, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+func isClosedAndDrained(ch reflect.Value) bool {
+	x, ok := ch.TryRecv()
+	// When the receive would block, x is the zero Value. When the channel
+	// has been closed, x is the zero value of the channel's element type.
+	return x.IsValid() && !ok
+}
+
+// The actual channel must have exactly 'n' buffered values, which have been
+// sent to it but not yet received. The reading is inherently racy when other
+// goroutines are sending to or receiving from the channel at the same time,
+// so the channel should be checked only when they are known to be idle or
+// blocked. Example:
+//    c.Expect(queue, HasBufferedLen(3))
+func HasBufferedLen(n int) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		ch := reflect.ValueOf(actual)
+		if ch.Kind() != reflect.Chan {
+			err = Errorf("type error: expected a channel, but was “%v” of type “%T”", actual, actual)
+			return
+		}
+
+		length, capacity := ch.Len(), ch.Cap()
+		match = length == n
+		pos = Messagef(actual, "has %v buffered values, but had %v of capacity %v", n, length, capacity)
+		neg = Messagef(actual, "does NOT have %v buffered values", n)
+		return
+	}
+}
+
+// The actual channel must emit the values of the expected collection,
+// in the same order, and then be closed. Every value, and also the closing,
+// must happen within 'timeout' from the previous value. Example:
+//    c.Expect(events, EmitsSequence(time.Second), []string{"started", "stopped"})
+func EmitsSequence(timeout time.Duration) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		ch, err := toReceivableChannel(actual_)
+		if err != nil {
+			return
+		}
+		expected, err := toArray(expected_)
+		if err != nil {
+			return
+		}
+
+		received := make([]interface{}, 0)
+		failure := ""
+		for i, value := range expected {
+			x, ok, timedOut := receiveWithTimeout(ch, timeout)
+			if timedOut {
+				failure = fmt.Sprintf("but no value at index %v was emitted within %v", i, timeout)
+				break
+			}
+			if !ok {
+				failure = fmt.Sprintf("but it was closed after %v values", i)
+				break
+			}
+			received = append(received, x)
+			if !areEqual(x, value) {
+				failure = fmt.Sprintf("but the value at index %v was “%v”", i, x)
+				break
+			}
+		}
+		if failure == "" {
+			x, ok, timedOut := receiveWithTimeout(ch, timeout)
+			switch {
+			case timedOut:
+				failure = fmt.Sprintf("but it was not closed within %v", timeout)
+			case ok:
+				received = append(received, x)
+				failure = fmt.Sprintf("but it emitted an extra value “%v”", x)
+			}
+		}
+
+		match = failure == ""
+		if match {
+			pos = Messagef(received, "emits “%v” and is closed", expected)
+		} else {
+			pos = Messagef(received, "emits “%v” and is closed, %v", expected, failure)
+		}
+		neg = Messagef(received, "does NOT emit “%v” and is closed", expected)
+		return
+	}
+}
+
+func receiveWithTimeout(ch reflect.Value, timeout time.Duration) (x interface{}, ok bool, timedOut bool) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(timeout))},
+	}
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == 1 {
+		return nil, false, true
+	}
+	if !ok {
+		return nil, false, false
+	}
+	return value.Interface(), true, false
+}
+
+// The actual collection must contain at least one element which matches the
+// given matcher. The expected value is passed on to the matcher. For example:
+//    c.Expect(values, ContainsMatching(IsWithin(0.1)), 3.0)
+func ContainsMatching(matcher Matcher) Matcher {
+	return func(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+		matching, _, description, err := matchElements(actual, matcher, expected)
+		if err != nil {
+			return
+		}
+
+		match = len(matching) > 0
+		pos = Messagef(actual, "contains an element which %v, but none of the %v elements matched", description, len(actual))
+		neg = Messagef(actual, "does NOT contain an element which %v, but the elements at indices %v matched", description, matching)
+		return
+	}
+}
+
+// The actual collection must contain exactly one element which matches the
+// given matcher. The expected value is passed on to the matcher. For example:
+//    c.Expect(values, ContainsExactlyOneMatching(IsWithin(0.1)), 3.0)
+func ContainsExactlyOneMatching(matcher Matcher) Matcher {
+	return func(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+		matching, _, description, err := matchElements(actual, matcher, expected)
+		if err != nil {
+			return
+		}
+
+		match = len(matching) == 1
+		pos = Messagef(actual, "contains exactly one element which %v, but %v elements matched, at indices %v", description, len(matching), matching)
+		neg = Messagef(actual, "does NOT contain exactly one element which %v, but the elements at indices %v matched", description, matching)
+		return
+	}
+}
+
+// All elements of the actual collection must match the given matcher.
+// The expected value is passed on to the matcher. For example:
+//    c.Expect(values, AllMatching(IsWithin(0.1)), 3.0)
+func AllMatching(matcher Matcher) Matcher {
+	return func(actual_ interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+		_, notMatching, description, err := matchElements(actual, matcher, expected)
+		if err != nil {
+			return
+		}
+
+		match = len(notMatching) == 0
+		pos = Messagef(actual, "contains only elements which %v, but the elements at indices %v did not match", description, notMatching)
+		neg = Messagef(actual, "does NOT contain only elements which %v", description)
+		return
+	}
+}
+
+// Applies the matcher to every element, and returns the indices of the
+// matching and not matching elements, and a description of what the matcher
+// expects from the elements.
+func matchElements(elements []interface{}, matcher Matcher, expected interface{}) (matching []int, notMatching []int, description string, err error) {
+	matching = make([]int, 0)
+	notMatching = make([]int, 0)
+	description = "matches"
+	for i, element := range elements {
+		match, pos, _, e := matcher.Match(element, expected)
+		if e != nil {
+			err = Errorf("element at index %v: %v", i, e)
+			return
+		}
+		if pos != nil {
+			description = pos.Expectation()
+		}
+		if match {
+			matching = append(matching, i)
+		} else {
+			notMatching = append(notMatching, i)
+		}
+	}
+	return
+}
+
+// The actual collection must contain numbers in which every number is
+// greater than or equal to the previous number.
+func IsMonotonicallyIncreasing(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	return isIncreasing(actual, false, "is monotonically increasing")
+}
+
+// The actual collection must contain numbers in which every number is
+// greater than the previous number, so that no two consecutive numbers
+// are equal.
+func IsStrictlyIncreasing(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	return isIncreasing(actual, true, "is strictly increasing")
+}
+
+func isIncreasing(actual_ interface{}, strict bool, description string) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	numbers, err := toExactNumbers(actual)
+	if err != nil {
+		return
+	}
+
+	breakIndex := -1
+	for i := 1; i < len(numbers); i++ {
+		if numbers[i].less(numbers[i-1]) || (strict && numbers[i].equals(numbers[i-1])) {
+			breakIndex = i
+			break
+		}
+	}
+
+	match = breakIndex < 0
+	if match {
+		pos = Messagef(actual, "%v", description)
+	} else {
+		pos = Messagef(actual, "%v, but at index %v “%v” is followed by “%v”",
+			description, breakIndex-1, actual[breakIndex-1], actual[breakIndex])
+	}
+	neg = Messagef(actual, "is NOT %v", strings.TrimPrefix(description, "is "))
+	return
+}
+
+// The actual collection must be such that for every element, the relation
+// is true for the previous element and it. This generalizes the matchers such
+// as IsStrictlyIncreasing to any relation. Example:
+//    c.Expect(events, EachPairSatisfies(func(prev, next interface{}) bool {
+//        return next.(Event).Time.After(prev.(Event).Time)
+//    }))
+func EachPairSatisfies(relation func(prev, next interface{}) bool) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		if relation == nil {
+			err = Errorf("the relation must not be nil")
+			return
+		}
+		actual, err := toArray(actual_)
+		if err != nil {
+			return
+		}
+
+		description := "satisfies the relation for each pair of consecutive elements"
+		neg = Messagef(actual, "does NOT satisfy the relation for each pair of consecutive elements")
+		for i := 1; i < len(actual); i++ {
+			if !relation(actual[i-1], actual[i]) {
+				pos = Messagef(actual, "%v, but the elements at indices %v and %v did not: “%v” and “%v”",
+					description, i-1, i, actual[i-1], actual[i])
+				return
+			}
+		}
+		match = true
+		pos = Messagef(actual, "%v", description)
+		return
+	}
+}
+
+// The actual collection must contain integers in which every integer is
+// exactly one more than the previous integer, for example [5 6 7 8].
+// The optional expected integer is the number with which the range must
+// start. Example:
+//    c.Expect(ids, IsContiguousRange, 5)
+func IsContiguousRange(actual_ interface{}, start interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	integers, err := toIntegers(actual)
+	if err != nil {
+		return
+	}
+
+	description := "is a contiguous range"
+	if start != nil {
+		first, e := toInteger(start)
+		if e != nil {
+			err = e
+			return
+		}
+		description = fmt.Sprintf("is a contiguous range starting from %v", first)
+		if len(integers) > 0 && integers[0] != first {
+			pos = Messagef(actual, "%v, but it started from %v", description, integers[0])
+			neg = Messagef(actual, "is NOT %v", strings.TrimPrefix(description, "is "))
+			return
+		}
+	}
+	gapIndex := -1
+	for i := 1; i < len(integers); i++ {
+		// The successor of the largest int64 would wrap around to the smallest.
+		if integers[i-1] == math.MaxInt64 || integers[i] != integers[i-1]+1 {
+			gapIndex = i
+			break
+		}
+	}
+
+	match = gapIndex < 0
+	if match {
+		pos = Messagef(actual, "%v", description)
+	} else {
+		pos = Messagef(actual, "%v, but at index %v “%v” is followed by “%v”",
+			description, gapIndex-1, actual[gapIndex-1], actual[gapIndex])
+	}
+	neg = Messagef(actual, "is NOT %v", strings.TrimPrefix(description, "is "))
+	return
+}
+
+func toIntegers(values []interface{}) ([]int64, error) {
+	result := make([]int64, len(values))
+	for i, value := range values {
+		integer, err := toInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = integer
+	}
+	return result, nil
+}
+
+// Converts any signed or unsigned integer to an int64. The unsigned
+// integers which are too large for an int64 are errors.
+func toInteger(value interface{}) (result int64, err error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			err = Errorf("expected an integer which fits into an int64, but was “%v” of type “%T”", value, value)
+			return
+		}
+		result = int64(v.Uint())
+	default:
+		err = Errorf("type error: expected an integer, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+func toNumbers(values []interface{}) ([]float64, error) {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		number, err := toNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = number
+	}
+	return result, nil
+}
+
+// Converts any integer or float to a float64.
+func toNumber(value interface{}) (result float64, err error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		result = v.Float()
+	default:
+		err = Errorf("type error: expected a number, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+// A number which keeps the integers exact. A float64 cannot represent all
+// the integers above 2^53, so converting them would make some of them equal.
+type exactNumber struct {
+	kind     reflect.Kind
+	integer  int64
+	unsigned uint64
+	float    float64
+}
+
+func toExactNumbers(values []interface{}) ([]exactNumber, error) {
+	result := make([]exactNumber, len(values))
+	for i, value := range values {
+		switch v := reflect.ValueOf(value); v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			result[i] = exactNumber{kind: reflect.Int64, integer: v.Int()}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			result[i] = exactNumber{kind: reflect.Uint64, unsigned: v.Uint()}
+		default:
+			number, err := toNumber(value)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = exactNumber{kind: reflect.Float64, float: number}
+		}
+	}
+	return result, nil
+}
+
+func (a exactNumber) less(b exactNumber) bool {
+	switch {
+	case a.kind == reflect.Float64 || b.kind == reflect.Float64:
+		return a.toFloat() < b.toFloat()
+	case a.kind == reflect.Int64 && b.kind == reflect.Int64:
+		return a.integer < b.integer
+	case a.kind == reflect.Uint64 && b.kind == reflect.Uint64:
+		return a.unsigned < b.unsigned
+	case a.kind == reflect.Int64:
+		return a.integer < 0 || uint64(a.integer) < b.unsigned
+	default:
+		return b.integer >= 0 && a.unsigned < uint64(b.integer)
+	}
+}
+
+func (a exactNumber) equals(b exactNumber) bool {
+	if a.kind == reflect.Float64 || b.kind == reflect.Float64 {
+		return a.toFloat() == b.toFloat()
+	}
+	return !a.less(b) && !b.less(a)
+}
+
+func (a exactNumber) toFloat() float64 {
+	switch a.kind {
+	case reflect.Int64:
+		return float64(a.integer)
+	case reflect.Uint64:
+		return float64(a.unsigned)
+	}
+	return a.float
+}
+
+// The actual integer must be a multiple of n, so that dividing it by n
+// leaves no remainder. The signs do not matter: -6 is a multiple of 3 and
+// of -3. Zero is a multiple of every n. Since only zero is a multiple of
+// zero, n must not be zero. Example:
+//    c.Expect(len(buffer), IsMultipleOf(blockSize))
+func IsMultipleOf(n int) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		if n == 0 {
+			err = Errorf("cannot check for multiples of 0")
+			return
+		}
+		remainder, err := integerRemainder(actual, n)
+		if err != nil {
+			return
+		}
+
+		match = remainder == 0
+		pos = Messagef(actual, "is a multiple of %v, but the remainder was %v", n, remainder)
+		neg = Messagef(actual, "is NOT a multiple of %v", n)
+		return
+	}
+}
+
+func integerRemainder(value interface{}, n int) (remainder int64, err error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		remainder = v.Int() % int64(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		divisor := int64(n)
+		if divisor < 0 {
+			divisor = -divisor
+		}
+		remainder = int64(v.Uint() % uint64(divisor))
+	default:
+		err = Errorf("type error: expected an integer, but was “%v” of type “%T”", value, value)
+	}
+	return
+}
+
+// All elements of the actual collection must be also in the expected
+// collection. The collections are treated as sets, so the number of
+// times that an element occurs in them is not significant.
+func IsSubsetOf(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toArray(expected_)
+	if err != nil {
+		return
+	}
+
+	notAllowed := elementsNotIn(actual, expected)
+
+	match = len(notAllowed) == 0
+	pos = Messagef(actual, "is a subset of “%v”, but it also has “%v”", expected, notAllowed)
+	neg = Messagef(actual, "is NOT a subset of “%v”", expected)
+	return
+}
+
+// All elements of the expected collection must be also in the actual
+// collection. The collections are treated as sets, so the number of
+// times that an element occurs in them is not significant.
+func IsSupersetOf(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toArray(expected_)
+	if err != nil {
+		return
+	}
+
+	missing := elementsNotIn(expected, actual)
+
+	match = len(missing) == 0
+	pos = Messagef(actual, "is a superset of “%v”, but it is missing “%v”", expected, missing)
+	neg = Messagef(actual, "is NOT a superset of “%v”", expected)
+	return
+}
+
+// Returns the distinct elements which are not in the other collection.
+func elementsNotIn(elements []interface{}, other []interface{}) []interface{} {
+	result := make([]interface{}, 0)
+	for _, element := range elements {
+		if !arrayContains(other, element) && !arrayContains(result, element) {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// The actual value must be a function which takes no parameters and whose
+// last return value is an error, for example “func() error”. The function
+// is called and it must not return an error. Example:
+//    c.Expect(func() error { return file.Close() }, Succeeds)
+func Succeeds(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	_, callErr, err := callReturningError(actual)
+	if err != nil {
+		return
+	}
+
+	match = callErr == nil
+	pos = Messagef(callErr, "succeeds")
+	neg = Messagef(callErr, "does NOT succeed")
+	return
+}
+
+// The actual value must be a function which takes no parameters and returns
+// an error, like for Succeeds. The function is called once every 'interval'
+// until it does not return an error, or until the next call would be more than
+// 'timeout' after the first call. The last error is reported. Useful for
+// waiting until a server is ready. Example:
+//    c.Expect(func() error { return ping() }, EventuallySucceeds(5*time.Second, 100*time.Millisecond))
+func EventuallySucceeds(timeout time.Duration, interval time.Duration) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		if interval <= 0 {
+			err = Errorf("the interval must be positive, but was %v", interval)
+			return
+		}
+
+		deadline := time.Now().Add(timeout)
+		attempts := 0
+		var callErr error
+		for {
+			_, callErr, err = callReturningError(actual)
+			if err != nil {
+				return
+			}
+			attempts++
+			if callErr == nil || time.Now().Add(interval).After(deadline) {
+				break
+			}
+			time.Sleep(interval)
+		}
+
+		match = callErr == nil
+		if match {
+			pos = Messagef(callErr, "eventually succeeds within %v", timeout)
+		} else {
+			pos = Messagef(callErr, "eventually succeeds within %v, but all %v attempts failed, the last one with this error", timeout, attempts)
+		}
+		neg = Messagef(callErr, "does NOT eventually succeed within %v, but attempt %v succeeded", timeout, attempts)
+		return
+	}
+}
+
+// The actual value must be a function which takes no parameters and returns
+// a value and an error, for example “func() (int, error)”. The function is
+// called and it must not return an error, and the returned value must equal
+// the expected value. Example:
+//    c.Expect(func() (int, error) { return strconv.Atoi("42") }, SucceedsWith, 42)
+func SucceedsWith(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+	results, callErr, err := callReturningError(actual)
+	if err != nil {
+		return
+	}
+	if len(results) != 1 {
+		err = Errorf("type error: expected a function returning a value and an error, but was “%v” of type “%T”", actual, actual)
+		return
+	}
+
+	if callErr != nil {
+		pos = Messagef(callErr, "succeeds with “%v”", expected)
+		neg = Messagef(callErr, "does NOT succeed with “%v”", expected)
+		return
+	}
+	value := results[0]
+	match = areEqual(value, expected)
+	pos = Messagef(value, "succeeds with “%v”", expected)
+	neg = Messagef(value, "does NOT succeed with “%v”", expected)
+	return
+}
+
+// The actual value must be a function which takes no parameters and returns
+// a value and an error, like for SucceedsWith. The function is called and it
+// must not return an error, and the returned value must match the given
+// matcher. The expected value is passed on to the matcher. Example:
+//    c.Expect(func() (float64, error) { return measure() }, ReturnsValueMatching(IsWithin(0.1)), 3.0)
+func ReturnsValueMatching(matcher Matcher) Matcher {
+	return func(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		results, callErr, err := callReturningError(actual)
+		if err != nil {
+			return
+		}
+		if len(results) != 1 {
+			err = Errorf("type error: expected a function returning a value and an error, but was “%v” of type “%T”", actual, actual)
+			return
+		}
+
+		if callErr != nil {
+			pos = Messagef(callErr, "returns a value without an error, but it returned an error")
+			neg = Messagef(callErr, "does NOT return a value without an error")
+			return
+		}
+		value := results[0]
+		match, valuePos, valueNeg, e := matcher.Match(value, expected)
+		if e != nil {
+			err = Errorf("the returned value: %v", e)
+			return
+		}
+		pos = Messagef(value, "returns a value which %v", expectationOr(valuePos, "matches"))
+		neg = Messagef(value, "returns a value which %v", expectationOr(valueNeg, "does NOT match"))
+		return
+	}
+}
+
+// The actual value must be a function of type “func() interface{}”. It is
+// called twice, and the second call must return a value which is equal to
+// the value of the first call, and be at least 'speedupFactor' times faster,
+// as is expected when the result of the first call is cached. Both results
+// and how long the calls took are reported. The check depends on timing, so
+// it can fail for example when the garbage collector runs during the second
+// call, especially when the first call is also fast. Use generous factors,
+// such as 10 when caching makes the call a thousand times faster, and make
+// the first call slow enough to be measured reliably. Example:
+//    c.Expect(func() interface{} { return repo.Find(42) }, IsMemoized(10))
+func IsMemoized(speedupFactor float64) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		call, ok := actual.(func() interface{})
+		if !ok || call == nil {
+			err = Errorf("type error: expected a function of type “func() interface{}”, but was “%v” of type “%T”", actual, actual)
+			return
+		}
+		if speedupFactor <= 0 {
+			err = Errorf("the speedup factor must be positive, but was %v", speedupFactor)
+			return
+		}
+
+		start := time.Now()
+		first := call()
+		firstDuration := time.Since(start)
+		start = time.Now()
+		second := call()
+		secondDuration := time.Since(start)
+
+		match = areEqual(second, first) && float64(secondDuration)*speedupFactor <= float64(firstDuration)
+		calls := fmt.Sprintf("the first call returned “%v” in %v, and the second call returned “%v” in %v",
+			first, firstDuration, second, secondDuration)
+		if match {
+			pos = Messagef(second, "is memoized, with the second call at least %v times faster", speedupFactor)
+		} else {
+			pos = Messagef(second, "is memoized, with the second call at least %v times faster, but %v", speedupFactor, calls)
+		}
+		neg = Messagef(second, "is NOT memoized, with the second call at least %v times faster, but %v", speedupFactor, calls)
+		return
+	}
+}
+
+// Calls the function and returns the values which it returned before the error.
+func callReturningError(function interface{}) (results []interface{}, callErr error, err error) {
+	f := reflect.ValueOf(function)
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if f.Kind() != reflect.Func || f.IsNil() || f.Type().NumIn() != 0 ||
+		f.Type().NumOut() == 0 || f.Type().Out(f.Type().NumOut()-1) != errorType {
+		err = Errorf("type error: expected a function returning an error, but was “%v” of type “%T”", function, function)
+		return
+	}
+
+	out := f.Call([]reflect.Value{})
+	last := len(out) - 1
+	for _, v := range out[:last] {
+		results = append(results, v.Interface())
+	}
+	if !out[last].IsNil() {
+		callErr = out[last].Interface().(error)
+	}
+	return
+}
+
+// The actual value must be a string which is valid in the expected format.
+// The known formats are "email", "url" and "uuid", and more formats can be
+// added with RegisterFormat. Example:
+//    c.Expect(id, IsValid, "uuid")
+func IsValid(actual_ interface{}, format_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, ok := actual_.(string)
+	if !ok {
+		err = Errorf("type error: expected a string, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+	format, ok := format_.(string)
+	if !ok {
+		err = Errorf("type error: expected the name of a format, but was “%v” of type “%T”", format_, format_)
+		return
+	}
+	validator, found := formatValidator(format)
+	if !found {
+		err = Errorf("unknown format “%v”, it can be added with RegisterFormat", format)
+		return
+	}
+
+	match = validator(actual)
+	pos = Messagef(actual, "is a valid %v", format)
+	neg = Messagef(actual, "is NOT a valid %v", format)
+	return
+}
+
+// The actual value must be a *Spy which has been called
+// the expected number of times. Example:
+//    c.Expect(spy, WasCalled, 2)
+func WasCalled(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+	spy, ok := actual.(*Spy)
+	if !ok {
+		err = Errorf("type error: expected a *Spy, but was “%v” of type “%T”", actual, actual)
+		return
+	}
+	times, ok := expected.(int)
+	if !ok {
+		err = Errorf("type error: expected the number of calls, but was “%v” of type “%T”", expected, expected)
+		return
+	}
+
+	match = spy.CallCount() == times
+	pos = Messagef(spy, "was called %v times", times)
+	neg = Messagef(spy, "was NOT called %v times", times)
+	return
+}
+
+// The actual value must equal the value which is got by encoding it and
+// then decoding the encoded bytes. Example:
+//    c.Expect(message, RoundTrips(encodeMessage, decodeMessage))
+func RoundTrips(encode func(interface{}) ([]byte, error), decode func([]byte) (interface{}, error)) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		neg = Messagef(actual, "does NOT round-trip")
+		encoded, encodeErr := encode(actual)
+		if encodeErr != nil {
+			pos = Messagef(actual, "round-trips, but encoding it failed: %v", encodeErr)
+			return
+		}
+		decoded, decodeErr := decode(encoded)
+		if decodeErr != nil {
+			pos = Messagef(actual, "round-trips, but decoding %q failed: %v", encoded, decodeErr)
+			return
+		}
+
+		match = areEqual(decoded, actual)
+		pos = Messagef(actual, "round-trips, but it was decoded from %q as “%v”", encoded, decoded)
+		return
+	}
+}
+
+// Applying the operation twice to the actual value must give the same
+// result as applying it once. Example:
+//    c.Expect(state, IsIdempotent(migrate))
+func IsIdempotent(op func(interface{}) interface{}) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		once, err := applyOperation(op, actual)
+		if err != nil {
+			return
+		}
+		twice, err := applyOperation(op, once)
+		if err != nil {
+			return
+		}
+
+		match = areEqual(twice, once)
+		pos = Messagef(actual, "is idempotent, but applying the operation once gave “%v” and twice gave “%v”", once, twice)
+		neg = Messagef(actual, "is NOT idempotent, but applying the operation once and twice gave “%v”", once)
+		return
+	}
+}
+
+func applyOperation(op func(interface{}) interface{}, value interface{}) (result interface{}, err error) {
+	if cause, panicked := recoverPanic(func() { result = op(value) }); panicked {
+		err = Errorf("the operation panicked when applied to “%v”: %v", value, cause)
+	}
+	return
+}
+
+// The actual function must return the same results as the reference function,
+// when both are called with each of the inputs. If the reference function
+// panics with some input, the actual function must also panic with it.
+// The function must be of the type func(interface{}) interface{}. Example:
+//    c.Expect(fastParse, EquivalentTo(slowParse, Values("", "1", "-1", "1e9")))
+func EquivalentTo(reference func(interface{}) interface{}, inputs []interface{}) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, ok := actual_.(func(interface{}) interface{})
+		if !ok {
+			err = Errorf("type error: expected a function of type “func(interface{}) interface{}”, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+
+		neg = Messagef(actual_, "is NOT equivalent to the reference function with the inputs “%v”", inputs)
+		for _, input := range inputs {
+			expected, expectedErr := applyOperation(reference, input)
+			result, resultErr := applyOperation(actual, input)
+			switch {
+			case expectedErr != nil && resultErr != nil:
+				continue
+			case expectedErr != nil:
+				pos = Messagef(result, "is equivalent to the reference function, but with the input “%v” the reference panicked and the function returned “%v”", input, result)
+				return
+			case resultErr != nil:
+				pos = Messagef(expected, "is equivalent to the reference function, but with the input “%v” the reference returned “%v” and the function panicked", input, expected)
+				return
+			case !areEqual(result, expected):
+				pos = Messagef(result, "is equivalent to the reference function, but with the input “%v” the reference returned “%v” and the function returned “%v”", input, expected, result)
+				return
+			}
+		}
+		match = true
+		pos = Messagef(actual_, "is equivalent to the reference function with the inputs “%v”", inputs)
+		return
+	}
+}
+
+// The actual function must return an equal result every time when it is
+// called the given number of times. The function must be of the type
+// func() interface{}. Example:
+//    c.Expect(func() interface{} { return render(page) }, IsDeterministic(5))
+func IsDeterministic(runs int) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, ok := actual_.(func() interface{})
+		if !ok {
+			err = Errorf("type error: expected a function of type “func() interface{}”, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+		if runs < 2 {
+			err = Errorf("the function must be called at least 2 times, but the number of runs was %v", runs)
+			return
+		}
+
+		var first interface{}
+		for run := 1; run <= runs; run++ {
+			var result interface{}
+			if cause, panicked := recoverPanic(func() { result = actual() }); panicked {
+				err = Errorf("the function panicked on call %v: %v", run, cause)
+				return
+			}
+			if run == 1 {
+				first = result
+			} else if !areEqual(result, first) {
+				pos = Messagef(first, "is deterministic, but call %v of %v returned “%v”", run, runs, result)
+				neg = Messagef(first, "is NOT deterministic")
+				return
+			}
+		}
+		match = true
+		pos = Messagef(first, "is deterministic")
+		neg = Messagef(first, "is NOT deterministic, but all %v calls returned the same result", runs)
+		return
+	}
+}
+
+// The actual value must be deeply equal before and after calling the
+// operation. Usually the actual value is a pointer to the value which the
+// operation should not modify. The value is copied before the operation,
+// following pointers, slices, maps and interfaces, also when the value refers
+// to itself. Unexported struct fields, channels and functions are copied as
+// they are, so changes through pointers in unexported fields and changes to
+// the contents of channels are not detected. Example:
+//    c.Expect(&config, IsUnchangedBy(func() { process(&config) }))
+func IsUnchangedBy(op func()) Matcher {
+	return func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		before := deepCopy(reflect.ValueOf(actual), make(copiedValues))
+		if cause, panicked := recoverPanic(op); panicked {
+			err = Errorf("the operation panicked: %v", cause)
+			return
+		}
+		after := reflect.ValueOf(actual)
+
+		match = reflect.DeepEqual(before.Interface(), after.Interface())
+		pos = Messagef(showPointedValue(after), "is unchanged by the operation, but before the operation it was “%v”", showPointedValue(before))
+		neg = Messagef(showPointedValue(after), "is NOT unchanged by the operation")
+		return
+	}
+}
+
+// The actual value must be a func() which panics when it is called.
+// Example:
+//    c.Expect(func() { stack.Pop() }, Panics)
+func Panics(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	f, ok := actual.(func())
+	if !ok {
+		err = Errorf("type error: expected a func(), but was “%v” of type “%T”", actual, actual)
+		return
+	}
+
+	cause, panicked := recoverPanic(f)
+	match = panicked
+	pos = Messagef(actual, "panics")
+	neg = Messagef(actual, "does NOT panic, but it panicked with “%v”", cause)
+	return
+}
+
+// The actual value must be a func() which panics when it is called, and the
+// value with which it panicked must match the given matcher. The expected
+// value is passed on to the matcher. Example:
+//    c.Expect(func() { stack.Pop() }, PanicsMatching(Equals), "the stack is empty")
+func PanicsMatching(matcher Matcher) Matcher {
+	return func(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		f, ok := actual.(func())
+		if !ok {
+			err = Errorf("type error: expected a func(), but was “%v” of type “%T”", actual, actual)
+			return
+		}
+
+		cause, panicked := recoverPanic(f)
+		if !panicked {
+			pos = Messagef(actual, "panics, but it did not panic")
+			neg = Messagef(actual, "does NOT panic")
+			return
+		}
+		match, causePos, causeNeg, e := matcher.Match(cause, expected)
+		if e != nil {
+			err = Errorf("the panic value: %v", e)
+			return
+		}
+		pos = Messagef(cause, "panics with a value which %v", expectationOr(causePos, "matches"))
+		neg = Messagef(cause, "panics with a value which %v", expectationOr(causeNeg, "does NOT match"))
+		return
+	}
+}
+
+// Calls the function and returns the value with which it panicked.
+// Also panic(nil) is detected, when the recovered value is nil.
+func recoverPanic(f func()) (cause interface{}, panicked bool) {
+	defer func() {
+		if panicked {
+			cause = recover()
+		}
+	}()
+	panicked = true
+	f()
+	panicked = false
+	return
+}
+
+// The pointers, maps and slices which have already been copied, so that
+// the values which refer to themselves are copied without an endless loop,
+// and the values which are shared are still shared in the copy.
+type copiedValues map[copiedValue]reflect.Value
+
+type copiedValue struct {
+	address uintptr
+	length  int
+	typ     reflect.Type
+}
+
+func deepCopy(v reflect.Value, copies copiedValues) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedValue{v.Pointer(), 0, v.Type()}
+		if result, found := copies[key]; found {
+			return result
+		}
+		result := reflect.New(v.Type().Elem())
+		copies[key] = result
+		result.Elem().Set(deepCopy(v.Elem(), copies))
+		return result
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		result := reflect.New(v.Type()).Elem()
+		result.Set(deepCopy(v.Elem(), copies))
+		return result
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedValue{v.Pointer(), v.Len(), v.Type()}
+		if result, found := copies[key]; found {
+			return result
+		}
+		result := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		copies[key] = result
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(deepCopy(v.Index(i), copies))
+		}
+		return result
+	case reflect.Array:
+		result := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(deepCopy(v.Index(i), copies))
+		}
+		return result
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedValue{v.Pointer(), 0, v.Type()}
+		if result, found := copies[key]; found {
+			return result
+		}
+		result := reflect.MakeMap(v.Type())
+		copies[key] = result
+		for _, key := range v.MapKeys() {
+			result.SetMapIndex(key, deepCopy(v.MapIndex(key), copies))
+		}
+		return result
+	case reflect.Struct:
+		result := reflect.New(v.Type()).Elem()
+		result.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := result.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i), copies))
+			}
+		}
+		return result
+	}
+	return v
+}
+
+// Pointers are shown as the values which they point to,
+// because the addresses would not tell what changed.
+func showPointedValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		return fmt.Sprintf("%+v", v.Elem().Interface())
+	}
+	return v.Interface()
+}
+
+// The actual collection must start with the elements of the expected
+// collection, in the same order. Example:
+//    c.Expect(frame, StartsWithSequence, []byte{0xCA, 0xFE})
+func StartsWithSequence(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toArray(expected_)
+	if err != nil {
+		return
+	}
+
+	neg = Messagef(actual, "does NOT start with “%v”", expected)
+	if len(expected) > len(actual) {
+		pos = Messagef(actual, "starts with “%v”, but it is shorter than the sequence", expected)
+		return
+	}
+	if i, differs := firstDifference(actual, expected, 0); differs {
+		pos = Messagef(actual, "starts with “%v”, but differs at index %v", expected, i)
+		return
+	}
+	match = true
+	pos = Messagef(actual, "starts with “%v”", expected)
+	return
+}
+
+// The actual collection must end with the elements of the expected
+// collection, in the same order. Example:
+//    c.Expect(frame, EndsWithSequence, []byte{0x0D, 0x0A})
+func EndsWithSequence(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	actual, err := toArray(actual_)
+	if err != nil {
+		return
+	}
+	expected, err := toArray(expected_)
+	if err != nil {
+		return
+	}
+
+	neg = Messagef(actual, "does NOT end with “%v”", expected)
+	if len(expected) > len(actual) {
+		pos = Messagef(actual, "ends with “%v”, but it is shorter than the sequence", expected)
+		return
+	}
+	if i, differs := firstDifference(actual, expected, len(actual)-len(expected)); differs {
+		pos = Messagef(actual, "ends with “%v”, but differs at index %v", expected, i)
+		return
+	}
+	match = true
+	pos = Messagef(actual, "ends with “%v”", expected)
+	return
+}
+
+// Compares the sequence to the actual elements starting from the offset,
+// and returns the index of the first actual element which differs.
+func firstDifference(actual []interface{}, sequence []interface{}, offset int) (index int, differs bool) {
+	for i, expected := range sequence {
+		if !areEqual(actual[offset+i], expected) {
+			return offset + i, true
+		}
+	}
+	return -1, false
+}
+
+// An ordered container, such as a sorted map or a search tree, which calls
+// 'yield' with each of its keys in the order of the container.
+type KeyIterator interface {
+	Iterate(yield func(key interface{}))
+}
+
+// The actual KeyIterator must yield the expected keys, in the same order and
+// no other keys. The actual value may also be a collection which contains
+// the keys in order, for example the result of a method which returns them.
+// Example:
+//    c.Expect(tree, IteratesInOrder("a", "b", "c"))
+func IteratesInOrder(expectedKeys ...interface{}) Matcher {
+	return func(actual_ interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+		var actual []interface{}
+		if iterator, ok := actual_.(KeyIterator); ok {
+			actual = make([]interface{}, 0)
+			iterator.Iterate(func(key interface{}) {
+				actual = append(actual, key)
+			})
+		} else if actual, err = toArray(actual_); err != nil {
+			err = Errorf("type error: expected a KeyIterator or a collection, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+
+		neg = Messagef(actual, "does NOT iterate in the order “%v”", expectedKeys)
+		shorter := len(actual)
+		if len(expectedKeys) < shorter {
+			shorter = len(expectedKeys)
+		}
+		if i, differs := firstDifference(actual[:shorter], expectedKeys[:shorter], 0); differs {
+			pos = Messagef(actual, "iterates in the order “%v”, but the key at index %v was “%v” instead of “%v”", expectedKeys, i, actual[i], expectedKeys[i])
+			return
+		}
+		switch {
+		case len(actual) < len(expectedKeys):
+			pos = Messagef(actual, "iterates in the order “%v”, but it ended after %v keys", expectedKeys, len(actual))
+		case len(actual) > len(expectedKeys):
+			pos = Messagef(actual, "iterates in the order “%v”, but it yielded an extra key “%v”", expectedKeys, actual[len(expectedKeys)])
+		default:
+			match = true
+			pos = Messagef(actual, "iterates in the order “%v”", expectedKeys)
+		}
+		return
+	}
+}
+
+// The actual string must differ from the expected string by at most
+// the given number of inserted, deleted or substituted characters
+// (the Levenshtein distance). Example:
+//    c.Expect(greeting, IsSimilarTo(2), "Hello, world!")
+func IsSimilarTo(maxEditDistance int) Matcher {
+	return func(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+		actual, ok := actual_.(string)
+		if !ok {
+			err = Errorf("type error: expected a string, but was “%v” of type “%T”", actual_, actual_)
+			return
+		}
+		expected, ok := expected_.(string)
+		if !ok {
+			err = Errorf("type error: expected a string, but was “%v” of type “%T”", expected_, expected_)
+			return
+		}
+
+		distance := editDistance(actual, expected)
+		match = distance <= maxEditDistance
+		pos = Messagef(actual, "is within edit distance %v of “%v”, but the distance was %v, diff:\n%v",
+			maxEditDistance, expected, distance, lazyLineDiff{expected, actual})
+		neg = Messagef(actual, "is NOT within edit distance %v of “%v”, but the distance was %v",
+			maxEditDistance, expected, distance)
+		return
+	}
+}
+
+// Levenshtein distance between the strings, counted in runes.
+func editDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	if len(s) < len(t) {
+		s, t = t, s
+	}
+	// Only the previous row of the distance matrix is needed,
+	// so the memory use is proportional to the shorter string.
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(t)]
+}
+
+func minInt(first int, rest ...int) int {
+	min := first
+	for _, v := range rest {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// Every element of the actual collection must match the given matcher.
+// The expected value is passed on to the matcher. Also maps are supported,
+// in which case the matcher is applied to the map's values. Unlike
+// AllMatching, the failure message lists the reason why every failing
+// element did not match. Example:
+//    c.Expect(names, Each(Not(Equals)), "")
+//    c.Expect(scores, Each(IsWithin(0.5)), 3.0)
+func Each(matcher Matcher) Matcher {
+	return func(actual interface{}, expected interface{}) (match bool, pos Message, neg Message, err error) {
+		labels, elements, err := toLabeledElements(actual)
+		if err != nil {
+			return
+		}
+
+		description := "matches"
+		failures := make([]string, 0)
+		for i, element := range elements {
+			m, p, _, e := matcher.Match(element, expected)
+			if e != nil {
+				err = Errorf("element at %v: %v", labels[i], e)
+				return
+			}
+			expectation := description
+			if p != nil {
+				expectation = p.Expectation()
+				description = expectation
+			}
+			if !m {
+				failures = append(failures, fmt.Sprintf("at %v “%v”: %v", labels[i], element, expectation))
+			}
+		}
+
+		match = len(failures) == 0
+		pos = Messagef(actual, "each element %v, but %v of the %v elements did not match:\n  %v",
+			description, len(failures), len(elements), strings.Join(failures, "\n  "))
+		neg = Messagef(actual, "NOT each element %v", description)
+		return
+	}
+}
+
+// Returns the elements of a collection together with a description of
+// where each element is. Map entries are sorted by their keys, so that
+// the order is the same on every run.
+func toLabeledElements(values interface{}) (labels []string, elements []interface{}, err error) {
+	if v := reflect.ValueOf(values); v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			labels = append(labels, fmt.Sprintf("key “%v”", key.Interface()))
+			elements = append(elements, v.MapIndex(key).Interface())
+		}
+		return
+	}
+	elements, err = toArray(values)
+	for i := range elements {
+		labels = append(labels, fmt.Sprintf("index %v", i))
+	}
+	return
+}
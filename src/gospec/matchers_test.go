@@ -5,12 +5,20 @@
 package gospec
 
 import (
+	"bytes"
 	"container/list"
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
 	"math"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
 	"os"
+	"path/filepath"
+	"strings"
+	"testing/iotest"
+	"time"
 )
 
 func MatcherMessagesSpec(c nanospec.Context) {
@@ -95,6 +103,158 @@ func MatchersSpec(c nanospec.Context) {
 		})
 	})
 
+	c.Specify("Matcher: ReadsAs", func() {
+		c.Expect(E(strings.NewReader("line 1\nline 2"), ReadsAs, "line 1\nline 2")).Matches(Passes)
+		c.Expect(E(strings.NewReader("abc"), ReadsAs, []byte("abc"))).Matches(Passes)
+		c.Expect(E(strings.NewReader("line 1\nline X"), ReadsAs, "line 1\nline 2")).Matches(FailsWithMessage(
+			"reads as “line 1\nline 2”, diff:\n  line 1\n- line 2\n+ line X",
+			"does NOT read as “line 1\nline 2”"))
+
+		c.Specify("buffers are not drained", func() {
+			buf := bytes.NewBufferString("abc")
+			c.Expect(E(buf, ReadsAs, "abc")).Matches(Passes)
+			c.Expect(buf.String()).Equals("abc")
+		})
+		c.Specify("read errors are errors", func() {
+			c.Expect(E(iotest.ErrReader(errors.New("broken")), ReadsAs, "")).Matches(GivesError("cannot read from the reader: broken"))
+		})
+		c.Specify("cannot read non-readers", func() {
+			c.Expect(E("abc", ReadsAs, "abc")).Matches(GivesError("type error: expected an io.Reader, but was “abc” of type “string”"))
+			c.Expect(E(strings.NewReader("1"), ReadsAs, 1)).Matches(GivesError("type error: expected a string or []byte, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: ImageMatches", func() {
+		newImage := func(bounds image.Rectangle, fill color.RGBA) *image.RGBA {
+			img := image.NewRGBA(bounds)
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					img.SetRGBA(x, y, fill)
+				}
+			}
+			return img
+		}
+		gray := color.RGBA{100, 100, 100, 255}
+		expected := newImage(image.Rect(0, 0, 4, 3), gray)
+
+		c.Expect(E(newImage(image.Rect(0, 0, 4, 3), gray), ImageMatches(expected, 0))).Matches(Passes)
+		c.Expect(E(newImage(image.Rect(10, 10, 14, 13), gray), ImageMatches(expected, 0))).Matches(Passes)
+
+		c.Specify("the channels may differ within the tolerance", func() {
+			actual := newImage(image.Rect(0, 0, 4, 3), gray)
+			actual.SetRGBA(2, 1, color.RGBA{102, 98, 100, 255})
+			c.Expect(E(actual, ImageMatches(expected, 2))).Matches(Passes)
+			c.Expect(E(actual, ImageMatches(expected, 1))).Matches(FailsWithMessage(
+				"matches the expected image within 1 per channel, but the pixel at (2, 1) was RGBA(102, 98, 100, 255) instead of RGBA(100, 100, 100, 255), a difference of 2",
+				"does NOT match the expected image within 1 per channel"))
+		})
+		c.Specify("the images must have the same size", func() {
+			c.Expect(E(newImage(image.Rect(0, 0, 3, 4), gray), ImageMatches(expected, 255))).Matches(FailsWithMessage(
+				"matches the expected image within 255 per channel, but its size was 3x4 instead of 4x3",
+				"does NOT match the expected image within 255 per channel"))
+		})
+		c.Specify("the actual pixels are not printed", func() {
+			_, pos, _, _ := ImageMatches(expected, 0).Match(newImage(image.Rect(0, 0, 1, 1), gray))
+			c.Expect(pos.Actual()).Equals("*image.RGBA (0,0)-(1,1)")
+		})
+		c.Specify("cannot compare other values", func() {
+			c.Expect(E("img", ImageMatches(expected, 0))).Matches(GivesError("type error: expected an image.Image, but was “img” of type “string”"))
+		})
+		c.Specify("cannot compare nil images", func() {
+			var nilImage *image.RGBA
+			c.Expect(E(nil, ImageMatches(expected, 0))).Matches(GivesError("type error: expected an image.Image, but was “<nil>” of type “<nil>”"))
+			c.Expect(E(nilImage, ImageMatches(expected, 0))).Matches(GivesError("type error: expected an image.Image, but was “<nil>” of type “*image.RGBA”"))
+			c.Expect(E(expected, ImageMatches(nil, 0))).Matches(GivesError("the expected image must not be nil"))
+			c.Expect(E(expected, ImageMatches(nilImage, 0))).Matches(GivesError("the expected image must not be nil"))
+		})
+	})
+	c.Specify("Matcher: ReadsSameAs", func() {
+		c.Expect(E(strings.NewReader("abc"), ReadsSameAs(strings.NewReader("abc")))).Matches(Passes)
+		c.Expect(E(strings.NewReader(""), ReadsSameAs(strings.NewReader("")))).Matches(Passes)
+
+		c.Specify("the first difference is reported with the bytes around it", func() {
+			actual := strings.NewReader("0123456789abcdefghij")
+			other := strings.NewReader("0123456789abXdefghij")
+			match, pos, neg, _ := ReadsSameAs(other).Match(actual)
+			c.Expect(match).IsFalse()
+			c.Expect(pos.Expectation()).Equals("reads the same bytes as the other reader, but they differed at offset 12, " +
+				"where it had [34 35 36 37 38 39 61 62 63 64 65 66 67 68 69 6a] instead of [34 35 36 37 38 39 61 62 58 64 65 66 67 68 69 6a]")
+			c.Expect(neg.Expectation()).Equals("does NOT read the same bytes as the other reader")
+		})
+		c.Specify("streams of different lengths differ", func() {
+			_, pos, _, _ := ReadsSameAs(strings.NewReader("abcd")).Match(strings.NewReader("ab"))
+			c.Expect(pos.Expectation()).Equals("reads the same bytes as the other reader, but it ended at offset 2, where the other reader had [61 62 63 64]")
+			_, pos, _, _ = ReadsSameAs(strings.NewReader("ab")).Match(strings.NewReader("abcd"))
+			c.Expect(pos.Expectation()).Equals("reads the same bytes as the other reader, but the other reader ended at offset 2, where it had [61 62 63 64]")
+		})
+		c.Specify("streams longer than one chunk are compared", func() {
+			long := strings.Repeat("x", readerChunkSize+10)
+			c.Expect(E(iotest.OneByteReader(strings.NewReader(long)), ReadsSameAs(strings.NewReader(long)))).Matches(Passes)
+			_, pos, _, _ := ReadsSameAs(strings.NewReader(long+"y")).Match(strings.NewReader(long + "z"))
+			c.Expect(pos.Expectation()).Satisfies(strings.HasPrefix(pos.Expectation(), fmt.Sprintf(
+				"reads the same bytes as the other reader, but they differed at offset %v,", readerChunkSize+10)))
+		})
+		c.Specify("read errors are errors", func() {
+			c.Expect(E(iotest.ErrReader(errors.New("broken")), ReadsSameAs(strings.NewReader("")))).Matches(GivesError("cannot read from the reader: broken"))
+			c.Expect(E(strings.NewReader(""), ReadsSameAs(iotest.ErrReader(errors.New("broken"))))).Matches(GivesError("cannot read from the other reader: broken"))
+		})
+		c.Specify("cannot read non-readers", func() {
+			c.Expect(E("abc", ReadsSameAs(strings.NewReader("abc")))).Matches(GivesError("type error: expected an io.Reader, but was “abc” of type “string”"))
+		})
+	})
+
+	c.Specify("Matcher: StringsAs", func() {
+		c.Expect(E(time.Duration(90)*time.Second, StringsAs, "1m30s")).Matches(Passes)
+		c.Expect(E(time.Second, StringsAs, "1m")).Matches(FailsWithMessage(
+			"is a time.Duration whose String() is “1m”",
+			"is a time.Duration whose String() is NOT “1m”"))
+
+		c.Specify("cannot compare non-Stringers", func() {
+			c.Expect(E(42, StringsAs, "42")).Matches(GivesError("type error: expected a fmt.Stringer, but was “42” of type “int”"))
+			c.Expect(E(time.Second, StringsAs, 1)).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: ParsesAsNumber", func() {
+		c.Expect(E("42", ParsesAsNumber, 42)).Matches(Passes)
+		c.Expect(E("-7", ParsesAsNumber, int8(-7))).Matches(Passes)
+		c.Expect(E("255", ParsesAsNumber, uint8(255))).Matches(Passes)
+		c.Expect(E("2.5", ParsesAsNumber, 2.5)).Matches(Passes)
+		c.Expect(E("1e3", ParsesAsNumber, float32(1000))).Matches(Passes)
+		c.Expect(E("43", ParsesAsNumber, 42)).Matches(FailsWithMessage(
+			"parses as the int “42”, but it was parsed as “43”",
+			"does NOT parse as the int “42”"))
+
+		c.Specify("the string must be parseable as the type of the expected number", func() {
+			c.Expect(E("42.0", ParsesAsNumber, 42)).Matches(FailsWithMessage(
+				"parses as the int “42”, but it could not be parsed: strconv.ParseInt: parsing \"42.0\": invalid syntax",
+				"does NOT parse as the int “42”"))
+			c.Expect(E("256", ParsesAsNumber, uint8(255))).Matches(FailsWithMessage(
+				"parses as the uint8 “255”, but it could not be parsed: strconv.ParseUint: parsing \"256\": value out of range",
+				"does NOT parse as the uint8 “255”"))
+		})
+		c.Specify("cannot parse other values", func() {
+			c.Expect(E(42, ParsesAsNumber, 42)).Matches(GivesError("type error: expected a string, but was “42” of type “int”"))
+			c.Expect(E("42", ParsesAsNumber, "42")).Matches(GivesError("type error: expected a number, but was “42” of type “string”"))
+		})
+	})
+
+	c.Specify("Matcher: IsValidUTF8", func() {
+		c.Expect(E("häagen", IsValidUTF8)).Matches(Passes)
+		c.Expect(E([]byte("日本"), IsValidUTF8)).Matches(Passes)
+		c.Expect(E("", IsValidUTF8)).Matches(Passes)
+		c.Expect(E("ab\xffcd", IsValidUTF8)).Matches(FailsWithMessage(
+			"is valid UTF-8, but the bytes at offset 2 were invalid: [ff 63 64]",
+			"is NOT valid UTF-8"))
+		c.Expect(E([]byte{'a', 0xe6, 0x97}, IsValidUTF8)).Matches(FailsWithMessage(
+			"is valid UTF-8, but the bytes at offset 1 were invalid: [e6 97]",
+			"is NOT valid UTF-8"))
+
+		c.Specify("cannot check other values", func() {
+			c.Expect(E(42, IsValidUTF8)).Matches(GivesError("type error: expected a string or []byte, but was “42” of type “int”"))
+		})
+	})
+
 	c.Specify("Matcher: IsSame", func() {
 		a1 := new(os.File)
 		a2 := a1
@@ -134,6 +294,30 @@ func MatchersSpec(c nanospec.Context) {
 			"is NOT <false>"))
 	})
 
+	c.Specify("Matcher: IsZero", func() {
+		type point struct {
+			X, Y int
+			name string
+		}
+		c.Expect(E(0, IsZero)).Matches(Passes)
+		c.Expect(E("", IsZero)).Matches(Passes)
+		c.Expect(E(point{}, IsZero)).Matches(Passes)
+		c.Expect(E(nil, IsZero)).Matches(Passes)         // interface value nil
+		c.Expect(E((*int)(nil), IsZero)).Matches(Passes) // typed pointer nil inside an interface value
+		c.Expect(E([]int(nil), IsZero)).Matches(Passes)
+		c.Expect(E([]int{}, IsZero)).Matches(Fails)
+		c.Expect(E(new(int), IsZero)).Matches(Fails)
+		c.Expect(E(1.5, IsZero)).Matches(FailsWithMessage(
+			"is the zero value of type “float64”",
+			"is NOT the zero value of type “float64”"))
+
+		c.Specify("lists the non-zero fields of structs", func() {
+			c.Expect(E(point{X: 1, name: "a"}, IsZero)).Matches(FailsWithMessage(
+				"is the zero value of type “gospec.point”, but the fields X, name were not zero",
+				"is NOT the zero value of type “gospec.point”"))
+		})
+	})
+
 	c.Specify("Matcher: Satisfies", func() {
 		value := 42
 
@@ -143,6 +327,25 @@ func MatchersSpec(c nanospec.Context) {
 			"does NOT satisfy the criteria"))
 	})
 
+	c.Specify("Matcher: IsWithinPercent", func() {
+		c.Expect(E(1009.0, IsWithinPercent(1), 1000.0)).Matches(Passes)
+		c.Expect(E(990.0, IsWithinPercent(1), 1000.0)).Matches(Passes)
+		c.Expect(E(-1005.0, IsWithinPercent(1), -1000.0)).Matches(Passes)
+		c.Expect(E(1025.0, IsWithinPercent(1), 1000.0)).Matches(FailsWithMessage(
+			"is within 1% of 1000, but deviated by 2.5%",
+			"is NOT within 1% of 1000, but deviated by 2.5%"))
+
+		c.Specify("an expected 0 is compared with an absolute tolerance", func() {
+			c.Expect(E(0.005, IsWithinPercent(1), 0.0)).Matches(Passes)
+			c.Expect(E(-0.02, IsWithinPercent(1), 0.0)).Matches(FailsWithMessage(
+				"is within 0.01 of 0",
+				"is NOT within 0.01 of 0"))
+		})
+		c.Specify("cannot compare ints", func() {
+			c.Expect(E(1000, IsWithinPercent(1), 1000.0)).Matches(GivesError("type error: expected a float, but was “1000” of type “int”"))
+		})
+	})
+
 	c.Specify("Matcher: IsWithin", func() {
 		value := float64(3.141)
 		pi := float64(math.Pi)
@@ -161,6 +364,100 @@ func MatchersSpec(c nanospec.Context) {
 		})
 	})
 
+	c.Specify("Matcher: HasMeanWithin", func() {
+		c.Expect(E([]int{1, 2, 3, 6}, HasMeanWithin(3, 0))).Matches(Passes)
+		c.Expect(E([]float64{1.5, 2.5}, HasMeanWithin(2.1, 0.2))).Matches(Passes)
+		c.Expect(E([]float64{1.5, 2.5}, HasMeanWithin(2.5, 0.25))).Matches(FailsWithMessage(
+			"has a mean within 2.5 ± 0.25, but the mean was 2",
+			"does NOT have a mean within 2.5 ± 0.25, but the mean was 2"))
+
+		c.Specify("cannot compute the mean of other values", func() {
+			c.Expect(E([]int{}, HasMeanWithin(0, 1))).Matches(GivesError("cannot compute the mean of an empty collection"))
+			c.Expect(E([]string{"1"}, HasMeanWithin(0, 1))).Matches(GivesError("type error: expected a number, but was “1” of type “string”"))
+			c.Expect(E(42, HasMeanWithin(0, 1))).Matches(GivesError("type error: expected a collection type, but was “42” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: HasStdDevWithin", func() {
+		c.Expect(E([]int{2, 4, 4, 4, 5, 5, 7, 9}, HasStdDevWithin(2, 0))).Matches(Passes)
+		c.Expect(E([]float64{5, 5, 5}, HasStdDevWithin(0, 0))).Matches(Passes)
+		c.Expect(E([]int{2, 4, 4, 4, 5, 5, 7, 9}, HasStdDevWithin(1, 0.5))).Matches(FailsWithMessage(
+			"has a standard deviation within 1 ± 0.5, but the standard deviation was 2",
+			"does NOT have a standard deviation within 1 ± 0.5, but the standard deviation was 2"))
+		c.Expect(E([]int{}, HasStdDevWithin(0, 1))).Matches(GivesError("cannot compute the standard deviation of an empty collection"))
+	})
+
+	c.Specify("Matcher: IsWithinComplex", func() {
+		value := complex(3.0, 4.0)
+
+		c.Expect(E(value, IsWithinComplex(0.1), complex(3.05, 3.95))).Matches(Passes)
+		c.Expect(E(complex64(value), IsWithinComplex(0.1), value)).Matches(Passes)
+		c.Expect(E(value, IsWithinComplex(1.0), complex(0, 0))).Matches(FailsWithMessage(
+			"is within (0+0i) ± 1, but the distance was 5",
+			"is NOT within (0+0i) ± 1, but the distance was 5"))
+
+		c.Specify("cannot compare non-complex numbers", func() {
+			c.Expect(E(3.0, IsWithinComplex(0.1), value)).Matches(GivesError("type error: expected a complex number, but was “3” of type “float64”"))
+			c.Expect(E(value, IsWithinComplex(0.1), 3)).Matches(GivesError("type error: expected a complex number, but was “3” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsWholeNumber", func() {
+		c.Expect(E(3.0, IsWholeNumber)).Matches(Passes)
+		c.Expect(E(-3.0, IsWholeNumber)).Matches(Passes)
+		c.Expect(E(math.Copysign(0, -1), IsWholeNumber)).Matches(Passes)
+		c.Expect(E(float32(1e10), IsWholeNumber)).Matches(Passes)
+		c.Expect(E(-2.5, IsWholeNumber)).Matches(FailsWithMessage(
+			"is a whole number, but its fractional part was -0.5",
+			"is NOT a whole number"))
+		c.Expect(E(math.Inf(1), IsWholeNumber)).Matches(Fails)
+		c.Expect(E(math.NaN(), IsWholeNumber)).Matches(Fails)
+		c.Expect(E(3, IsWholeNumber)).Matches(GivesError("type error: expected a float, but was “3” of type “int”"))
+	})
+
+	c.Specify("Matcher: IsMultipleOf", func() {
+		c.Expect(E(12, IsMultipleOf(4))).Matches(Passes)
+		c.Expect(E(-12, IsMultipleOf(4))).Matches(Passes)
+		c.Expect(E(12, IsMultipleOf(-4))).Matches(Passes)
+		c.Expect(E(uint8(0), IsMultipleOf(7))).Matches(Passes)
+		c.Expect(E(uint64(math.MaxUint64), IsMultipleOf(-5))).Matches(Passes)
+		c.Expect(E(13, IsMultipleOf(4))).Matches(FailsWithMessage(
+			"is a multiple of 4, but the remainder was 1",
+			"is NOT a multiple of 4"))
+
+		c.Specify("cannot check non-integers or multiples of zero", func() {
+			c.Expect(E(12.0, IsMultipleOf(4))).Matches(GivesError("type error: expected an integer, but was “12” of type “float64”"))
+			c.Expect(E(12, IsMultipleOf(0))).Matches(GivesError("cannot check for multiples of 0"))
+		})
+	})
+
+	c.Specify("Matcher: IsBitEqual", func() {
+		a, b := 0.1, 0.2
+
+		c.Expect(E(a+b, IsBitEqual, 0.30000000000000004)).Matches(Passes)
+		c.Expect(E(float32(1.5), IsBitEqual, float32(1.5))).Matches(Passes)
+		c.Expect(E(float32(1.5), IsBitEqual, 1.5)).Matches(Passes)
+		c.Expect(E(a+b, IsBitEqual, 0.3)).Matches(FailsWithMessage(
+			"is bit-equal to “0.3” (0x3fd3333333333333), but its bits were 0x3fd3333333333334",
+			"is NOT bit-equal to “0.3” (0x3fd3333333333333)"))
+
+		c.Specify("positive and negative zero are different", func() {
+			c.Expect(E(math.Copysign(0, -1), IsBitEqual, 0.0)).Matches(FailsWithMessage(
+				"is bit-equal to “0” (0x0000000000000000), but its bits were 0x8000000000000000",
+				"is NOT bit-equal to “0” (0x0000000000000000)"))
+		})
+		c.Specify("NaN is equal to a NaN with the same bits", func() {
+			c.Expect(E(math.NaN(), IsBitEqual, math.NaN())).Matches(Passes)
+			c.Expect(E(math.NaN(), IsBitEqual, math.Float64frombits(0x7ff8000000000002))).Matches(FailsWithMessage(
+				"is bit-equal to “NaN” (0x7ff8000000000002), but its bits were 0x7ff8000000000001",
+				"is NOT bit-equal to “NaN” (0x7ff8000000000002)"))
+		})
+		c.Specify("cannot compare non-floats", func() {
+			c.Expect(E(3, IsBitEqual, 3.0)).Matches(GivesError("type error: expected a float, but was “3” of type “int”"))
+			c.Expect(E(3.0, IsBitEqual, 3)).Matches(GivesError("type error: expected a float, but was “3” of type “int”"))
+		})
+	})
+
 	c.Specify("Matcher: Contains", func() {
 		values := []string{"one", "two", "three"}
 
@@ -202,6 +499,39 @@ func MatchersSpec(c nanospec.Context) {
 			"does NOT contain any of “[four five]”"))
 	})
 
+	c.Specify("Matcher: ContainsAllSubstrings", func() {
+		log := "server started on port 8080"
+
+		c.Expect(E(log, ContainsAllSubstrings, Values("started", "8080"))).Matches(Passes)
+		c.Expect(E(log, ContainsAllSubstrings, []string{"server"})).Matches(Passes)
+		c.Expect(E(log, ContainsAllSubstrings, Values())).Matches(Passes)
+		c.Expect(E(log, ContainsAllSubstrings, Values("started", "stopped", "9090"))).Matches(FailsWithMessage(
+			"contains all of the substrings “[started stopped 9090]”, but was missing “[stopped 9090]”",
+			"does NOT contain all of the substrings “[started stopped 9090]”"))
+
+		c.Specify("cannot check non-strings", func() {
+			c.Expect(E(1, ContainsAllSubstrings, Values("1"))).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+			c.Expect(E(log, ContainsAllSubstrings, Values(1))).Matches(GivesError("type error: expected the substrings to be strings, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: ContainsAnySubstring", func() {
+		log := "server started on port 8080"
+
+		c.Expect(E(log, ContainsAnySubstring, Values("stopped", "started"))).Matches(Passes)
+		c.Expect(E(log, ContainsAnySubstring, Values())).Matches(Fails)
+		c.Expect(E(log, ContainsAnySubstring, Values("error", "warning"))).Matches(FailsWithMessage(
+			"contains any of the substrings “[error warning]”",
+			"does NOT contain any of the substrings “[error warning]”, but contained “[]”"))
+		c.Expect(E(log, Not(ContainsAnySubstring), Values("error", "port", "server"))).Matches(FailsWithMessage(
+			"does NOT contain any of the substrings “[error port server]”, but contained “[port server]”",
+			"contains any of the substrings “[error port server]”"))
+
+		c.Specify("cannot check non-strings", func() {
+			c.Expect(E(1, ContainsAnySubstring, Values("1"))).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+		})
+	})
+
 	c.Specify("Matcher: ContainsExactly", func() {
 		values := []string{"one", "two", "three"}
 
@@ -334,6 +664,1167 @@ func MatchersSpec(c nanospec.Context) {
 				})
 			}
 		})
+
+	c.Specify("Matcher: EmitsSequence", func() {
+		emit := func(values ...int) chan int {
+			ch := make(chan int, len(values))
+			for _, value := range values {
+				ch <- value
+			}
+			return ch
+		}
+		closed := func(ch chan int) chan int {
+			close(ch)
+			return ch
+		}
+		timeout := 10 * time.Millisecond
+
+		c.Expect(E(closed(emit(1, 2, 3)), EmitsSequence(timeout), []int{1, 2, 3})).Matches(Passes)
+		c.Expect(E(closed(emit()), EmitsSequence(timeout), []int{})).Matches(Passes)
+		c.Expect(E(closed(emit(1, 5, 3)), EmitsSequence(timeout), []int{1, 2, 3})).Matches(FailsWithMessage(
+			"emits “[1 2 3]” and is closed, but the value at index 1 was “5”",
+			"does NOT emit “[1 2 3]” and is closed"))
+
+		c.Specify("the values may be emitted later", func() {
+			ch := make(chan int)
+			go func() {
+				ch <- 1
+				time.Sleep(timeout / 2)
+				ch <- 2
+				close(ch)
+			}()
+			c.Expect(E(ch, EmitsSequence(timeout), []int{1, 2})).Matches(Passes)
+		})
+		c.Specify("the channel may not be closed too early", func() {
+			c.Expect(E(closed(emit(1)), EmitsSequence(timeout), []int{1, 2})).Matches(FailsWithMessage(
+				"emits “[1 2]” and is closed, but it was closed after 1 values",
+				"does NOT emit “[1 2]” and is closed"))
+		})
+		c.Specify("the channel must be closed after the values", func() {
+			c.Expect(E(emit(1, 2), EmitsSequence(timeout), []int{1, 2})).Matches(FailsWithMessage(
+				"emits “[1 2]” and is closed, but it was not closed within 10ms",
+				"does NOT emit “[1 2]” and is closed"))
+			c.Expect(E(closed(emit(1, 2, 3)), EmitsSequence(timeout), []int{1, 2})).Matches(FailsWithMessage(
+				"emits “[1 2]” and is closed, but it emitted an extra value “3”",
+				"does NOT emit “[1 2]” and is closed"))
+		})
+		c.Specify("the values must be emitted within the timeout", func() {
+			c.Expect(E(emit(1), EmitsSequence(timeout), []int{1, 2})).Matches(FailsWithMessage(
+				"emits “[1 2]” and is closed, but no value at index 1 was emitted within 10ms",
+				"does NOT emit “[1 2]” and is closed"))
+		})
+		c.Specify("cannot check non-channels", func() {
+			c.Expect(E(1, EmitsSequence(timeout), []int{1})).Matches(GivesError("type error: expected a receivable channel, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: Panics", func() {
+		panicking := func() { panic("boom") }
+		returning := func() {}
+
+		c.Expect(E(panicking, Panics)).Matches(Passes)
+		c.Expect(E(returning, Panics)).Matches(Fails)
+		c.Expect(E(func() { panic(nil) }, Panics)).Matches(Passes)
+		c.Expect(E(1, Panics)).Matches(GivesError("type error: expected a func(), but was “1” of type “int”"))
+
+		c.Specify("the panic value is reported when it was not expected", func() {
+			_, _, neg, _ := Panics(panicking, nil)
+			c.Expect(neg.Expectation()).Equals("does NOT panic, but it panicked with “boom”")
+		})
+	})
+
+	c.Specify("Matcher: PanicsMatching", func() {
+		panicking := func() { panic("boom") }
+
+		c.Expect(E(panicking, PanicsMatching(Equals), "boom")).Matches(Passes)
+		c.Expect(E(panicking, PanicsMatching(Equals), "bang")).Matches(FailsWithMessage(
+			"panics with a value which equals “bang”",
+			"panics with a value which does NOT equal “bang”"))
+		c.Expect(E(func() {}, PanicsMatching(Equals), "boom")).Matches(FailsWithMessage(
+			"panics, but it did not panic",
+			"does NOT panic"))
+
+		c.Specify("the panic value is reported as the actual value", func() {
+			_, pos, _, _ := PanicsMatching(Equals)(panicking, "bang")
+			c.Expect(pos.Actual()).Equals("boom")
+		})
+		c.Specify("the matcher of the panic value may give no messages", func() {
+			c.Expect(E(func() { panic(1) }, PanicsMatching(isPositiveWithoutMessages))).Matches(Passes)
+			c.Expect(E(func() { panic(-1) }, PanicsMatching(isPositiveWithoutMessages))).Matches(FailsWithMessage(
+				"panics with a value which matches",
+				"panics with a value which does NOT match"))
+		})
+		c.Specify("errors from the matcher are reported", func() {
+			c.Expect(E(panicking, PanicsMatching(IsWithin(0.1)), 1.0)).Matches(GivesError(
+				"the panic value: type error: expected a float, but was “boom” of type “string”"))
+		})
+		c.Specify("cannot check non-functions", func() {
+			c.Expect(E(1, PanicsMatching(Equals), 1)).Matches(GivesError("type error: expected a func(), but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsClosed", func() {
+		open := make(chan int, 2)
+		closed := make(chan int, 2)
+		close(closed)
+		buffered := make(chan int, 2)
+		buffered <- 1
+		buffered <- 2
+		close(buffered)
+
+		c.Expect(E(closed, IsClosed)).Matches(Passes)
+		c.Expect(E(open, IsClosed)).Matches(FailsWithMessage(
+			"is closed, but it is still open",
+			"is NOT closed"))
+
+		c.Specify("a closed channel with buffered values is not yet closed", func() {
+			c.Expect(E(buffered, IsClosed)).Matches(FailsWithMessage(
+				"is closed, but it has 2 buffered values",
+				"is NOT closed"))
+		})
+		c.Specify("the buffered values are not consumed", func() {
+			E(buffered, IsClosed)
+			c.Expect(len(buffered)).Equals(2)
+		})
+		c.Specify("cannot check non-channels", func() {
+			c.Expect(E(1, IsClosed)).Matches(GivesError("type error: expected a receivable channel, but was “1” of type “int”"))
+		})
+		c.Specify("cannot check send-only channels", func() {
+			var sendOnly chan<- int = open
+			c.Expect(E(sendOnly, IsClosed)).Matches(GivesError(fmt.Sprintf(
+				"type error: expected a receivable channel, but was “%v” of type “chan<- int”", sendOnly)))
+		})
+	})
+
+	c.Specify("Matcher: HasBufferedLen", func() {
+		queue := make(chan int, 4)
+		queue <- 1
+		queue <- 2
+
+		c.Expect(E(queue, HasBufferedLen(2))).Matches(Passes)
+		c.Expect(E(make(chan int), HasBufferedLen(0))).Matches(Passes)
+		c.Expect(E(queue, HasBufferedLen(3))).Matches(FailsWithMessage(
+			"has 3 buffered values, but had 2 of capacity 4",
+			"does NOT have 3 buffered values"))
+
+		c.Specify("can check send-only channels", func() {
+			var sendOnly chan<- int = queue
+			c.Expect(E(sendOnly, HasBufferedLen(2))).Matches(Passes)
+		})
+		c.Specify("cannot check non-channels", func() {
+			c.Expect(E([]int{1, 2}, HasBufferedLen(2))).Matches(GivesError("type error: expected a channel, but was “[1 2]” of type “[]int”"))
+		})
+	})
+
+	c.Specify("Matcher: ContainsBy", func() {
+		values := []DummyStruct{{1, 10}, {2, 20}, {3, 30}}
+		sameIgnoredValue := func(a, b interface{}) bool {
+			return a.(DummyStruct).ignoredValue == b.(DummyStruct).ignoredValue
+		}
+
+		c.Expect(E(values, ContainsBy(sameIgnoredValue), DummyStruct{999, 20})).Matches(Passes)
+		c.Expect(E(values, ContainsBy(sameIgnoredValue), DummyStruct{2, 999})).Matches(FailsWithMessage(
+			"contains “DummyStruct2”",
+			"does NOT contain “DummyStruct2”"))
+	})
+
+	c.Specify("Matcher: IsUniqueBy", func() {
+		length := func(s interface{}) interface{} {
+			return len(s.(string))
+		}
+
+		c.Expect(E([]string{"a", "bb", "ccc"}, IsUniqueBy(length))).Matches(Passes)
+		c.Expect(E([]string{}, IsUniqueBy(length))).Matches(Passes)
+		c.Expect(E([]string{"a", "bb", "cc", "d", "ee"}, IsUniqueBy(length))).Matches(FailsWithMessage(
+			"has unique keys, but the key “2” was at indices [1 2 4]",
+			"does NOT have unique keys"))
+		c.Expect(E(1, IsUniqueBy(length))).Matches(GivesError("type error: expected a collection type, but was “1” of type “int”"))
+	})
+
+	c.Specify("Matcher: ContainsExactlyBy", func() {
+		values := []string{"one", "two", "three"}
+		sameLength := func(a, b interface{}) bool {
+			return len(a.(string)) == len(b.(string))
+		}
+
+		c.Expect(E(values, ContainsExactlyBy(sameLength), Values("xxxxx", "xxx", "xxx"))).Matches(Passes)
+		c.Expect(E(values, ContainsExactlyBy(sameLength), Values("xxx", "xxx"))).Matches(Fails)
+		c.Expect(E(values, ContainsExactlyBy(sameLength), Values("xxx", "xxx", "xxxxx", "x"))).Matches(Fails)
+		c.Expect(E(values, ContainsExactlyBy(sameLength), Values("xxx", "xx", "xxxx"))).Matches(FailsWithMessage(
+			"contains exactly “[xxx xx xxxx]”, but was missing “[xx xxxx]” and had extra “[two three]”",
+			"does NOT contain exactly “[xxx xx xxxx]”"))
+	})
+
+	c.Specify("Matcher: IsWeekday", func() {
+		friday := time.Date(2011, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+		c.Expect(E(friday, IsWeekday(time.Friday))).Matches(Passes)
+		c.Expect(E(friday, IsWeekday(time.Monday))).Matches(FailsWithMessage(
+			"is on a Monday, but was on a Friday",
+			"is NOT on a Monday"))
+
+		c.Specify("the weekday is in the time's location", func() {
+			tokyo := time.FixedZone("JST", 9*60*60)
+			lateFriday := time.Date(2011, time.July, 1, 20, 0, 0, 0, time.UTC)
+			c.Expect(E(lateFriday.In(tokyo), IsWeekday(time.Saturday))).Matches(Passes)
+		})
+		c.Specify("cannot check non-times", func() {
+			c.Expect(E(5, IsWeekday(time.Friday))).Matches(GivesError("type error: expected a time.Time, but was “5” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsInMonth", func() {
+		newYear := time.Date(2011, time.December, 31, 23, 0, 0, 0, time.UTC)
+
+		c.Expect(E(newYear, IsInMonth(time.December))).Matches(Passes)
+		c.Expect(E(newYear, IsInMonth(time.January))).Matches(FailsWithMessage(
+			"is in January, but was in December",
+			"is NOT in January"))
+		c.Expect(E(newYear.In(time.FixedZone("CET", 60*60)), IsInMonth(time.January))).Matches(Passes)
+		c.Expect(E("2011-12-31", IsInMonth(time.December))).Matches(GivesError("type error: expected a time.Time, but was “2011-12-31” of type “string”"))
+	})
+
+	c.Specify("Matcher: IsDateBetween", func() {
+		start := time.Date(2011, time.January, 1, 0, 0, 0, 0, time.UTC)
+		end := time.Date(2011, time.March, 31, 0, 0, 0, 0, time.UTC)
+		between := IsDateBetween(start, end)
+
+		c.Expect(E(time.Date(2011, time.February, 14, 0, 0, 0, 0, time.UTC), between)).Matches(Passes)
+		c.Expect(E(start, between)).Matches(Passes)
+		c.Expect(E(end, between)).Matches(Passes)
+		c.Expect(E(end.Add(time.Nanosecond), between)).Matches(FailsWithMessage(
+			"is between 2011-01-01 00:00:00 +0000 UTC and 2011-03-31 00:00:00 +0000 UTC",
+			"is NOT between 2011-01-01 00:00:00 +0000 UTC and 2011-03-31 00:00:00 +0000 UTC"))
+		c.Expect(E(nil, between)).Matches(GivesError("type error: expected a time.Time, but was “<nil>” of type “<nil>”"))
+	})
+
+	c.Specify("Matcher: HasExactlyKeys", func() {
+		config := map[string]int{"host": 1, "port": 2}
+		c.Expect(E(config, HasExactlyKeys("port", "host"))).Matches(Passes)
+		c.Expect(E(map[int]bool{}, HasExactlyKeys())).Matches(Passes)
+		c.Expect(E(config, HasExactlyKeys("host", "port", "timeout"))).Matches(FailsWithMessage(
+			"has exactly the keys “[host port timeout]”, but it was missing “[timeout]”",
+			"does NOT have exactly the keys “[host port timeout]”"))
+		c.Expect(E(config, HasExactlyKeys("host"))).Matches(FailsWithMessage(
+			"has exactly the keys “[host]”, but it had also “[port]”",
+			"does NOT have exactly the keys “[host]”"))
+		c.Expect(E(config, HasExactlyKeys("host", "timeout"))).Matches(FailsWithMessage(
+			"has exactly the keys “[host timeout]”, but it was missing “[timeout]” and had also “[port]”",
+			"does NOT have exactly the keys “[host timeout]”"))
+
+		c.Specify("cannot check other values", func() {
+			c.Expect(E([]string{"host"}, HasExactlyKeys("host"))).Matches(GivesError("type error: expected a map, but was “[host]” of type “[]string”"))
+		})
+	})
+
+	c.Specify("Matcher: HasEntry", func() {
+		config := map[string]interface{}{
+			"timeout": 30.0,
+			"server":  map[string]int{"port": 8080},
+		}
+
+		c.Expect(E(config, HasEntry("timeout", IsWithin(0.5)), 30.2)).Matches(Passes)
+		c.Expect(E(config, HasEntry("timeout", IsWithin(0.5)), 10.0)).Matches(FailsWithMessage(
+			"has an entry at “timeout” which is within 10 ± 0.5",
+			"has an entry at “timeout” which is NOT within 10 ± 0.5"))
+		c.Expect(E(config, HasEntry("retries", Equals), 3)).Matches(FailsWithMessage(
+			"has an entry at “retries”, but the key “retries” was missing",
+			"does NOT have an entry at “retries”"))
+
+		c.Specify("the value of the entry is reported", func() {
+			_, pos, _, _ := HasEntry("timeout", Equals)(config, 10.0)
+			c.Expect(pos.Actual()).Equals(30.0)
+		})
+		c.Specify("the matcher of the entry may give no messages", func() {
+			scores := map[string]int{"a": 1, "b": -1}
+			c.Expect(E(scores, HasEntry("a", isPositiveWithoutMessages))).Matches(Passes)
+			c.Expect(E(scores, HasEntry("b", isPositiveWithoutMessages))).Matches(FailsWithMessage(
+				"has an entry at “b” which matches",
+				"has an entry at “b” which does NOT match"))
+		})
+		c.Specify("nested entries are found with a path of keys", func() {
+			c.Expect(E(config, HasEntryAtPath([]interface{}{"server", "port"}, Equals), 8080)).Matches(Passes)
+			c.Expect(E(config, HasEntryAtPath([]interface{}{"server", "host"}, Equals), "localhost")).Matches(FailsWithMessage(
+				"has an entry at “server.host”, but the key “host” was missing",
+				"does NOT have an entry at “server.host”"))
+			c.Expect(E(config, HasEntryAtPath([]interface{}{"timeout", "seconds"}, Equals), 30)).Matches(GivesError(
+				"type error: expected a map, but was “30” of type “float64”"))
+		})
+		c.Specify("errors from the matcher are reported with the key", func() {
+			c.Expect(E(config, HasEntry("server", IsWithin(0.5)), 1.0)).Matches(GivesError(
+				"entry at “server”: type error: expected a float, but was “map[port:8080]” of type “map[string]int”"))
+		})
+		c.Specify("cannot check non-maps or keys of the wrong type", func() {
+			c.Expect(E(1, HasEntry("timeout", Equals), 1)).Matches(GivesError(
+				"type error: expected a map, but was “1” of type “int”"))
+			c.Expect(E(config, HasEntry(1, Equals), 1)).Matches(GivesError(
+				"type error: expected a key of type “string”, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsWithinMap", func() {
+		values := map[string]float64{"a": 1.0, "b": 2.0}
+
+		c.Expect(E(values, IsWithinMap(0.1), map[string]float64{"a": 1.05, "b": 1.95})).Matches(Passes)
+		c.Expect(E(values, IsWithinMap(0.1), map[string]float64{"a": 1.0})).Matches(Fails)
+		c.Expect(E(values, IsWithinMap(0.1), map[string]float64{"a": 1.0, "b": 2.0, "c": 3.0})).Matches(Fails)
+		c.Expect(E(values, IsWithinMap(0.1), map[string]float64{"a": 1.0, "b": 2.5})).Matches(FailsWithMessage(
+			"is within map[a:1 b:2.5] ± 0.1, but was missing keys “[]”, had extra keys “[]” and differing values at keys “[b]”",
+			"is NOT within map[a:1 b:2.5] ± 0.1"))
+		c.Expect(E(values, IsWithinMap(0.1), map[string]float64{"a": 1.0, "c": 3.0})).Matches(FailsWithMessage(
+			"is within map[a:1 c:3] ± 0.1, but was missing keys “[c]”, had extra keys “[b]” and differing values at keys “[]”",
+			"is NOT within map[a:1 c:3] ± 0.1"))
+
+		c.Specify("the values may be any floats", func() {
+			c.Expect(E(map[string]float32{"a": 1.0}, IsWithinMap(0.1), map[string]float64{"a": 1.0})).Matches(Passes)
+		})
+		c.Specify("NaN values are not within any delta", func() {
+			c.Expect(E(map[string]float64{"a": math.NaN()}, IsWithinMap(0.1), map[string]float64{"a": 1.0})).Matches(Fails)
+			c.Expect(E(map[string]float64{"a": 1.0}, IsWithinMap(0.1), map[string]float64{"a": math.NaN()})).Matches(Fails)
+			c.Expect(E(map[string]float64{"a": math.NaN()}, IsWithinMap(0.1), map[string]float64{"a": math.NaN()})).Matches(Fails)
+		})
+		c.Specify("cannot compare non-maps or non-float values", func() {
+			c.Expect(E(1.0, IsWithinMap(0.1), values)).Matches(GivesError("type error: expected a map with string keys, but was “1” of type “float64”"))
+			c.Expect(E(values, IsWithinMap(0.1), map[int]float64{1: 1.0})).Matches(GivesError("type error: expected a map with string keys, but was “map[1:1]” of type “map[int]float64”"))
+			c.Expect(E(map[string]int{"a": 1}, IsWithinMap(0.1), values)).Matches(GivesError("type error: expected a float, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: EqualsMapUnordered", func() {
+		headers := map[string][]string{"Accept": {"text/html", "application/json"}, "Host": {"example.com"}}
+
+		c.Expect(E(headers, EqualsMapUnordered, map[string][]string{"Host": {"example.com"}, "Accept": {"application/json", "text/html"}})).Matches(Passes)
+		c.Expect(E(headers, EqualsMapUnordered, map[string][]string{"Accept": {"text/html", "text/html"}, "Host": {"example.com"}})).Matches(FailsWithMessage(
+			"equals “map[Accept:[text/html text/html] Host:[example.com]]” ignoring the order of the values, "+
+				"but was missing keys “[]” and had extra keys “[]”, and at key “Accept” was missing “[text/html]” and had extra “[application/json]”",
+			"does NOT equal “map[Accept:[text/html text/html] Host:[example.com]]” ignoring the order of the values"))
+		c.Expect(E(headers, EqualsMapUnordered, map[string][]string{"Accept": {"text/html", "application/json"}, "Cookie": {}})).Matches(FailsWithMessage(
+			"equals “map[Accept:[text/html application/json] Cookie:[]]” ignoring the order of the values, "+
+				"but was missing keys “[Cookie]” and had extra keys “[Host]”",
+			"does NOT equal “map[Accept:[text/html application/json] Cookie:[]]” ignoring the order of the values"))
+
+		c.Specify("cannot compare non-maps or non-collection values", func() {
+			c.Expect(E(1, EqualsMapUnordered, headers)).Matches(GivesError("type error: expected a map with string keys, but was “1” of type “int”"))
+			c.Expect(E(map[string]int{"a": 1}, EqualsMapUnordered, headers)).Matches(GivesError("type error: expected a collection type, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsJsonSerializable", func() {
+		type Node struct {
+			Name string
+			Next *Node
+		}
+
+		c.Expect(E(Node{Name: "a"}, IsJsonSerializable)).Matches(Passes)
+		c.Expect(E(nil, IsJsonSerializable)).Matches(Passes)
+		c.Expect(E([]int{1, 2}, Not(IsJsonSerializable))).Matches(FailsWithMessage(
+			"is NOT serializable to JSON, but it was serialized as “[1,2]”",
+			"is serializable to JSON as “[1,2]”"))
+
+		c.Specify("the error of the marshaling is reported", func() {
+			c.Expect(E(map[string]interface{}{"f": func() {}}, IsJsonSerializable)).Matches(FailsWithMessage(
+				"is serializable to JSON, but: json: unsupported type: func()",
+				"is NOT serializable to JSON"))
+		})
+		c.Specify("cyclic values are not serializable", func() {
+			node := &Node{Name: "a"}
+			node.Next = node
+			match, pos, _, err := IsJsonSerializable(node, nil)
+			c.Expect(match).IsFalse()
+			c.Expect(err == nil).IsTrue()
+			c.Expect(pos.Expectation()).Satisfies(strings.HasPrefix(pos.Expectation(), "is serializable to JSON, but: json: unsupported value: encountered a cycle"))
+		})
+	})
+
+	c.Specify("Matcher: EqualsJson", func() {
+		type Point struct {
+			Y int
+			X int
+		}
+
+		c.Expect(E(Point{1, 2}, EqualsJson, map[string]int{"X": 2, "Y": 1})).Matches(Passes)
+		c.Expect(E(Point{1, 2}, EqualsJson, Point{1, 2})).Matches(Passes)
+		c.Expect(E(Point{1, 2}, EqualsJson, Point{1, 3})).Matches(FailsWithMessage(
+			"equals JSON “{\n  \"X\": 3,\n  \"Y\": 1\n}”, diff:\n  {\n-   \"X\": 3,\n+   \"X\": 2,\n    \"Y\": 1\n  }",
+			"does NOT equal JSON “{\n  \"X\": 3,\n  \"Y\": 1\n}”"))
+
+		c.Specify("large integers are compared exactly", func() {
+			c.Expect(E(map[string]int64{"id": 1<<53 + 1}, EqualsJson, map[string]int64{"id": 1 << 53})).Matches(Fails)
+			c.Expect(E(map[string]int64{"id": 1<<53 + 1}, EqualsJson, map[string]uint64{"id": 1<<53 + 1})).Matches(Passes)
+		})
+		c.Specify("the actual value is reported as JSON", func() {
+			_, pos, _, _ := EqualsJson(Point{1, 2}, Point{1, 3})
+			c.Expect(pos.Actual()).Equals("{\n  \"X\": 2,\n  \"Y\": 1\n}")
+		})
+		c.Specify("cannot compare values which can not be marshaled", func() {
+			ch := make(chan int)
+			c.Expect(E(ch, EqualsJson, 1)).Matches(GivesError(fmt.Sprintf(
+				"cannot marshal “%v” to JSON: json: unsupported type: chan int", ch)))
+			c.Expect(E(1, EqualsJson, ch)).Matches(GivesError(fmt.Sprintf(
+				"cannot marshal “%v” to JSON: json: unsupported type: chan int", ch)))
+		})
+	})
+
+	c.Specify("Matcher: MatchesSchema", func() {
+		schema := `{"type": "object", "required": ["X", "Y"], "properties": {"X": {"minimum": 0}}}`
+		type Point struct {
+			X int
+			Y int
+		}
+
+		c.Expect(E(`{"X": 1, "Y": 2}`, MatchesSchema(schema))).Matches(Passes)
+		c.Expect(E([]byte(`{"X": 1, "Y": 2}`), MatchesSchema(schema))).Matches(Passes)
+		c.Expect(E(Point{1, 2}, MatchesSchema(schema))).Matches(Passes)
+		c.Expect(E(`{"X": -1}`, MatchesSchema(schema))).Matches(FailsWithMessage(
+			"matches the JSON schema, but:\n  $: missing required property \"Y\"\n  $.X: expected at least 0, but was -1",
+			"does NOT match the JSON schema"))
+
+		c.Specify("cannot check invalid JSON", func() {
+			c.Expect(E(`{"X": `, MatchesSchema(schema))).Matches(GivesError(
+				"cannot unmarshal JSON “{\"X\": ”: unexpected end of JSON input"))
+		})
+		c.Specify("cannot check with an invalid schema", func() {
+			c.Expect(E(`{}`, MatchesSchema(`{"required": "X"}`))).Matches(GivesError(
+				"invalid JSON schema: \"required\" at # must be an array of strings, but was “X”"))
+		})
+		c.Specify("cannot check with unsupported keywords", func() {
+			c.Expect(E(`{}`, MatchesSchema(`{"oneOf": [true]}`))).Matches(GivesError(
+				"invalid JSON schema: unsupported keyword \"oneOf\" at #"))
+		})
+	})
+
+	c.Specify("Matcher: IsApproxDuration", func() {
+		value := 110 * time.Millisecond
+
+		c.Expect(E(value, IsApproxDuration(20*time.Millisecond), 100*time.Millisecond)).Matches(Passes)
+		c.Expect(E(value, IsApproxDuration(10*time.Millisecond), 100*time.Millisecond)).Matches(Passes)
+		c.Expect(E(value, IsApproxDuration(10*time.Millisecond), 120*time.Millisecond)).Matches(Passes)
+		c.Expect(E(value, IsApproxDuration(5*time.Millisecond), 100*time.Millisecond)).Matches(FailsWithMessage(
+			"is within 100ms ± 5ms (95ms to 105ms)",
+			"is NOT within 100ms ± 5ms (95ms to 105ms)"))
+
+		c.Specify("cannot compare non-durations", func() {
+			c.Expect(E(110, IsApproxDuration(time.Millisecond), value)).Matches(GivesError("type error: expected a time.Duration, but was “110” of type “int”"))
+			c.Expect(E(value, IsApproxDuration(time.Millisecond), 110)).Matches(GivesError("type error: expected a time.Duration, but was “110” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsRecent", func() {
+		clock := NewFakeClock(time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC))
+		now := clock.Now()
+
+		c.Expect(E(now, IsRecent(clock, time.Second))).Matches(Passes)
+		c.Expect(E(now.Add(-time.Second), IsRecent(clock, time.Second))).Matches(Passes)
+		c.Expect(E(now.Add(-2*time.Second), IsRecent(clock, time.Second))).Matches(FailsWithMessage(
+			"is at most 1s before “2000-01-01 12:00:00 +0000 UTC”",
+			"is NOT at most 1s before “2000-01-01 12:00:00 +0000 UTC”"))
+		c.Expect(E(now.Add(time.Nanosecond), IsRecent(clock, time.Second))).Matches(FailsWithMessage(
+			"is at most 1s before “2000-01-01 12:00:00 +0000 UTC”",
+			"is NOT at most 1s before “2000-01-01 12:00:00 +0000 UTC”"))
+
+		c.Specify("uses the current time of the clock", func() {
+			clock.Advance(time.Minute)
+			c.Expect(E(now, IsRecent(clock, time.Second))).Matches(FailsWithMessage(
+				"is at most 1s before “2000-01-01 12:01:00 +0000 UTC”",
+				"is NOT at most 1s before “2000-01-01 12:01:00 +0000 UTC”"))
+		})
+		c.Specify("cannot compare non-times", func() {
+			c.Expect(E(42, IsRecent(clock, time.Second))).Matches(GivesError("type error: expected a time.Time, but was “42” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: MatchesGolden", func() {
+		dir, _ := ioutil.TempDir("", "gospec")
+		defer os.RemoveAll(dir)
+		golden := filepath.Join(dir, "testdata", "expected.golden")
+		ioutil.WriteFile(filepath.Join(dir, "existing.golden"), []byte("line 1\nline 2\n"), 0644)
+		existing := filepath.Join(dir, "existing.golden")
+
+		c.Expect(E("line 1\nline 2\n", MatchesGolden(existing))).Matches(Passes)
+		c.Expect(E([]byte("line 1\nline 2\n"), MatchesGolden(existing))).Matches(Passes)
+		c.Expect(E("line 1\nline X\n", MatchesGolden(existing))).Matches(FailsWithMessage(
+			"matches golden file “"+existing+"”, diff:\n  line 1\n- line 2\n+ line X\n  ",
+			"does NOT match golden file “"+existing+"”"))
+
+		c.Specify("a missing golden file is an error", func() {
+			_, _, _, err := MatchesGolden(golden)("foo", nil)
+			c.Expect(strings.HasPrefix(err.Error(), "cannot read golden file: ")).IsTrue()
+		})
+		c.Specify("cannot compare non-text values", func() {
+			c.Expect(E(1, MatchesGolden(existing))).Matches(GivesError("type error: expected a string or []byte, but was “1” of type “int”"))
+		})
+		c.Specify("when updating the golden files", func() {
+			spy := new(SpyErrorLogger)
+			m := newMatcherAdapter(nil, spy, ExpectFailed)
+			m.updateGolden = true
+
+			c.Specify("the golden file is created", func() {
+				m.Expect("new content", MatchesGolden(golden))
+				c.Expect(spy.LastError()).Equals("")
+				data, _ := ioutil.ReadFile(golden)
+				c.Expect(string(data)).Equals("new content")
+			})
+			c.Specify("the golden file is overwritten", func() {
+				m.Expect("new content", MatchesGolden(existing))
+				c.Expect(spy.LastError()).Equals("")
+				data, _ := ioutil.ReadFile(existing)
+				c.Expect(string(data)).Equals("new content")
+			})
+			c.Specify("the other expectations are not affected", func() {
+				m.Expect(1, Equals, 2)
+				c.Expect(spy.LastError()).Equals("1 equals “2”")
+			})
+		})
+		c.Specify("the runner decides whether the golden files are updated", func() {
+			r := NewRunner()
+			r.SetUpdateGolden(true)
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.Expect("new content", MatchesGolden(golden))
+			})
+			r.Run()
+			c.Expect(r.Results().FailCount()).Equals(0)
+			data, _ := ioutil.ReadFile(golden)
+			c.Expect(string(data)).Equals("new content")
+
+			c.Specify("and the golden files are not updated by the other runners", func() {
+				other := NewRunner()
+				other.AddNamedSpec("RootSpec", func(c Context) {
+					c.Expect("other content", MatchesGolden(golden))
+				})
+				other.Run()
+				c.Expect(other.Results().FailCount()).Equals(1)
+				data, _ := ioutil.ReadFile(golden)
+				c.Expect(string(data)).Equals("new content")
+			})
+		})
+	})
+
+	c.Specify("Matcher: ContainsMatching", func() {
+		values := []float64{1.0, 2.0, 3.0}
+
+		c.Expect(E(values, ContainsMatching(IsWithin(0.1)), 2.05)).Matches(Passes)
+		c.Expect(E(values, ContainsMatching(IsWithin(0.1)), 5.0)).Matches(FailsWithMessage(
+			"contains an element which is within 5 ± 0.1, but none of the 3 elements matched",
+			"does NOT contain an element which is within 5 ± 0.1, but the elements at indices [] matched"))
+		c.Expect(E(values, Not(ContainsMatching(IsWithin(1.5))), 1.0)).Matches(FailsWithMessage(
+			"does NOT contain an element which is within 1 ± 1.5, but the elements at indices [0 1] matched",
+			"contains an element which is within 1 ± 1.5, but none of the 3 elements matched"))
+
+		c.Specify("an empty collection has no matching elements", func() {
+			c.Expect(E([]float64{}, ContainsMatching(IsWithin(0.1)), 1.0)).Matches(FailsWithMessage(
+				"contains an element which matches, but none of the 0 elements matched",
+				"does NOT contain an element which matches, but the elements at indices [] matched"))
+		})
+		c.Specify("errors from the matcher are reported with the index of the element", func() {
+			c.Expect(E([]interface{}{1.0, 2}, ContainsMatching(IsWithin(0.1)), 3.0)).Matches(GivesError(
+				"element at index 1: type error: expected a float, but was “2” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: ContainsExactlyOneMatching", func() {
+		values := []float64{1.0, 2.0, 3.0}
+
+		c.Expect(E(values, ContainsExactlyOneMatching(IsWithin(0.1)), 2.05)).Matches(Passes)
+		c.Expect(E(values, ContainsExactlyOneMatching(IsWithin(0.1)), 5.0)).Matches(FailsWithMessage(
+			"contains exactly one element which is within 5 ± 0.1, but 0 elements matched, at indices []",
+			"does NOT contain exactly one element which is within 5 ± 0.1, but the elements at indices [] matched"))
+		c.Expect(E(values, ContainsExactlyOneMatching(IsWithin(1.5)), 1.0)).Matches(FailsWithMessage(
+			"contains exactly one element which is within 1 ± 1.5, but 2 elements matched, at indices [0 1]",
+			"does NOT contain exactly one element which is within 1 ± 1.5, but the elements at indices [0 1] matched"))
+		c.Expect(E(values, Not(ContainsExactlyOneMatching(IsWithin(0.1))), 3.0)).Matches(FailsWithMessage(
+			"does NOT contain exactly one element which is within 3 ± 0.1, but the elements at indices [2] matched",
+			"contains exactly one element which is within 3 ± 0.1, but 1 elements matched, at indices [2]"))
+
+		c.Specify("errors from the matcher are reported with the index of the element", func() {
+			c.Expect(E([]interface{}{1.0, 2}, ContainsExactlyOneMatching(IsWithin(0.1)), 3.0)).Matches(GivesError(
+				"element at index 1: type error: expected a float, but was “2” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: AllMatching", func() {
+		values := []float64{1.0, 2.0, 3.0}
+
+		c.Expect(E(values, AllMatching(IsWithin(1.5)), 2.0)).Matches(Passes)
+		c.Expect(E([]float64{}, AllMatching(IsWithin(1.5)), 2.0)).Matches(Passes)
+		c.Expect(E(values, AllMatching(IsWithin(1.5)), 1.0)).Matches(FailsWithMessage(
+			"contains only elements which is within 1 ± 1.5, but the elements at indices [2] did not match",
+			"does NOT contain only elements which is within 1 ± 1.5"))
+	})
+
+	c.Specify("Matcher: Each", func() {
+		values := []float64{1.0, 2.0, 3.0, 4.0}
+
+		c.Expect(E(values, Each(IsWithin(2.0)), 2.5)).Matches(Passes)
+		c.Expect(E([]float64{}, Each(IsWithin(1.5)), 2.0)).Matches(Passes)
+		c.Expect(E(values, Each(IsWithin(1.5)), 1.0)).Matches(FailsWithMessage(
+			"each element is within 1 ± 1.5, but 2 of the 4 elements did not match:\n"+
+				"  at index 2 “3”: is within 1 ± 1.5\n"+
+				"  at index 3 “4”: is within 1 ± 1.5",
+			"NOT each element is within 1 ± 1.5"))
+
+		c.Specify("the values of maps are matched in the order of their keys", func() {
+			scores := map[string]float64{"b": 5.0, "a": 1.0, "c": 7.0}
+			c.Expect(E(scores, Each(IsWithin(1.0)), 1.0)).Matches(FailsWithMessage(
+				"each element is within 1 ± 1, but 2 of the 3 elements did not match:\n"+
+					"  at key “b” “5”: is within 1 ± 1\n"+
+					"  at key “c” “7”: is within 1 ± 1",
+				"NOT each element is within 1 ± 1"))
+		})
+		c.Specify("matchers without messages are described as matching", func() {
+			isPositive := func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+				return actual.(int) > 0, nil, nil, nil
+			}
+			c.Expect(E([]int{1, -2}, Each(isPositive))).Matches(FailsWithMessage(
+				"each element matches, but 1 of the 2 elements did not match:\n"+
+					"  at index 1 “-2”: matches",
+				"NOT each element matches"))
+		})
+		c.Specify("errors in the matcher tell which element caused them", func() {
+			c.Expect(E([]interface{}{1.0, "x"}, Each(IsWithin(1.0)), 1.0)).Matches(GivesError(
+				"element at index 1: type error: expected a float, but was “x” of type “string”"))
+			c.Expect(E(1, Each(IsTrue))).Matches(GivesError("type error: expected a collection type, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsMonotonicallyIncreasing", func() {
+		c.Expect(E([]int{1, 2, 2, 5}, IsMonotonicallyIncreasing)).Matches(Passes)
+		c.Expect(E([]float64{0.5, 1.5}, IsMonotonicallyIncreasing)).Matches(Passes)
+		c.Expect(E([]int{}, IsMonotonicallyIncreasing)).Matches(Passes)
+		c.Expect(E([]int{1, 2, 5, 4, 3}, IsMonotonicallyIncreasing)).Matches(FailsWithMessage(
+			"is monotonically increasing, but at index 2 “5” is followed by “4”",
+			"is NOT monotonically increasing"))
+
+		c.Specify("cannot check non-numbers", func() {
+			c.Expect(E([]string{"a"}, IsMonotonicallyIncreasing)).Matches(GivesError("type error: expected a number, but was “a” of type “string”"))
+			c.Expect(E(1, IsMonotonicallyIncreasing)).Matches(GivesError("type error: expected a collection type, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsStrictlyIncreasing", func() {
+		c.Expect(E([]int{1, 2, 5}, IsStrictlyIncreasing)).Matches(Passes)
+		c.Expect(E([]uint8{1, 2, 2}, IsStrictlyIncreasing)).Matches(FailsWithMessage(
+			"is strictly increasing, but at index 1 “2” is followed by “2”",
+			"is NOT strictly increasing"))
+
+		c.Specify("compares the integers exactly, also when they are too large for a float64", func() {
+			c.Expect(E([]int64{1 << 53, 1<<53 + 1}, IsStrictlyIncreasing)).Matches(Passes)
+			c.Expect(E([]uint64{math.MaxUint64 - 1, math.MaxUint64}, IsStrictlyIncreasing)).Matches(Passes)
+			c.Expect(E([]interface{}{int64(-1), uint64(0), int8(1), uint(math.MaxUint64)}, IsStrictlyIncreasing)).Matches(Passes)
+			c.Expect(E([]interface{}{uint64(1), int64(-1)}, IsStrictlyIncreasing)).Matches(Fails)
+			c.Expect(E([]interface{}{uint64(1), int64(1)}, IsStrictlyIncreasing)).Matches(Fails)
+			c.Expect(E([]interface{}{1, 1.5, uint(2)}, IsStrictlyIncreasing)).Matches(Passes)
+		})
+	})
+
+	c.Specify("Matcher: EachPairSatisfies", func() {
+		isBefore := EachPairSatisfies(func(prev, next interface{}) bool {
+			return prev.(time.Time).Before(next.(time.Time))
+		})
+		base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		c.Expect(E([]time.Time{base, base.Add(time.Second), base.Add(time.Hour)}, isBefore)).Matches(Passes)
+		c.Expect(E([]time.Time{}, isBefore)).Matches(Passes)
+		c.Expect(E([]time.Time{base}, isBefore)).Matches(Passes)
+
+		isSmaller := EachPairSatisfies(func(prev, next interface{}) bool { return prev.(int) < next.(int) })
+		c.Expect(E([3]int{1, 3, 2}, isSmaller)).Matches(FailsWithMessage(
+			"satisfies the relation for each pair of consecutive elements, but the elements at indices 1 and 2 did not: “3” and “2”",
+			"does NOT satisfy the relation for each pair of consecutive elements"))
+
+		c.Specify("cannot check other values", func() {
+			c.Expect(E(42, isSmaller)).Matches(GivesError("type error: expected a collection type, but was “42” of type “int”"))
+			c.Expect(E([]int{1}, EachPairSatisfies(nil))).Matches(GivesError("the relation must not be nil"))
+		})
+	})
+
+	c.Specify("Matcher: IsContiguousRange", func() {
+		c.Expect(E([]int{5, 6, 7, 8}, IsContiguousRange)).Matches(Passes)
+		c.Expect(E([]uint16{}, IsContiguousRange)).Matches(Passes)
+		c.Expect(E([]int{5, 6, 8, 9}, IsContiguousRange)).Matches(FailsWithMessage(
+			"is a contiguous range, but at index 1 “6” is followed by “8”",
+			"is NOT a contiguous range"))
+		c.Expect(E([]int64{3, 2}, IsContiguousRange)).Matches(FailsWithMessage(
+			"is a contiguous range, but at index 0 “3” is followed by “2”",
+			"is NOT a contiguous range"))
+		c.Expect(E([]int64{math.MaxInt64, math.MinInt64}, IsContiguousRange)).Matches(FailsWithMessage(
+			"is a contiguous range, but at index 0 “9223372036854775807” is followed by “-9223372036854775808”",
+			"is NOT a contiguous range"))
+
+		c.Specify("the start of the range may be given", func() {
+			c.Expect(E([]int{5, 6, 7}, IsContiguousRange, 5)).Matches(Passes)
+			c.Expect(E([]int{4, 5, 6}, IsContiguousRange, 5)).Matches(FailsWithMessage(
+				"is a contiguous range starting from 5, but it started from 4",
+				"is NOT a contiguous range starting from 5"))
+		})
+		c.Specify("cannot check other values", func() {
+			c.Expect(E([]float64{1, 2}, IsContiguousRange)).Matches(GivesError("type error: expected an integer, but was “1” of type “float64”"))
+			c.Expect(E([]int{1, 2}, IsContiguousRange, "1")).Matches(GivesError("type error: expected an integer, but was “1” of type “string”"))
+			c.Expect(E([]uint64{math.MaxUint64}, IsContiguousRange)).Matches(GivesError("expected an integer which fits into an int64, but was “18446744073709551615” of type “uint64”"))
+		})
+	})
+
+	c.Specify("Matcher: IsSubsetOf", func() {
+		values := []string{"one", "two", "two"}
+
+		c.Expect(E(values, IsSubsetOf, Values("one", "two", "three"))).Matches(Passes)
+		c.Expect(E(values, IsSubsetOf, Values("two", "one"))).Matches(Passes)
+		c.Expect(E([]string{}, IsSubsetOf, Values())).Matches(Passes)
+		c.Expect(E(values, IsSubsetOf, Values("three"))).Matches(FailsWithMessage(
+			"is a subset of “[three]”, but it also has “[one two]”",
+			"is NOT a subset of “[three]”"))
+	})
+
+	c.Specify("Matcher: IsSupersetOf", func() {
+		values := []string{"one", "two", "two"}
+
+		c.Expect(E(values, IsSupersetOf, Values("two", "one"))).Matches(Passes)
+		c.Expect(E(values, IsSupersetOf, Values("two", "two", "two"))).Matches(Passes)
+		c.Expect(E(values, IsSupersetOf, Values())).Matches(Passes)
+		c.Expect(E(values, IsSupersetOf, Values("one", "three", "four", "three"))).Matches(FailsWithMessage(
+			"is a superset of “[one three four three]”, but it is missing “[three four]”",
+			"is NOT a superset of “[one three four three]”"))
+	})
+
+	c.Specify("Matcher: Succeeds", func() {
+		boom := errors.New("boom")
+		succeeding := func() error { return nil }
+		failing := func() error { return boom }
+
+		c.Expect(E(succeeding, Succeeds)).Matches(Passes)
+		c.Expect(E(failing, Succeeds)).Matches(FailsWithMessage(
+			"succeeds",
+			"does NOT succeed"))
+
+		c.Specify("the error is shown as the actual value", func() {
+			_, pos, _, _ := Succeeds(failing, nil)
+			c.Expect(pos.Actual()).Equals(boom)
+		})
+		c.Specify("functions may return values before the error", func() {
+			c.Expect(E(func() (int, error) { return 1, nil }, Succeeds)).Matches(Passes)
+		})
+		c.Specify("cannot check functions which do not return an error", func() {
+			noError := func() int { return 1 }
+			c.Expect(E(noError, Succeeds)).Matches(GivesError(fmt.Sprintf(
+				"type error: expected a function returning an error, but was “%p” of type “func() int”", noError)))
+		})
+		c.Specify("cannot check non-functions", func() {
+			c.Expect(E(1, Succeeds)).Matches(GivesError(
+				"type error: expected a function returning an error, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: EventuallySucceeds", func() {
+		failingTimes := func(n int) func() error {
+			calls := 0
+			return func() error {
+				calls++
+				if calls <= n {
+					return fmt.Errorf("failure %v", calls)
+				}
+				return nil
+			}
+		}
+		c.Expect(E(failingTimes(0), EventuallySucceeds(time.Second, time.Millisecond))).Matches(Passes)
+		c.Expect(E(failingTimes(3), EventuallySucceeds(time.Second, time.Millisecond))).Matches(Passes)
+		c.Expect(E(failingTimes(3), Not(EventuallySucceeds(time.Second, time.Millisecond)))).Matches(FailsWithMessage(
+			"does NOT eventually succeed within 1s, but attempt 4 succeeded",
+			"eventually succeeds within 1s"))
+
+		c.Specify("the last error is reported after the timeout", func() {
+			start := time.Now()
+			_, pos, _, _ := EventuallySucceeds(30*time.Millisecond, 10*time.Millisecond).Match(failingTimes(1000))
+			elapsed := time.Since(start)
+			c.Expect(pos.Expectation()).Satisfies(strings.HasPrefix(pos.Expectation(), "eventually succeeds within 30ms, but all "))
+			c.Expect(pos.Expectation()).Satisfies(strings.HasSuffix(pos.Expectation(), " attempts failed, the last one with this error"))
+			c.Expect(fmt.Sprint(pos.Actual())).Satisfies(strings.HasPrefix(fmt.Sprint(pos.Actual()), "failure "))
+			c.Expect(elapsed).Satisfies(elapsed >= 20*time.Millisecond && elapsed < time.Second)
+		})
+		c.Specify("the function is called once every interval", func() {
+			calls := 0
+			EventuallySucceeds(50*time.Millisecond, 20*time.Millisecond).Match(func() error {
+				calls++
+				return errors.New("not ready")
+			})
+			c.Expect(calls).Satisfies(calls >= 2 && calls <= 3)
+		})
+		c.Specify("the interval must be positive", func() {
+			c.Expect(E(failingTimes(0), EventuallySucceeds(time.Second, 0))).Matches(GivesError("the interval must be positive, but was 0s"))
+		})
+		c.Specify("cannot call other values", func() {
+			c.Expect(E(42, EventuallySucceeds(time.Second, time.Millisecond))).Matches(GivesError("type error: expected a function returning an error, but was “42” of type “int”"))
+		})
+	})
+	c.Specify("Matcher: SucceedsWith", func() {
+		succeeding := func() (int, error) { return 42, nil }
+		failing := func() (int, error) { return 0, errors.New("boom") }
+
+		c.Expect(E(succeeding, SucceedsWith, 42)).Matches(Passes)
+		c.Expect(E(succeeding, SucceedsWith, 1)).Matches(FailsWithMessage(
+			"succeeds with “1”",
+			"does NOT succeed with “1”"))
+		c.Expect(E(failing, SucceedsWith, 0)).Matches(FailsWithMessage(
+			"succeeds with “0”",
+			"does NOT succeed with “0”"))
+
+		c.Specify("cannot check functions which return only an error", func() {
+			onlyError := func() error { return nil }
+			c.Expect(E(onlyError, SucceedsWith, 1)).Matches(GivesError(fmt.Sprintf(
+				"type error: expected a function returning a value and an error, but was “%p” of type “func() error”", onlyError)))
+		})
+	})
+
+	c.Specify("Matcher: ReturnsValueMatching", func() {
+		succeeding := func() (float64, error) { return 3.05, nil }
+		failing := func() (float64, error) { return 0, errors.New("boom") }
+
+		c.Expect(E(succeeding, ReturnsValueMatching(IsWithin(0.1)), 3.0)).Matches(Passes)
+		c.Expect(E(succeeding, ReturnsValueMatching(IsWithin(0.01)), 3.0)).Matches(FailsWithMessage(
+			"returns a value which is within 3 ± 0.01",
+			"returns a value which is NOT within 3 ± 0.01"))
+		c.Expect(E(failing, ReturnsValueMatching(IsWithin(0.1)), 0.0)).Matches(FailsWithMessage(
+			"returns a value without an error, but it returned an error",
+			"does NOT return a value without an error"))
+
+		c.Specify("cannot check functions which return only an error", func() {
+			onlyError := func() error { return nil }
+			c.Expect(E(onlyError, ReturnsValueMatching(Equals), 1)).Matches(GivesError(fmt.Sprintf(
+				"type error: expected a function returning a value and an error, but was “%p” of type “func() error”", onlyError)))
+		})
+		c.Specify("the matcher of the value may give no messages", func() {
+			returns := func(n int) func() (int, error) { return func() (int, error) { return n, nil } }
+			c.Expect(E(returns(1), ReturnsValueMatching(isPositiveWithoutMessages))).Matches(Passes)
+			c.Expect(E(returns(-1), ReturnsValueMatching(isPositiveWithoutMessages))).Matches(FailsWithMessage(
+				"returns a value which matches",
+				"returns a value which does NOT match"))
+		})
+		c.Specify("the errors of the matcher are prefixed", func() {
+			returnsString := func() (string, error) { return "x", nil }
+			c.Expect(E(returnsString, ReturnsValueMatching(IsWithin(0.1)), 3.0)).Matches(GivesError(
+				"the returned value: type error: expected a float, but was “x” of type “string”"))
+		})
+	})
+
+	c.Specify("Matcher: IsMemoized", func() {
+		cached := make(map[int]int)
+		square := func(n int) int {
+			if result, found := cached[n]; found {
+				return result
+			}
+			time.Sleep(20 * time.Millisecond)
+			cached[n] = n * n
+			return cached[n]
+		}
+		calls := 0
+		counter := func() interface{} {
+			calls++
+			return calls
+		}
+
+		c.Expect(E(func() interface{} { return square(3) }, IsMemoized(10))).Matches(Passes)
+		c.Expect(E(func() interface{} { time.Sleep(5 * time.Millisecond); return 9 }, IsMemoized(10))).Matches(Fails)
+
+		c.Specify("the second call must return an equal value", func() {
+			match, pos, neg, err := IsMemoized(0.001)(counter, nil)
+			c.Expect(match).IsFalse()
+			c.Expect(err).Equals(nil)
+			c.Expect(strings.HasPrefix(pos.Expectation(), "is memoized, with the second call at least 0.001 times faster, but the first call returned “1” in ")).IsTrue()
+			c.Expect(strings.Contains(pos.Expectation(), ", and the second call returned “2” in ")).IsTrue()
+			c.Expect(strings.HasPrefix(neg.Expectation(), "is NOT memoized, with the second call at least 0.001 times faster, but the first call returned “1” in ")).IsTrue()
+		})
+		c.Specify("the passing message does not tell why it would have failed", func() {
+			match, pos, neg, _ := IsMemoized(10)(func() interface{} { return square(4) }, nil)
+			c.Expect(match).IsTrue()
+			c.Expect(pos.Expectation()).Equals("is memoized, with the second call at least 10 times faster")
+			c.Expect(strings.HasPrefix(neg.Expectation(), "is NOT memoized, with the second call at least 10 times faster, but the first call returned “16” in ")).IsTrue()
+		})
+		c.Specify("the speedup factor must be positive", func() {
+			c.Expect(E(counter, IsMemoized(0))).Matches(GivesError("the speedup factor must be positive, but was 0"))
+		})
+		c.Specify("cannot call other than functions returning a value", func() {
+			c.Expect(E(42, IsMemoized(10))).Matches(GivesError("type error: expected a function of type “func() interface{}”, but was “42” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsValid", func() {
+		c.Expect(E("123e4567-e89b-12d3-a456-426614174000", IsValid, "uuid")).Matches(Passes)
+		c.Expect(E("not-a-uuid", IsValid, "uuid")).Matches(FailsWithMessage(
+			"is a valid uuid",
+			"is NOT a valid uuid"))
+
+		c.Specify("formats can be registered", func() {
+			RegisterFormat("test-lowercase", func(s string) bool { return s == strings.ToLower(s) })
+			c.Expect(E("abc", IsValid, "test-lowercase")).Matches(Passes)
+			c.Expect(E("ABC", IsValid, "test-lowercase")).Matches(Fails)
+		})
+		c.Specify("cannot check unknown formats", func() {
+			c.Expect(E("abc", IsValid, "no-such-format")).Matches(GivesError(
+				"unknown format “no-such-format”, it can be added with RegisterFormat"))
+		})
+		c.Specify("cannot check non-strings", func() {
+			c.Expect(E(1, IsValid, "uuid")).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+			c.Expect(E("abc", IsValid, 1)).Matches(GivesError("type error: expected the name of a format, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: WasCalled", func() {
+		spy := new(Spy)
+		spy.Call("hello")
+		spy.Call("world")
+
+		c.Expect(E(spy, WasCalled, 2)).Matches(Passes)
+		c.Expect(E(spy, WasCalled, 1)).Matches(FailsWithMessage(
+			"was called 1 times",
+			"was NOT called 1 times"))
+
+		c.Specify("the calls are shown as the actual value", func() {
+			_, pos, _, _ := WasCalled(spy, 1)
+			c.Expect(fmt.Sprint(pos.Actual())).Equals(`Spy with 2 calls: ("hello"), ("world")`)
+		})
+		c.Specify("cannot check non-spies", func() {
+			c.Expect(E(1, WasCalled, 1)).Matches(GivesError("type error: expected a *Spy, but was “1” of type “int”"))
+			c.Expect(E(spy, WasCalled, "1")).Matches(GivesError("type error: expected the number of calls, but was “1” of type “string”"))
+		})
+	})
+
+	c.Specify("Matcher: RoundTrips", func() {
+		encodeInt := func(v interface{}) ([]byte, error) {
+			if v.(int) < 0 {
+				return nil, errors.New("negative")
+			}
+			return []byte(fmt.Sprint(v)), nil
+		}
+		decodeInt := func(b []byte) (interface{}, error) {
+			var v int
+			_, err := fmt.Sscan(string(b), &v)
+			return v, err
+		}
+		decodeTruncated := func(b []byte) (interface{}, error) {
+			return decodeInt(b[:1])
+		}
+		decodeFailing := func(b []byte) (interface{}, error) {
+			return nil, errors.New("corrupted")
+		}
+
+		c.Expect(E(42, RoundTrips(encodeInt, decodeInt))).Matches(Passes)
+		c.Expect(E(42, RoundTrips(encodeInt, decodeTruncated))).Matches(FailsWithMessage(
+			"round-trips, but it was decoded from \"42\" as “4”",
+			"does NOT round-trip"))
+
+		c.Specify("tells when encoding fails", func() {
+			c.Expect(E(-1, RoundTrips(encodeInt, decodeInt))).Matches(FailsWithMessage(
+				"round-trips, but encoding it failed: negative",
+				"does NOT round-trip"))
+		})
+		c.Specify("tells when decoding fails and shows the encoded bytes", func() {
+			c.Expect(E(42, RoundTrips(encodeInt, decodeFailing))).Matches(FailsWithMessage(
+				"round-trips, but decoding \"42\" failed: corrupted",
+				"does NOT round-trip"))
+		})
+	})
+
+	c.Specify("Matcher: IsIdempotent", func() {
+		abs := func(v interface{}) interface{} { return int(math.Abs(float64(v.(int)))) }
+		increment := func(v interface{}) interface{} { return v.(int) + 1 }
+		panicking := func(v interface{}) interface{} { panic("boom") }
+
+		c.Expect(E(-2, IsIdempotent(abs))).Matches(Passes)
+		c.Expect(E(1, IsIdempotent(increment))).Matches(FailsWithMessage(
+			"is idempotent, but applying the operation once gave “2” and twice gave “3”",
+			"is NOT idempotent, but applying the operation once and twice gave “2”"))
+
+		c.Specify("panics in the operation are errors", func() {
+			c.Expect(E(1, IsIdempotent(panicking))).Matches(GivesError(
+				"the operation panicked when applied to “1”: boom"))
+		})
+	})
+
+	c.Specify("Matcher: EquivalentTo", func() {
+		double := func(v interface{}) interface{} { return v.(int) * 2 }
+		shift := func(v interface{}) interface{} { return v.(int) << 1 }
+		square := func(v interface{}) interface{} { return v.(int) * v.(int) }
+		inputs := Values(0, 2, 3)
+
+		c.Expect(E(shift, EquivalentTo(double, inputs))).Matches(Passes)
+		c.Expect(E(square, EquivalentTo(double, inputs))).Matches(FailsWithMessage(
+			"is equivalent to the reference function, but with the input “3” the reference returned “6” and the function returned “9”",
+			"is NOT equivalent to the reference function with the inputs “[0 2 3]”"))
+
+		c.Specify("panics must happen with the same inputs", func() {
+			c.Expect(E(shift, EquivalentTo(double, Values(1, "x")))).Matches(Passes)
+			tolerant := func(v interface{}) interface{} {
+				if n, ok := v.(int); ok {
+					return n * 2
+				}
+				return 0
+			}
+			c.Expect(E(tolerant, EquivalentTo(double, Values("x")))).Matches(FailsWithMessage(
+				"is equivalent to the reference function, but with the input “x” the reference panicked and the function returned “0”",
+				"is NOT equivalent to the reference function with the inputs “[x]”"))
+			c.Expect(E(double, EquivalentTo(tolerant, Values("x")))).Matches(FailsWithMessage(
+				"is equivalent to the reference function, but with the input “x” the reference returned “0” and the function panicked",
+				"is NOT equivalent to the reference function with the inputs “[x]”"))
+		})
+		c.Specify("cannot call other types", func() {
+			c.Expect(E(1, EquivalentTo(double, inputs))).Matches(GivesError(
+				"type error: expected a function of type “func(interface{}) interface{}”, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsDeterministic", func() {
+		constant := func() interface{} { return "same" }
+		calls := 0
+		counter := func() interface{} {
+			calls++
+			if calls < 3 {
+				return 1
+			}
+			return calls
+		}
+
+		c.Expect(E(constant, IsDeterministic(5))).Matches(Passes)
+		c.Expect(E(counter, IsDeterministic(5))).Matches(FailsWithMessage(
+			"is deterministic, but call 3 of 5 returned “3”",
+			"is NOT deterministic"))
+
+		c.Specify("panics in the function are errors", func() {
+			panicCalls := 0
+			panicking := func() interface{} {
+				panicCalls++
+				if panicCalls == 2 {
+					panic("boom")
+				}
+				return 1
+			}
+			c.Expect(E(panicking, IsDeterministic(3))).Matches(GivesError("the function panicked on call 2: boom"))
+		})
+		c.Specify("cannot call other types", func() {
+			c.Expect(E(1, IsDeterministic(3))).Matches(GivesError(
+				"type error: expected a function of type “func() interface{}”, but was “1” of type “int”"))
+			c.Expect(E(constant, IsDeterministic(1))).Matches(GivesError(
+				"the function must be called at least 2 times, but the number of runs was 1"))
+		})
+	})
+
+	c.Specify("Matcher: IsUnchangedBy", func() {
+		type Config struct {
+			Name  string
+			Tags  []string
+			Limit *int
+		}
+		limit := 10
+		config := Config{"app", []string{"a", "b"}, &limit}
+
+		c.Expect(E(&config, IsUnchangedBy(func() { _ = config.Name + "x" }))).Matches(Passes)
+		c.Expect(E(&config, IsUnchangedBy(func() { config.Tags[1] = "c" }))).Matches(Fails)
+
+		c.Specify("the values before and after the operation are reported", func() {
+			tags := []string{"a", "b"}
+			c.Expect(E(&tags, IsUnchangedBy(func() { tags[1] = "c" }))).Matches(FailsWithMessage(
+				"is unchanged by the operation, but before the operation it was “[a b]”",
+				"is NOT unchanged by the operation"))
+		})
+		c.Specify("changes behind pointers are detected", func() {
+			c.Expect(E(&config, IsUnchangedBy(func() { *config.Limit = 20 }))).Matches(Fails)
+		})
+		c.Specify("changes to maps are detected", func() {
+			counts := map[string]int{"a": 1}
+			c.Expect(E(counts, IsUnchangedBy(func() { counts["b"] = 2 }))).Matches(Fails)
+			c.Expect(E(counts, IsUnchangedBy(func() {}))).Matches(Passes)
+		})
+		c.Specify("values which refer to themselves can be copied", func() {
+			type Node struct {
+				Value int
+				Next  *Node
+			}
+			node := &Node{Value: 1}
+			node.Next = node
+			c.Expect(E(node, IsUnchangedBy(func() {}))).Matches(Passes)
+			c.Expect(E(node, IsUnchangedBy(func() { node.Next.Value = 2 }))).Matches(Fails)
+		})
+		c.Specify("panics in the operation are errors", func() {
+			c.Expect(E(&config, IsUnchangedBy(func() { panic("boom") }))).Matches(GivesError("the operation panicked: boom"))
+		})
+	})
+
+	c.Specify("Matcher: StartsWithSequence", func() {
+		values := []string{"a", "b", "c"}
+		c.Expect(E(values, StartsWithSequence, []string{"a", "b"})).Matches(Passes)
+		c.Expect(E(values, StartsWithSequence, []string{})).Matches(Passes)
+		c.Expect(E(values, StartsWithSequence, []string{"a", "x"})).Matches(FailsWithMessage(
+			"starts with “[a x]”, but differs at index 1",
+			"does NOT start with “[a x]”"))
+
+		c.Specify("the sequence must not be longer than the collection", func() {
+			c.Expect(E(values, StartsWithSequence, []string{"a", "b", "c", "d"})).Matches(FailsWithMessage(
+				"starts with “[a b c d]”, but it is shorter than the sequence",
+				"does NOT start with “[a b c d]”"))
+		})
+		c.Specify("works with byte slices and arrays", func() {
+			c.Expect(E([]byte{0xCA, 0xFE, 0x01}, StartsWithSequence, []byte{0xCA, 0xFE})).Matches(Passes)
+			c.Expect(E([3]int{1, 2, 3}, StartsWithSequence, []int{1})).Matches(Passes)
+		})
+		c.Specify("cannot compare non-collections", func() {
+			c.Expect(E(1, StartsWithSequence, values)).Matches(GivesError("type error: expected a collection type, but was “1” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: EndsWithSequence", func() {
+		values := []string{"a", "b", "c"}
+		c.Expect(E(values, EndsWithSequence, []string{"b", "c"})).Matches(Passes)
+		c.Expect(E(values, EndsWithSequence, []string{})).Matches(Passes)
+		c.Expect(E(values, EndsWithSequence, []string{"x", "c"})).Matches(FailsWithMessage(
+			"ends with “[x c]”, but differs at index 1",
+			"does NOT end with “[x c]”"))
+
+		c.Specify("the sequence must not be longer than the collection", func() {
+			c.Expect(E(values, EndsWithSequence, []string{"0", "a", "b", "c"})).Matches(FailsWithMessage(
+				"ends with “[0 a b c]”, but it is shorter than the sequence",
+				"does NOT end with “[0 a b c]”"))
+		})
+		c.Specify("works with byte slices", func() {
+			c.Expect(E([]byte{0x01, 0x0D, 0x0A}, EndsWithSequence, []byte{0x0D, 0x0A})).Matches(Passes)
+		})
+	})
+
+	c.Specify("Matcher: IteratesInOrder", func() {
+		tree := sortedKeys{"a", "b", "c"}
+		c.Expect(E(tree, IteratesInOrder("a", "b", "c"))).Matches(Passes)
+		c.Expect(E(sortedKeys{}, IteratesInOrder())).Matches(Passes)
+		c.Expect(E(tree, IteratesInOrder("a", "c", "b"))).Matches(FailsWithMessage(
+			"iterates in the order “[a c b]”, but the key at index 1 was “b” instead of “c”",
+			"does NOT iterate in the order “[a c b]”"))
+		c.Expect(E(tree, IteratesInOrder("a", "b", "c", "d"))).Matches(FailsWithMessage(
+			"iterates in the order “[a b c d]”, but it ended after 3 keys",
+			"does NOT iterate in the order “[a b c d]”"))
+		c.Expect(E(tree, IteratesInOrder("a", "b"))).Matches(FailsWithMessage(
+			"iterates in the order “[a b]”, but it yielded an extra key “c”",
+			"does NOT iterate in the order “[a b]”"))
+
+		c.Specify("the keys may be given as a collection", func() {
+			c.Expect(E([]int{1, 2, 3}, IteratesInOrder(1, 2, 3))).Matches(Passes)
+			c.Expect(E([]int{1, 3, 2}, IteratesInOrder(1, 2, 3))).Matches(Fails)
+		})
+		c.Specify("cannot check other values", func() {
+			c.Expect(E(42, IteratesInOrder(42))).Matches(GivesError("type error: expected a KeyIterator or a collection, but was “42” of type “int”"))
+		})
+	})
+
+	c.Specify("Matcher: IsSimilarTo", func() {
+		c.Expect(E("kitten", IsSimilarTo(3), "sitting")).Matches(Passes)
+		c.Expect(E("kitten", IsSimilarTo(0), "kitten")).Matches(Passes)
+		c.Expect(E("kitten", IsSimilarTo(2), "sitting")).Matches(FailsWithMessage(
+			"is within edit distance 2 of “sitting”, but the distance was 3, diff:\n- sitting\n+ kitten",
+			"is NOT within edit distance 2 of “sitting”, but the distance was 3"))
+
+		c.Specify("the distance is counted in characters", func() {
+			c.Expect(editDistance("smörgåsbord", "smorgasbord")).Equals(2)
+			c.Expect(editDistance("", "abc")).Equals(3)
+			c.Expect(editDistance("abc", "")).Equals(3)
+		})
+		c.Specify("cannot compare non-strings", func() {
+			c.Expect(E(1, IsSimilarTo(1), "a")).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+			c.Expect(E("a", IsSimilarTo(1), 1)).Matches(GivesError("type error: expected a string, but was “1” of type “int”"))
+		})
+	})
 }
 
 // Used by the Equals matcher's tests
@@ -420,3 +1911,16 @@ func (this *ExpectationHolder) ToError() error {
 		"Mather failed its expectations\n\tmatch: %v\n\tpos: %v\n\tneg: %v\n\terr: %v",
 		this.match, this.pos, this.neg, this.err))
 }
+
+type sortedKeys []string
+
+func (keys sortedKeys) Iterate(yield func(key interface{})) {
+	for _, key := range keys {
+		yield(key)
+	}
+}
+
+// Matchers need to give the messages only when they are needed.
+func isPositiveWithoutMessages(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+	return actual.(int) > 0, nil, nil, nil
+}
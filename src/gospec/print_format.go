@@ -15,6 +15,14 @@ type PrintFormat interface {
 	PrintSummary(passCount int, failCount int)
 }
 
+// PrintFormats may implement also this interface, to tell in the summary
+// about the errors which concern the whole run instead of any single spec,
+// so that the summary does not look like the run passed. When there were
+// such errors, this is called instead of PrintSummary.
+type RunErrorSummaryFormat interface {
+	PrintSummaryWithRunErrors(passCount int, failCount int, runErrorCount int)
+}
+
 // PrintFormat for production use.
 func DefaultPrintFormat(out io.Writer) PrintFormat {
 	return &defaultPrintFormat{out}
@@ -77,6 +85,11 @@ func (this *defaultPrintFormat) PrintSummary(passCount int, failCount int) {
 	fmt.Fprintf(this.out, "\n%v specs, %v failures\n", totalCount, failCount)
 }
 
+func (this *defaultPrintFormat) PrintSummaryWithRunErrors(passCount int, failCount int, runErrorCount int) {
+	totalCount := passCount + failCount
+	fmt.Fprintf(this.out, "\n%v specs, %v failures, %v run errors\n", totalCount, failCount, runErrorCount)
+}
+
 // PrintFormat for use in only tests. Does not print line numbers, colors or
 // other fancy stuff. Makes comparing as a string easier.
 func SimplePrintFormat(out io.Writer) PrintFormat {
@@ -110,6 +123,11 @@ func (this *simplePrintFormat) PrintSummary(passCount int, failCount int) {
 	fmt.Fprintf(this.out, "\n%v specs, %v failures\n", totalCount, failCount)
 }
 
+func (this *simplePrintFormat) PrintSummaryWithRunErrors(passCount int, failCount int, runErrorCount int) {
+	totalCount := passCount + failCount
+	fmt.Fprintf(this.out, "\n%v specs, %v failures, %v run errors\n", totalCount, failCount, runErrorCount)
+}
+
 func indent(level int) string {
 	s := ""
 	for i := 0; i < level; i++ {
@@ -4,7 +4,10 @@
 
 package gospec
 
-import ()
+import (
+	"fmt"
+	"strings"
+)
 
 type printMode int
 
@@ -15,18 +18,59 @@ const (
 
 // Printer formats the spec results into a human-readable format.
 type Printer struct {
-	format      PrintFormat
-	show        printMode
-	showSummary bool
-	notPrinted  []string
+	format           PrintFormat
+	show             printMode
+	showSummary      bool
+	showAllocations  bool
+	showDurations    bool
+	showLocations    bool
+	showExpectations bool
+	collapseChains   bool
+	notPrinted       []string
+	maxIndentation   int
+
+	// When collapsing chains, the names of the collapsed parents of the
+	// next spec, and for every nesting level how many of its parents
+	// have been collapsed.
+	collapsedNames string
+	collapsedDepth []int
+
+	// The last spec which was collapsed into its only child. It is printed
+	// after all, if the child is not the next spec to be visited, because
+	// the visitor does not visit the child, for example when it is
+	// quarantined.
+	collapsing *collapsingSpec
+
+	// When grouping identical failures, the printing is postponed until
+	// all specs have been visited and it is known which errors are shared.
+	groupFailures bool
+	postponed     []func()
+	failureGroups []*failureGroup
+	names         []string
+
+	// The number of the errors of the whole run, for the summary.
+	runErrorCount int
+}
+
+type collapsingSpec struct {
+	nestingLevel   int
+	spec           *SpecDetails
+	collapsedNames string
+}
+
+// The specs which failed with the same error.
+type failureGroup struct {
+	error *Error
+	names []string
 }
 
 func NewPrinter(format PrintFormat) *Printer {
 	return &Printer{
-		format:      format,
-		show:        ALL,
-		showSummary: true,
-		notPrinted:  []string{},
+		format:         format,
+		show:           ALL,
+		showSummary:    true,
+		notPrinted:     []string{},
+		maxIndentation: -1,
 	}
 }
 
@@ -46,27 +90,272 @@ func (this *Printer) ShowSummary() {
 	this.showSummary = true
 }
 
+// Shows after the names of the leaf specs how many heap allocations were
+// made during their execution, when they have been measured.
+// See Runner.SetMeasureAllocations.
+func (this *Printer) ShowAllocations() {
+	this.showAllocations = true
+}
+
+// Shows after the names of the leaf specs how long they took to execute,
+// and after the names of the other specs the total of their leaf specs,
+// so that the expensive branches of the tree can be seen at a glance.
+func (this *Printer) ShowDurations() {
+	this.showDurations = true
+}
+
+// Shows after the names of the specs where they were declared, so that
+// the report can be used as a clickable index of the specs.
+func (this *Printer) ShowDeclarationLocations() {
+	this.showLocations = true
+}
+
+// Lists under every printed spec the expectations which it evaluated,
+// marked with ✓ when they passed and ✗ when they failed, when they have
+// been recorded. Together with ShowAll the report shows everything which
+// the specs check. See Runner.RecordExpectations.
+func (this *Printer) ShowExpectations() {
+	this.showExpectations = true
+}
+
+// Prints a chain of specs, where each spec has only one child,
+// on one line as "Parent > Child > Grandchild" to reduce the nesting
+// of the report. Failing specs are not collapsed into their child,
+// so that their errors are printed after their own name.
+func (this *Printer) CollapseSingleChildChains() {
+	this.collapseChains = true
+}
+
+// Prints only once the errors which are identical in many specs, together
+// with the names of those specs, after all the other specs. The specs are
+// still marked as failing at their place in the report. Errors are identical
+// when their messages, actual values and stack traces are the same.
+func (this *Printer) GroupIdenticalFailures() {
+	this.groupFailures = true
+}
+
+// Indents the specs which are nested deeper than 'level' only as much as
+// the specs at 'level', to keep the lines of deeply nested specs readable.
+// Their true nesting level is shown after their name.
+func (this *Printer) LimitIndentation(level int) {
+	this.maxIndentation = level
+}
+
+func (this *Printer) VisitSpecDetails(nestingLevel int, spec *SpecDetails) {
+	name := spec.Name
+	if this.collapseChains {
+		this.printUncollapsed(nestingLevel)
+		var collapsed bool
+		nestingLevel, name, collapsed = this.collapse(nestingLevel, spec, true)
+		if collapsed {
+			return
+		}
+	}
+	this.printDetails(nestingLevel, name, spec)
+}
+
+func (this *Printer) printDetails(nestingLevel int, name string, spec *SpecDetails) {
+	if spec.SkipReason != "" {
+		name += fmt.Sprintf(" [SKIPPED: %v]", spec.SkipReason)
+	}
+	if this.showLocations && spec.Location != nil {
+		name += fmt.Sprintf(" (%v:%v)", spec.Location.File(), spec.Location.Line())
+	}
+	if this.showDurations {
+		if spec.ChildCount == 0 {
+			name += fmt.Sprintf(" (%v)", spec.TotalDuration)
+		} else {
+			name += fmt.Sprintf(" (%v total)", spec.TotalDuration)
+		}
+	}
+	if this.showAllocations && spec.Allocations != nil {
+		name += fmt.Sprintf(" (%v allocs, %v bytes)", spec.Allocations.Count, spec.Allocations.Bytes)
+	}
+	if this.showExpectations {
+		this.visitSpec(nestingLevel, name, spec.Errors, spec.Expectations)
+	} else {
+		this.VisitSpec(nestingLevel, name, spec.Errors)
+	}
+}
+
+func (this *Printer) collapse(nestingLevel int, spec *SpecDetails, canCollapse bool) (printedLevel int, name string, collapsed bool) {
+	if nestingLevel+1 >= len(this.collapsedDepth) {
+		resizeIntArray(&this.collapsedDepth, nestingLevel+2)
+	}
+	depth := this.collapsedDepth[nestingLevel]
+	if nestingLevel == 0 {
+		depth = 0
+	}
+	name = this.collapsedNames + spec.Name
+	if canCollapse && spec.ChildCount == 1 && len(spec.Errors) == 0 && !(this.showExpectations && len(spec.Expectations) > 0) {
+		// The only child should be the next spec to be visited.
+		this.collapsing = &collapsingSpec{nestingLevel, spec, this.collapsedNames}
+		this.collapsedNames = name + " > "
+		this.collapsedDepth[nestingLevel+1] = depth + 1
+		return nestingLevel, name, true
+	}
+	this.collapsedNames = ""
+	this.collapsedDepth[nestingLevel+1] = depth
+	return nestingLevel - depth, name, false
+}
+
+// Prints the last collapsed spec without collapsing it, unless its only
+// child is visited next at 'nextLevel'.
+func (this *Printer) printUncollapsed(nextLevel int) {
+	collapsing := this.collapsing
+	this.collapsing = nil
+	if collapsing == nil || nextLevel == collapsing.nestingLevel+1 {
+		return
+	}
+	this.collapsedNames = collapsing.collapsedNames
+	level, name, _ := this.collapse(collapsing.nestingLevel, collapsing.spec, false)
+	this.printDetails(level, name, collapsing.spec)
+}
+
 func (this *Printer) VisitSpec(nestingLevel int, name string, errors []*Error) {
-	isPassing := len(errors) == 0
-	isFailing := !isPassing
+	this.visitSpec(nestingLevel, name, errors, nil)
+}
+
+func (this *Printer) visitSpec(nestingLevel int, name string, errors []*Error, expectations []*Expectation) {
+	if this.groupFailures {
+		this.groupErrors(nestingLevel, name, errors)
+	}
+	this.print(func() {
+		if this.printSpec(nestingLevel, name, this.ungroupedErrors(errors), len(errors) > 0) {
+			this.printExpectations(nestingLevel, expectations)
+		}
+	})
+}
+
+// Returns whether the spec was printed.
+func (this *Printer) printSpec(nestingLevel int, name string, errors []*Error, isFailing bool) bool {
+	isPassing := !isFailing
 
 	if isPassing {
 		if this.show == ALL {
-			this.format.PrintPassing(nestingLevel, name)
-		} else {
-			this.saveNotPrinted(nestingLevel, name)
+			this.format.PrintPassing(this.indentation(nestingLevel, name))
+			return true
 		}
+		this.saveNotPrinted(nestingLevel, name)
+		return false
 	}
-	if isFailing {
-		this.printNotPrintedParents(nestingLevel)
-		this.format.PrintFailing(nestingLevel, name, errors)
+	this.printNotPrintedParents(nestingLevel)
+	level, name := this.indentation(nestingLevel, name)
+	this.format.PrintFailing(level, name, errors)
+	return true
+}
+
+func (this *Printer) printExpectations(nestingLevel int, expectations []*Expectation) {
+	level, _ := this.indentation(nestingLevel, "")
+	for _, expectation := range expectations {
+		marker := "✓"
+		if !expectation.Passed {
+			marker = "✗"
+		}
+		this.format.PrintPassing(level+1, marker+" "+expectation.Description)
+	}
+}
+
+func (this *Printer) indentation(nestingLevel int, name string) (int, string) {
+	if this.maxIndentation >= 0 && nestingLevel > this.maxIndentation {
+		return this.maxIndentation, fmt.Sprintf("%v (nesting level %v)", name, nestingLevel)
 	}
+	return nestingLevel, name
+}
+
+func (this *Printer) VisitQuarantinedFailure(names []string, errors []*Error) {
+	this.printUncollapsed(0)
+	this.print(func() {
+		this.format.PrintFailing(0, "Quarantined: "+strings.Join(names, " > "), errors)
+	})
+}
+
+func (this *Printer) VisitQuarantinedPass(names []string) {
+	this.printUncollapsed(0)
+	// Printed also when showing only failing specs, because
+	// the spec should be changed back to a normal spec.
+	this.print(func() {
+		this.format.PrintPassing(0, "Quarantined but passing: "+strings.Join(names, " > "))
+	})
+}
+
+func (this *Printer) VisitRunErrors(errors []*Error) {
+	this.printUncollapsed(0)
+	this.runErrorCount += len(errors)
+	this.print(func() {
+		this.format.PrintFailing(0, "Run", errors)
+	})
 }
 
 func (this *Printer) VisitEnd(passCount int, failCount int) {
+	this.printUncollapsed(0)
+	for _, action := range this.postponed {
+		action()
+	}
+	this.postponed = nil
+	for _, group := range this.failureGroups {
+		if len(group.names) > 1 {
+			this.format.PrintFailing(0, fmt.Sprintf("Identical failure in %v specs", len(group.names)), []*Error{group.error})
+			for _, name := range group.names {
+				this.format.PrintPassing(1, name)
+			}
+		}
+	}
+	this.failureGroups = nil
 	if this.showSummary {
-		this.format.PrintSummary(passCount, failCount)
+		if format, ok := this.format.(RunErrorSummaryFormat); ok && this.runErrorCount > 0 {
+			format.PrintSummaryWithRunErrors(passCount, failCount, this.runErrorCount)
+		} else {
+			this.format.PrintSummary(passCount, failCount)
+		}
+	}
+	this.runErrorCount = 0
+}
+
+func (this *Printer) print(action func()) {
+	if this.groupFailures {
+		this.postponed = append(this.postponed, action)
+	} else {
+		action()
+	}
+}
+
+func (this *Printer) groupErrors(nestingLevel int, name string, errors []*Error) {
+	if nestingLevel >= len(this.names) {
+		resizeArray(&this.names, nestingLevel+1)
+	}
+	this.names[nestingLevel] = name
+	fullName := strings.Join(this.names[:nestingLevel+1], " > ")
+	for _, error := range errors {
+		group := this.findGroup(error)
+		if group == nil {
+			group = &failureGroup{error, []string{}}
+			this.failureGroups = append(this.failureGroups, group)
+		}
+		if n := len(group.names); n == 0 || group.names[n-1] != fullName {
+			group.names = append(group.names, fullName)
+		}
+	}
+}
+
+func (this *Printer) findGroup(error *Error) *failureGroup {
+	for _, group := range this.failureGroups {
+		if group.error.Type == error.Type && group.error.equals(error) {
+			return group
+		}
 	}
+	return nil
+}
+
+// The errors which are not printed in a group of identical failures.
+func (this *Printer) ungroupedErrors(errors []*Error) []*Error {
+	result := make([]*Error, 0, len(errors))
+	for _, error := range errors {
+		if group := this.findGroup(error); group == nil || len(group.names) < 2 {
+			result = append(result, error)
+		}
+	}
+	return result
 }
 
 func (this *Printer) saveNotPrinted(nestingLevel int, name string) {
@@ -79,7 +368,7 @@ func (this *Printer) saveNotPrinted(nestingLevel int, name string) {
 func (this *Printer) printNotPrintedParents(nestingLevel int) {
 	for i, name := range this.notPrinted {
 		if i < nestingLevel && name != "" {
-			this.format.PrintPassing(i, name)
+			this.format.PrintPassing(this.indentation(i, name))
 		}
 		this.notPrinted[i] = ""
 	}
@@ -90,3 +379,9 @@ func resizeArray(arr *[]string, newLength int) {
 	*arr = make([]string, newLength)
 	copy(*arr, old)
 }
+
+func resizeIntArray(arr *[]int, newLength int) {
+	old := *arr
+	*arr = make([]int, newLength)
+	copy(*arr, old)
+}
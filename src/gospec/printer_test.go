@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
 	"strings"
+	"time"
 )
 
 var noErrors = []*Error{}
@@ -130,4 +131,268 @@ func PrinterSpec(c nanospec.Context) {
 `))
 		})
 	})
+
+	c.Specify("When showing allocations", func() {
+		p.ShowAll()
+		p.HideSummary()
+		p.ShowAllocations()
+		allocations := &Allocations{12, 3456}
+
+		c.Specify("then the allocations of the measured specs are printed", func() {
+			p.VisitSpecDetails(0, &SpecDetails{Name: "Not measured", Errors: noErrors})
+			p.VisitSpecDetails(1, &SpecDetails{Name: "Measured", Errors: noErrors, Allocations: allocations})
+			c.Expect(trim(out.String())).Equals(trim(`
+- Not measured
+  - Measured (12 allocs, 3456 bytes)
+`))
+		})
+		c.Specify("then the allocations are not printed unless asked", func() {
+			p := NewPrinter(SimplePrintFormat(out))
+			p.VisitSpecDetails(0, &SpecDetails{Name: "Measured", Errors: noErrors, Allocations: allocations})
+			c.Expect(trim(out.String())).Equals("- Measured")
+		})
+	})
+
+	c.Specify("When showing durations", func() {
+		p.ShowAll()
+		p.HideSummary()
+		p.ShowDurations()
+
+		c.Specify("then the leaf specs show their own duration and the others the total", func() {
+			p.VisitSpecDetails(0, &SpecDetails{Name: "RootSpec", Errors: noErrors, ChildCount: 2, TotalDuration: 320 * time.Millisecond})
+			p.VisitSpecDetails(1, &SpecDetails{Name: "Child A", Errors: noErrors, Duration: 300 * time.Millisecond, TotalDuration: 300 * time.Millisecond})
+			p.VisitSpecDetails(1, &SpecDetails{Name: "Child B", Errors: noErrors, Duration: 20 * time.Millisecond, TotalDuration: 20 * time.Millisecond})
+			c.Expect(trim(out.String())).Equals(trim(`
+- RootSpec (320ms total)
+  - Child A (300ms)
+  - Child B (20ms)
+`))
+		})
+		c.Specify("then the totals are the sums of the leaf specs of a run", func() {
+			r := NewRunner()
+			r.SetRunCount(3)
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.Specify("Child A", func() {
+					c.Specify("Child AA", func() {})
+					c.Specify("Child AB", func() {})
+				})
+				c.Specify("Child B", func() {})
+			})
+			r.Run()
+			spy := &detailsSpy{make(map[string]*SpecDetails)}
+			r.Results().Visit(spy)
+			root, a, aa, ab, b := spy.details["RootSpec"], spy.details["Child A"], spy.details["Child AA"], spy.details["Child AB"], spy.details["Child B"]
+			c.Expect(aa.TotalDuration).Equals(aa.Duration)
+			c.Expect(aa.Duration > 0).IsTrue()
+			c.Expect(a.TotalDuration).Equals(aa.Duration + ab.Duration)
+			c.Expect(root.TotalDuration).Equals(aa.Duration + ab.Duration + b.Duration)
+		})
+	})
+
+	c.Specify("When showing expectations", func() {
+		p.HideSummary()
+		p.ShowExpectations()
+		failure := newError(ExpectFailed, "equals “2”", "1", []*Location{})
+		expectations := []*Expectation{
+			{true, "“1” equals “1”", nil},
+			{false, "“1” equals “2”", nil},
+		}
+
+		c.Specify("then the expectations are listed under the spec", func() {
+			p.ShowAll()
+			p.VisitSpecDetails(0, &SpecDetails{Name: "Spec", Errors: []*Error{failure}, Expectations: expectations})
+			c.Expect(trim(out.String())).Equals(trim(`
+- Spec [FAIL]
+*** Expected: equals “2”
+         got: “1”
+  - ✓ “1” equals “1”
+  - ✗ “1” equals “2”
+`))
+		})
+		c.Specify("then the expectations of the specs which are not printed are not listed", func() {
+			p.ShowOnlyFailing()
+			p.VisitSpecDetails(0, &SpecDetails{Name: "Passing", Errors: noErrors, Expectations: expectations[:1]})
+			c.Expect(out.String()).Equals("")
+		})
+	})
+
+	c.Specify("When showing declaration locations", func() {
+		p.ShowAll()
+		p.HideSummary()
+		p.ShowDeclarationLocations()
+
+		c.Specify("then the location is printed after the name", func() {
+			p.VisitSpecDetails(0, &SpecDetails{Name: "Spec", Errors: noErrors, Location: &Location{"name", "/path/file.go", 12}})
+			p.VisitSpecDetails(1, &SpecDetails{Name: "Unknown location", Errors: noErrors})
+			c.Expect(trim(out.String())).Equals(trim(`
+- Spec (/path/file.go:12)
+  - Unknown location
+`))
+		})
+	})
+
+	c.Specify("When limiting the indentation", func() {
+		p.ShowAll()
+		p.HideSummary()
+		p.LimitIndentation(1)
+
+		c.Specify("then the deeper specs are indented as much as the limit, with their true nesting level", func() {
+			p.VisitSpec(0, "Root", noErrors)
+			p.VisitSpec(1, "Child", noErrors)
+			p.VisitSpec(2, "Grandchild", noErrors)
+			p.VisitSpec(3, "Great-grandchild", someError)
+			c.Expect(trim(out.String())).Equals(trim(`
+- Root
+  - Child
+  - Grandchild (nesting level 2)
+  - Great-grandchild (nesting level 3) [FAIL]
+*** some error
+`))
+		})
+		c.Specify("then the limit applies also to the parents of failing specs", func() {
+			p.ShowOnlyFailing()
+			p.VisitSpec(0, "Root", noErrors)
+			p.VisitSpec(1, "Child", noErrors)
+			p.VisitSpec(2, "Grandchild", noErrors)
+			p.VisitSpec(3, "Great-grandchild", someError)
+			c.Expect(trim(out.String())).Equals(trim(`
+- Root
+  - Child
+  - Grandchild (nesting level 2)
+  - Great-grandchild (nesting level 3) [FAIL]
+*** some error
+`))
+		})
+	})
+
+	c.Specify("When collapsing single-child chains", func() {
+		p.HideSummary()
+		p.CollapseSingleChildChains()
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				c.Specify("Child AA", func() {
+					c.Specify("Child AAA", func() {
+						c.Expect(1, Equals, 2)
+					})
+					c.Specify("Child AAB", func() {
+						c.Specify("Child AABA", func() {})
+					})
+				})
+			})
+			c.Specify("Child B", func() {})
+		})
+		r.AddNamedSpec("FailingRootSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+			c.Specify("Child A", func() {})
+		})
+		r.Run()
+
+		c.Specify("then the chains are printed on one line", func() {
+			p.ShowAll()
+			r.Results().Visit(p)
+			c.Expect(trim(out.String())).Equals(trim(`
+- FailingRootSpec [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at printer_test.go
+  - Child A
+- RootSpec
+  - Child A > Child AA
+    - Child AAA [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at printer_test.go
+    - Child AAB > Child AABA
+  - Child B
+`))
+		})
+		c.Specify("then the collapsed parents of failing specs are printed on one line", func() {
+			p.ShowOnlyFailing()
+			r.Results().Visit(p)
+			c.Expect(trim(out.String())).Equals(trim(`
+- FailingRootSpec [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at printer_test.go
+- RootSpec
+  - Child A > Child AA
+    - Child AAA [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at printer_test.go
+`))
+		})
+		c.Specify("then a spec whose only child is not visited is not collapsed", func() {
+			p.ShowAll()
+			r := NewRunner()
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.Specify("Child A", func() {
+					c.QuarantineSpecify("Child AA", func() {
+						c.Expect(1, Equals, 2)
+					})
+				})
+				c.Specify("Child B", func() {})
+			})
+			r.Run()
+			r.Results().Visit(p)
+			c.Expect(trim(out.String())).Equals(trim(`
+- RootSpec
+  - Child A
+  - Child B
+- Quarantined: RootSpec > Child A > Child AA [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at printer_test.go
+`))
+		})
+	})
+
+	c.Specify("When grouping identical failures", func() {
+		p.ShowOnlyFailing()
+		p.HideSummary()
+		p.GroupIdenticalFailures()
+		otherError := []*Error{newError(OtherError, "other error", "", []*Location{})}
+
+		c.Specify("then the identical errors are printed once with the names of the failing specs", func() {
+			p.VisitSpec(0, "RootSpec", noErrors)
+			p.VisitSpec(1, "Child A", someError)
+			p.VisitSpec(1, "Child B", noErrors)
+			p.VisitSpec(1, "Child C", append(otherError, someError...))
+			p.VisitEnd(1, 2)
+			c.Expect(trim(out.String())).Equals(trim(`
+- RootSpec
+  - Child A [FAIL]
+  - Child C [FAIL]
+*** other error
+- Identical failure in 2 specs [FAIL]
+*** some error
+  - RootSpec > Child A
+  - RootSpec > Child C
+`))
+		})
+		c.Specify("then errors which occur only once are printed normally", func() {
+			p.VisitSpec(0, "RootSpec", noErrors)
+			p.VisitSpec(1, "Child A", someError)
+			p.VisitSpec(1, "Child B", otherError)
+			p.VisitEnd(0, 2)
+			c.Expect(trim(out.String())).Equals(trim(`
+- RootSpec
+  - Child A [FAIL]
+*** some error
+  - Child B [FAIL]
+*** other error
+`))
+		})
+		c.Specify("then errors with different locations are not identical", func() {
+			r := NewRunner()
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.Specify("Child A", func() { c.Expect(1, Equals, 2) })
+				c.Specify("Child B", func() { c.Expect(1, Equals, 2) })
+			})
+			r.Run()
+			r.Results().Visit(p)
+			c.Expect(strings.Contains(out.String(), "Identical failure")).IsFalse()
+		})
+	})
 }
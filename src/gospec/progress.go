@@ -0,0 +1,67 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Prints periodically how many specs have been executed and an estimate of
+// how long executing the rest will take, for example "[ 45/200+ ] 22%, ETA 00:31+".
+// The total number of specs is not known beforehand, because the child specs
+// are discovered only when their parents are executed, so the total will grow
+// as the run progresses. Until all specs have been executed, the "+" tells
+// that the total is the number of specs discovered so far, and that the
+// percentage and the ETA are based on it.
+type progressReporter struct {
+	out         io.Writer
+	interval    time.Duration
+	now         func() time.Time
+	started     time.Time
+	lastPrinted time.Time
+}
+
+func newProgressReporter(out io.Writer, interval time.Duration, now func() time.Time) *progressReporter {
+	started := now()
+	return &progressReporter{out, interval, now, started, started}
+}
+
+func (this *progressReporter) taskFinished(result *taskResult, finished int, total int) {
+	now := this.now()
+	if now.Sub(this.lastPrinted) < this.interval && finished < total {
+		return
+	}
+	this.lastPrinted = now
+	elapsed := now.Sub(this.started)
+	eta := time.Duration(int64(elapsed) / int64(finished) * int64(total-finished))
+	fmt.Fprintf(this.out, "%v\n", formatProgress(finished, total, eta))
+}
+
+func formatProgress(finished int, total int, eta time.Duration) string {
+	more := ""
+	if finished < total {
+		more = "+"
+	}
+	width := len(fmt.Sprint(total))
+	percent := finished * 100 / total
+	return fmt.Sprintf("[ %*d/%d%v ] %d%%, ETA %v%v", width, finished, total, more, percent, formatEta(eta), more)
+}
+
+func formatEta(eta time.Duration) string {
+	seconds := int64((eta + time.Second/2) / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
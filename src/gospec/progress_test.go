@@ -0,0 +1,82 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+func ProgressSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	clock := time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := func() time.Time { return clock }
+	p := newProgressReporter(out, 10*time.Second, now)
+
+	c.Specify("The progress shows the number of executed specs, a percentage and an ETA, based on the specs discovered so far", func() {
+		c.Expect(formatProgress(45, 200, 31*time.Second)).Equals("[  45/200+ ] 22%, ETA 00:31+")
+		c.Expect(formatProgress(200, 200, 0)).Equals("[ 200/200 ] 100%, ETA 00:00")
+		c.Expect(formatProgress(1, 3, 125*time.Second)).Equals("[ 1/3+ ] 33%, ETA 02:05+")
+	})
+	c.Specify("The ETA is estimated from the average time per spec so far", func() {
+		clock = clock.Add(20 * time.Second)
+		p.taskFinished(nil, 2, 5)
+		c.Expect(out.String()).Equals("[ 2/5+ ] 40%, ETA 00:30+\n")
+	})
+	c.Specify("The progress is printed at most once per interval", func() {
+		clock = clock.Add(10 * time.Second)
+		p.taskFinished(nil, 1, 10)
+		clock = clock.Add(5 * time.Second)
+		p.taskFinished(nil, 2, 10)
+		clock = clock.Add(5 * time.Second)
+		p.taskFinished(nil, 3, 10)
+		c.Expect(out.String()).Equals("" +
+			"[  1/10+ ] 10%, ETA 01:30+\n" +
+			"[  3/10+ ] 30%, ETA 00:47+\n")
+	})
+	c.Specify("The progress is always printed when all specs have been executed", func() {
+		clock = clock.Add(1 * time.Second)
+		p.taskFinished(nil, 4, 4)
+		c.Expect(out.String()).Equals("[ 4/4 ] 100%, ETA 00:00\n")
+	})
+	c.Specify("The runner reports the progress of all discovered specs", func() {
+		spy := new(progressSpy)
+		r := NewRunner()
+		r.addListener(spy)
+		r.AddSpec(DummySpecWithMultipleNestedChildren)
+		r.Run()
+		c.Expect(spy.finished).Equals(5)
+		c.Expect(spy.total).Equals(5)
+	})
+	c.Specify("The total grows as the child specs are discovered", func() {
+		r := NewRunner()
+		r.SetSerial(true)
+		r.addListener(newProgressReporter(out, 0, now))
+		r.AddSpec(DummySpecWithMultipleNestedChildren)
+		r.Run()
+		c.Expect(out.String()).Equals("" +
+			"[ 1/3+ ] 33%, ETA 00:00+\n" +
+			"[ 2/5+ ] 40%, ETA 00:00+\n" +
+			"[ 3/5+ ] 60%, ETA 00:00+\n" +
+			"[ 4/5+ ] 80%, ETA 00:00+\n" +
+			"[ 5/5 ] 100%, ETA 00:00\n")
+	})
+	c.Specify("The progress is not shown when the output is not a terminal", func() {
+		r := NewRunner()
+		r.ShowProgress(out, time.Second)
+		c.Expect(len(r.listeners)).Equals(0)
+	})
+}
+
+type progressSpy struct {
+	finished int
+	total    int
+}
+
+func (this *progressSpy) taskFinished(result *taskResult, finished int, total int) {
+	this.finished = finished
+	this.total = total
+}
@@ -0,0 +1,78 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"sync"
+	"testing"
+)
+
+// The specs which have been registered with Register, in the order
+// in which they were registered.
+var registry struct {
+	lock  sync.Mutex
+	specs []*registeredSpec
+}
+
+type registeredSpec struct {
+	location *Location
+	name     string
+	closure  func(Context)
+}
+
+// Registers a spec to be executed by RunAll, so that the specs do not need
+// to be listed in one place. Usually called from an init function in the
+// same file as the spec. Example:
+//     func init() {
+//         gospec.Register(StackSpec)
+//     }
+func Register(closure func(Context)) {
+	registerSpec(&registeredSpec{callerLocation(), functionName(closure), closure})
+}
+
+// Registers a spec the same way as Register, but uses the provided name
+// instead of retrieving the name of the spec function with reflection.
+func RegisterNamed(name string, closure func(Context)) {
+	registerSpec(&registeredSpec{callerLocation(), name, closure})
+}
+
+func registerSpec(spec *registeredSpec) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.specs = append(registry.specs, spec)
+}
+
+// Adds all specs which have been registered with Register to this Runner.
+func (r *Runner) AddRegisteredSpecs() {
+	for _, spec := range registeredSpecs() {
+		r.addSpec(spec.location, spec.name, spec.closure)
+	}
+}
+
+func registeredSpecs() []*registeredSpec {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	specs := make([]*registeredSpec, len(registry.specs))
+	copy(specs, registry.specs)
+	return specs
+}
+
+// Forgets all registered specs. For the framework's own tests.
+func clearRegistry() {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.specs = nil
+}
+
+// Executes all specs which have been registered with Register, the same
+// way as MainGoTest. Example:
+//     func TestAllSpecs(t *testing.T) {
+//         gospec.RunAll(t)
+//     }
+func RunAll(t *testing.T) {
+	r := NewRunner()
+	r.AddRegisteredSpecs()
+	MainGoTest(r, t)
+}
@@ -0,0 +1,45 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func RegistrySpec(c nanospec.Context) {
+	clearRegistry()
+	defer clearRegistry()
+
+	c.Specify("The registered specs are added to the runner", func() {
+		Register(DummySpecWithOneChild)
+		RegisterNamed("Named", DummySpecWithNoChildren)
+		r := NewRunner()
+		r.AddRegisteredSpecs()
+		r.Run()
+
+		c.Expect(r.Results()).Matches(ReportIs(`
+- Named
+- gospec.DummySpecWithOneChild
+  - Child A
+
+3 specs, 0 failures
+`))
+	})
+	c.Specify("The specs are added in the order in which they were registered", func() {
+		RegisterNamed("Zebra", DummySpecWithNoChildren)
+		RegisterNamed("Aardvark", DummySpecWithNoChildren)
+		r := NewRunner()
+		r.AddRegisteredSpecs()
+		c.Expect(r.rootNames[0]).Equals("Zebra")
+		c.Expect(r.rootNames[1]).Equals("Aardvark")
+	})
+	c.Specify("The registry can be cleared", func() {
+		Register(DummySpecWithOneChild)
+		clearRegistry()
+		r := NewRunner()
+		r.AddRegisteredSpecs()
+		c.Expect(len(r.scheduled)).Equals(0)
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Writes the report of all specs to a file, using a format such as
+// DefaultPrintFormat or SimplePrintFormat. The report is first written to
+// a temporary file, which then replaces the file, so that a partially
+// written report is never left behind if writing it fails.
+func (r *ResultCollector) WriteToFile(path string, format func(io.Writer) PrintFormat) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	out := &errorRecordingWriter{out: tmp}
+	r.Visit(NewPrinter(format(out)))
+	if err = out.err; err != nil {
+		return
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// The print formats ignore write errors, so they are recorded here.
+type errorRecordingWriter struct {
+	out io.Writer
+	err error
+}
+
+func (this *errorRecordingWriter) Write(p []byte) (n int, err error) {
+	if this.err != nil {
+		return 0, this.err
+	}
+	n, err = this.out.Write(p)
+	this.err = err
+	return
+}
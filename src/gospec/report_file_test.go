@@ -0,0 +1,53 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func ReportFileSpec(c nanospec.Context) {
+	dir, _ := ioutil.TempDir("", "gospec")
+	defer os.RemoveAll(dir)
+	report := filepath.Join(dir, "report.txt")
+
+	r := NewRunner()
+	r.AddSpec(DummySpecWithOneChild)
+	r.Run()
+	results := r.Results()
+
+	c.Specify("The report is written to the file", func() {
+		err := results.WriteToFile(report, SimplePrintFormat)
+		c.Expect(err).Equals(nil)
+		data, _ := ioutil.ReadFile(report)
+		c.Expect(string(data)).Equals("" +
+			"- gospec.DummySpecWithOneChild\n" +
+			"  - Child A\n" +
+			"\n" +
+			"2 specs, 0 failures\n")
+	})
+	c.Specify("An existing file is replaced", func() {
+		ioutil.WriteFile(report, []byte("old report"), 0644)
+		results.WriteToFile(report, DefaultPrintFormat)
+		data, _ := ioutil.ReadFile(report)
+		c.Expect(string(data)).NotEquals("old report")
+	})
+	c.Specify("When writing fails", func() {
+		os.Mkdir(report, 0755)
+		err := results.WriteToFile(report, SimplePrintFormat)
+
+		c.Specify("the error is returned", func() {
+			c.Expect(err).NotEquals(nil)
+		})
+		c.Specify("no partial files are left behind", func() {
+			files, _ := ioutil.ReadDir(dir)
+			c.Expect(len(files)).Equals(1)
+			c.Expect(files[0].IsDir()).IsTrue()
+		})
+	})
+}
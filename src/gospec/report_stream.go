@@ -0,0 +1,91 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"sort"
+)
+
+// Visits the results of a root spec as soon as all of its specs have been
+// executed. To keep the report in the same order as when it is visited after
+// the run, a root spec is visited only after all the root specs before it in
+// the report have also been visited.
+type reportStreamer struct {
+	visitor       ResultVisitor
+	nameFormatter func(name string) string
+	roots         []string
+	next          int
+
+	// For every root spec, how many of its tasks have not yet finished,
+	// and the specs executed by the tasks which have finished.
+	unfinished map[string]int
+	executed   map[string][]*specRun
+}
+
+func newReportStreamer(visitor ResultVisitor) *reportStreamer {
+	return &reportStreamer{visitor: visitor}
+}
+
+func (this *reportStreamer) start(tasks []*scheduledTask, order RootOrder, nameFormatter func(name string) string) {
+	this.nameFormatter = nameFormatter
+	this.roots = make([]string, 0, len(tasks))
+	this.next = 0
+	this.unfinished = make(map[string]int)
+	this.executed = make(map[string][]*specRun)
+	for _, task := range tasks {
+		if _, seen := this.unfinished[task.name]; !seen {
+			this.roots = append(this.roots, task.name)
+		}
+		this.unfinished[task.name]++
+	}
+	if order == AlphabeticalOrder {
+		sort.Strings(this.roots)
+	}
+}
+
+func (this *reportStreamer) taskFinished(result *taskResult, finished int, total int) {
+	if this.next == len(this.roots) {
+		// Only the first run is streamed, when the specs are run many times.
+		return
+	}
+	name := result.name
+	this.executed[name] = append(this.executed[name], result.executedSpecs...)
+	this.executed[name] = append(this.executed[name], result.skippedSpecs...)
+	// Every postponed spec will be executed by a new task of the same root spec.
+	this.unfinished[name] += len(result.postponedSpecs) - 1
+
+	for this.next < len(this.roots) && this.unfinished[this.roots[this.next]] == 0 {
+		this.visitRoot(this.roots[this.next])
+		this.next++
+	}
+}
+
+func (this *reportStreamer) visitRoot(name string) {
+	executed := this.executed[name]
+	delete(this.executed, name)
+	sort.Stable(byExecutionOrder(executed))
+
+	results := newResultCollector()
+	results.nameFormatter = this.nameFormatter
+	for _, spec := range executed {
+		results.Update(spec)
+	}
+	results.visitSpecs(this.visitor, results.sortedRootNames())
+}
+
+// Visits the root specs which could not be visited during the run,
+// because the run was stopped before they finished, and then the rest
+// of the report after the specs.
+func (this *reportStreamer) finish(results *ResultCollector) {
+	remaining := make([]string, 0)
+	for _, name := range this.roots[this.next:] {
+		if _, executed := results.rootsByName[name]; executed {
+			remaining = append(remaining, name)
+		}
+	}
+	this.next = len(this.roots)
+	results.visitSpecs(this.visitor, remaining)
+	results.visitEnd(this.visitor)
+}
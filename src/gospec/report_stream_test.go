@@ -0,0 +1,62 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func ReportStreamSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	r := NewRunner()
+	r.SetSerial(true)
+	r.StreamReport(NewPrinter(SimplePrintFormat(out)))
+
+	c.Specify("The streamed report is the same as the report printed after the run", func() {
+		r.AddNamedSpec("RootSpec B", func(c Context) {
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Child B", func() {})
+		})
+		r.AddNamedSpec("RootSpec A", func(c Context) {
+			c.Specify("Child A", func() {})
+		})
+		r.Run()
+
+		c.Expect(out.String()).Equals(resultToString(r.Results()))
+	})
+	c.Specify("A root spec is visited as soon as it and the root specs before it have been executed", func() {
+		// The serial runner executes the last added spec first.
+		var printedBeforeB string
+		r.AddNamedSpec("RootSpec B", func(c Context) {
+			printedBeforeB = out.String()
+		})
+		r.AddNamedSpec("RootSpec A", func(c Context) {})
+		r.Run()
+
+		c.Expect(printedBeforeB).Equals("- RootSpec A\n")
+	})
+	c.Specify("A root spec is not visited before the root specs before it", func() {
+		var printedBeforeA string
+		r.AddNamedSpec("RootSpec A", func(c Context) {
+			printedBeforeA = out.String()
+		})
+		r.AddNamedSpec("RootSpec B", func(c Context) {})
+		r.Run()
+
+		c.Expect(printedBeforeA).Equals("")
+		c.Expect(out.String()).Equals("- RootSpec A\n- RootSpec B\n\n2 specs, 0 failures\n")
+	})
+	c.Specify("The root specs are visited in declaration order when so configured", func() {
+		r.SetRootOrder(DeclarationOrder)
+		r.AddNamedSpec("RootSpec B", func(c Context) {})
+		r.AddNamedSpec("RootSpec A", func(c Context) {})
+		r.Run()
+
+		c.Expect(out.String()).Equals("- RootSpec B\n- RootSpec A\n\n2 specs, 0 failures\n")
+	})
+}
@@ -0,0 +1,116 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// The failures file has one line for every leaf spec which failed,
+// or whose parent failed. The line has the names of the specs from
+// the root spec to the leaf spec, separated by " > ", for example
+// "RootSpec > Child A > Child AA".
+const failuresFileSeparator = " > "
+
+// Writes to the failures file the leaf specs which failed in the last run,
+// so that they can be rerun with SetRerunFailedFrom. If no specs failed,
+// the file will be empty.
+func (r *Runner) WriteFailuresFile(path string) error {
+	out := new(bytes.Buffer)
+	for _, names := range r.Results().failedLeaves() {
+		out.WriteString(strings.Join(names, failuresFileSeparator) + "\n")
+	}
+	return ioutil.WriteFile(path, out.Bytes(), 0644)
+}
+
+// Executes only the specs which are listed in the failures file, which was
+// written with WriteFailuresFile. Specs which no longer exist are ignored.
+// If the file is empty, because no specs failed, then all specs are executed.
+func (r *Runner) SetRerunFailedFrom(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	filter := newSpecFilter()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			filter.allow(strings.Split(line, failuresFileSeparator))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if filter.isEmpty() {
+		filter = nil
+	}
+	r.filter = filter
+	return nil
+}
+
+func (r *ResultCollector) failedLeaves() [][]string {
+	leaves := make([][]string, 0)
+	for root := range r.sortedRoots() {
+		root.visitFailedLeaves([]string{}, false, func(names []string) {
+			leaves = append(leaves, names)
+		})
+	}
+	return leaves
+}
+
+func (this *specResult) visitFailedLeaves(parents []string, parentFailed bool, visitor func([]string)) {
+	names := make([]string, len(parents), len(parents)+1)
+	copy(names, parents)
+	names = append(names, this.name)
+	failed := parentFailed || this.isFailed()
+	if this.children.Len() == 0 {
+		if failed {
+			visitor(names)
+		}
+		return
+	}
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		child := e.Value.(*specResult)
+		child.visitFailedLeaves(names, failed, visitor)
+	}
+}
+
+// Allows executing only some of the specs. A nil filter allows all specs.
+type specFilter struct {
+	allowed map[string]bool
+
+	// The full names of the specs which were allowed, without their parents.
+	specs []string
+}
+
+func newSpecFilter() *specFilter {
+	return &specFilter{make(map[string]bool), []string{}}
+}
+
+// Allows the spec and its parents.
+func (this *specFilter) allow(names []string) {
+	for i := 1; i <= len(names); i++ {
+		this.allowed[strings.Join(names[:i], failuresFileSeparator)] = true
+	}
+	this.specs = append(this.specs, strings.Join(names, failuresFileSeparator))
+}
+
+func (this *specFilter) isEmpty() bool {
+	return len(this.allowed) == 0
+}
+
+func (this *specFilter) allows(spec *specRun) bool {
+	return this.allowsNames(spec.namePath())
+}
+
+func (this *specFilter) allowsNames(names []string) bool {
+	return this == nil || this.allowed[strings.Join(names, failuresFileSeparator)]
+}
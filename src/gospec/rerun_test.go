@@ -0,0 +1,95 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func RerunSpec(c nanospec.Context) {
+	dir, _ := ioutil.TempDir("", "gospec")
+	defer os.RemoveAll(dir)
+	failures := filepath.Join(dir, "failures.txt")
+
+	r := NewRunner()
+	r.AddNamedSpec("RootSpec", FailingSpecForRerun)
+	r.AddSpec(DummySpecWithOneChild)
+	r.Run()
+	r.WriteFailuresFile(failures)
+
+	c.Specify("The failed leaf specs are written to the failures file", func() {
+		data, _ := ioutil.ReadFile(failures)
+		c.Expect(string(data)).Equals("" +
+			"RootSpec > Child A\n" +
+			"RootSpec > Child C > Child CA\n" +
+			"RootSpec > Child C > Child CB\n")
+	})
+	c.Specify("Only the specs in the failures file are rerun", func() {
+		rerun := NewRunner()
+		rerun.AddNamedSpec("RootSpec", FailingSpecForRerun)
+		rerun.AddSpec(DummySpecWithOneChild)
+		err := rerun.SetRerunFailedFrom(failures)
+		rerun.Run()
+
+		c.Expect(err).Equals(nil)
+		c.Expect(rerun.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at rerun_test.go
+  - Child C [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at rerun_test.go
+    - Child CA
+    - Child CB
+
+5 specs, 2 failures
+`))
+	})
+	c.Specify("Specs which no longer exist are ignored", func() {
+		ioutil.WriteFile(failures, []byte("RootSpec > Removed\nRemovedSpec > Child A\nRootSpec > Child B\n"), 0644)
+		rerun := NewRunner()
+		rerun.AddNamedSpec("RootSpec", FailingSpecForRerun)
+		rerun.SetRerunFailedFrom(failures)
+		rerun.Run()
+
+		c.Expect(rerun.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child B
+
+2 specs, 0 failures
+`))
+	})
+	c.Specify("When no specs failed, all specs are rerun", func() {
+		ioutil.WriteFile(failures, []byte{}, 0644)
+		rerun := NewRunner()
+		rerun.AddSpec(DummySpecWithOneChild)
+		rerun.SetRerunFailedFrom(failures)
+		rerun.Run()
+
+		c.Expect(rerun.Results().TotalCount()).Equals(2)
+	})
+	c.Specify("A missing failures file is an error", func() {
+		err := NewRunner().SetRerunFailedFrom(filepath.Join(dir, "no-such-file"))
+		c.Expect(os.IsNotExist(err)).IsTrue()
+	})
+}
+
+func FailingSpecForRerun(c Context) {
+	c.Specify("Child A", func() {
+		c.Expect(1, Equals, 2)
+	})
+	c.Specify("Child B", func() {})
+	c.Specify("Child C", func() {
+		c.Expect(1, Equals, 2)
+		c.Specify("Child CA", func() {})
+		c.Specify("Child CB", func() {})
+	})
+}
@@ -0,0 +1,132 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stores the results of the leaf specs as soon as they have been executed,
+// for example to a database for following the results over time. A Runner
+// calls RecordSpec from the goroutine which called Run, one call at a time,
+// so a sink which is added to only one Runner needs no locking. A sink which
+// is shared by Runners that are run concurrently must synchronize itself.
+// The calls are in the order in which the leaf specs finished executing,
+// which is not the order of the report. See Runner.AddResultSink.
+type ResultSink interface {
+	RecordSpec(result SpecResult)
+}
+
+// The result of one execution of a leaf spec.
+type SpecResult struct {
+	// The names of the leaf spec and its parents, from the root spec
+	// to the leaf, as formatted by the Runner's name formatter.
+	Names []string
+
+	// The errors of the leaf spec and its parents. A leaf fails also when
+	// one of its parents failed, because they were executed together.
+	Errors []*Error
+
+	// How long it took to execute the leaf spec and its parents.
+	Duration time.Duration
+
+	// Where the leaf spec was declared.
+	Location *Location
+
+	// Why the leaf spec was skipped, or empty if it was not skipped.
+	SkipReason string
+
+	// Whether the leaf spec or one of its parents is quarantined,
+	// so that its failures are known failures.
+	Quarantined bool
+}
+
+// The names of the spec and its parents joined with " > ".
+func (result SpecResult) Name() string {
+	return strings.Join(result.Names, " > ")
+}
+
+func (result SpecResult) Failed() bool {
+	return len(result.Errors) > 0
+}
+
+// Adds a sink to which the result of every leaf spec is given as soon as
+// it has been executed. When the specs are run many times (see SetRunCount),
+// the sink gets the result of every run.
+func (r *Runner) AddResultSink(sink ResultSink) {
+	r.addListener(&resultSinkNotifier{sink, r})
+}
+
+type resultSinkNotifier struct {
+	sink   ResultSink
+	runner *Runner
+}
+
+func (this *resultSinkNotifier) taskFinished(result *taskResult, finished int, total int) {
+	n := len(result.executedSpecs)
+	if n == 0 {
+		return
+	}
+	leaf := result.executedSpecs[n-1]
+	errors := make([]*Error, 0)
+	for _, spec := range result.executedSpecs {
+		errors = append(errors, listToErrorArray(spec.errors)...)
+	}
+	this.sink.RecordSpec(SpecResult{
+		Names:       this.names(leaf),
+		Errors:      errors,
+		Duration:    result.duration,
+		Location:    leaf.location,
+		SkipReason:  leaf.skipReason,
+		Quarantined: leaf.isQuarantined(),
+	})
+	// The parents of the skipped specs were executed for the leaf,
+	// so their errors are recorded only with the leaf.
+	for _, skipped := range result.skippedSpecs {
+		this.sink.RecordSpec(SpecResult{
+			Names:       this.names(skipped),
+			Errors:      []*Error{},
+			Location:    skipped.location,
+			SkipReason:  skipped.skipReason,
+			Quarantined: skipped.isQuarantined(),
+		})
+	}
+}
+
+func (this *resultSinkNotifier) names(spec *specRun) []string {
+	names := spec.namePath()
+	for i, name := range names {
+		names[i] = this.runner.nameFormatter(name)
+	}
+	return names
+}
+
+// A ResultSink which keeps the results in memory. It is safe to use from
+// many Runners concurrently.
+type MemoryResultSink struct {
+	lock    sync.Mutex
+	results []SpecResult
+}
+
+func NewMemoryResultSink() *MemoryResultSink {
+	return &MemoryResultSink{results: make([]SpecResult, 0)}
+}
+
+func (sink *MemoryResultSink) RecordSpec(result SpecResult) {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	sink.results = append(sink.results, result)
+}
+
+// The results which have been recorded so far, in the order they were recorded.
+func (sink *MemoryResultSink) Results() []SpecResult {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	results := make([]SpecResult, len(sink.results))
+	copy(results, sink.results)
+	return results
+}
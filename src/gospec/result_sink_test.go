@@ -0,0 +1,97 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sort"
+)
+
+func ResultSinkSpec(c nanospec.Context) {
+	sink := NewMemoryResultSink()
+	r := NewRunner()
+	r.AddResultSink(sink)
+
+	resultNames := func() []string {
+		names := make([]string, 0)
+		for _, result := range sink.Results() {
+			names = append(names, result.Name())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	c.Specify("The sink gets the result of every leaf spec", func() {
+		r.AddSpec(DummySpecWithMultipleNestedChildren)
+		r.Run()
+		c.Expect(resultNames()).Equals([]string{
+			"gospec.DummySpecWithMultipleNestedChildren > Child A > Child AA",
+			"gospec.DummySpecWithMultipleNestedChildren > Child A > Child AB",
+			"gospec.DummySpecWithMultipleNestedChildren > Child B > Child BA",
+			"gospec.DummySpecWithMultipleNestedChildren > Child B > Child BB",
+			"gospec.DummySpecWithMultipleNestedChildren > Child B > Child BC",
+		})
+	})
+	c.Specify("A leaf fails when it or one of its parents failed", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {
+				c.Expect(3, Equals, 4)
+			})
+		})
+		r.Run()
+		results := sink.Results()
+		sort.Slice(results, func(i, j int) bool { return results[i].Name() < results[j].Name() })
+		c.Expect(len(results)).Equals(2)
+		c.Expect(results[0].Failed()).IsTrue()
+		c.Expect(len(results[0].Errors)).Equals(1)
+		c.Expect(len(results[1].Errors)).Equals(2)
+		c.Expect(results[1].Location.FileName()).Equals("result_sink_test.go")
+	})
+	c.Specify("The names are formatted with the name formatter", func() {
+		r.SetNameFormatter(func(name string) string { return "<" + name + ">" })
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {})
+		})
+		r.Run()
+		c.Expect(resultNames()).Equals([]string{"<RootSpec> > <Child A>"})
+	})
+	c.Specify("Skipped and quarantined specs are marked as such", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.SpecifyIf(false, "not ready", "Child A", func() {})
+			c.QuarantineSpecify("Child B", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		r.Run()
+		results := sink.Results()
+		sort.Slice(results, func(i, j int) bool { return results[i].Name() < results[j].Name() })
+		c.Expect(results[0].SkipReason).Equals("not ready")
+		c.Expect(results[0].Quarantined).IsFalse()
+		c.Expect(results[1].Quarantined).IsTrue()
+		c.Expect(results[1].Failed()).IsTrue()
+	})
+	c.Specify("The errors of the parents are recorded only with the executed leaf spec", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+			c.Specify("Child A", func() {})
+			c.SpecifyIf(false, "not ready", "Child B", func() {})
+		})
+		r.Run()
+		results := sink.Results()
+		sort.Slice(results, func(i, j int) bool { return results[i].Name() < results[j].Name() })
+		c.Expect(len(results)).Equals(2)
+		c.Expect(results[0].Failed()).IsTrue()
+		c.Expect(results[1].SkipReason).Equals("not ready")
+		c.Expect(results[1].Failed()).IsFalse()
+	})
+	c.Specify("Every run of the specs is recorded", func() {
+		r.SetRunCount(3)
+		r.AddNamedSpec("RootSpec", func(c Context) {})
+		r.Run()
+		c.Expect(len(sink.Results())).Equals(3)
+	})
+}
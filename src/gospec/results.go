@@ -7,14 +7,29 @@ package gospec
 import (
 	"container/list"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
+	"time"
 )
 
 // Collects test results for all specs in a reporting friendly format.
 type ResultCollector struct {
-	rootsByName map[string]*specResult
-	passCount   int
-	failCount   int
+	rootsByName      map[string]*specResult
+	passCount        int
+	failCount        int
+	quarantinedCount int
+	skippedCount     int
+	config           []configEntry
+	runErrors        *list.List
+	rootOrder        []string
+	randomSeed       int64
+	usedRandom       bool
+	executionOrder   []executedLeaf
+	nameFormatter    func(name string) string
+	runCount         int
+	leafRuns         []leafRuns
+	duration         time.Duration
 }
 
 func newResultCollector() *ResultCollector {
@@ -22,6 +37,18 @@ func newResultCollector() *ResultCollector {
 		make(map[string]*specResult),
 		-1,
 		-1,
+		-1,
+		-1,
+		[]configEntry{},
+		list.New(),
+		nil,
+		0,
+		false,
+		[]executedLeaf{},
+		IdentityNameFormatter,
+		1,
+		[]leafRuns{},
+		0,
 	}
 }
 
@@ -61,6 +88,50 @@ func (r *ResultCollector) FailCount() int {
 	return r.failCount
 }
 
+// Number of failed quarantined specs. They are not included
+// in FailCount or TotalCount.
+func (r *ResultCollector) QuarantinedCount() int {
+	if r.quarantinedCount < 0 {
+		r.calculateSpecCount()
+	}
+	return r.quarantinedCount
+}
+
+// Number of specs which were skipped, because they were declared with
+// Context.SpecifyIf and their condition was false. They are not included
+// in PassCount or TotalCount.
+func (r *ResultCollector) SkippedCount() int {
+	if r.skippedCount < 0 {
+		r.calculateSpecCount()
+	}
+	return r.skippedCount
+}
+
+// Errors which concern the whole run instead of any single spec,
+// for example when fewer specs were executed than were required.
+func (r *ResultCollector) RunErrors() []*Error {
+	return listToErrorArray(r.runErrors)
+}
+
+// The seed from which the random sources of the specs were seeded,
+// when some spec used Context.Rand.
+func (r *ResultCollector) RandomSeed() (seed int64, used bool) {
+	return r.randomSeed, r.usedRandom
+}
+
+func (r *ResultCollector) recordRandomSeed(seed int64) {
+	r.randomSeed = seed
+	r.usedRandom = true
+}
+
+func (r *ResultCollector) addRunError(error *Error) {
+	r.runErrors.PushBack(error)
+}
+
+func (r *ResultCollector) hasFailures() bool {
+	return r.FailCount() > 0 || r.runErrors.Len() > 0
+}
+
 func (r *ResultCollector) calculateSpecCount() {
 	r.resetSpecCount()
 	r.visitAll(func(spec *specResult) {
@@ -71,12 +142,19 @@ func (r *ResultCollector) calculateSpecCount() {
 func (r *ResultCollector) resetSpecCount() {
 	r.failCount = 0
 	r.passCount = 0
+	r.quarantinedCount = 0
+	r.skippedCount = 0
 }
 
 func (r *ResultCollector) incrementSpecCount(spec *specResult) {
-	if spec.isFailed() {
+	switch {
+	case spec.isFailed() && spec.quarantined:
+		r.quarantinedCount++
+	case spec.skipReason != "":
+		r.skippedCount++
+	case spec.isFailed():
 		r.failCount++
-	} else {
+	default:
 		r.passCount++
 	}
 }
@@ -88,12 +166,121 @@ type ResultVisitor interface {
 	VisitEnd(passCount int, failCount int)
 }
 
+// ResultVisitors may implement also this interface, to be told more details
+// about each spec. Then VisitSpecDetails is called instead of VisitSpec.
+type DetailedResultVisitor interface {
+	ResultVisitor
+	VisitSpecDetails(nestingLevel int, spec *SpecDetails)
+}
+
+// The results of one spec, for DetailedResultVisitors.
+type SpecDetails struct {
+	Name   string
+	Errors []*Error
+
+	// Where the spec was declared: the call to Context.Specify,
+	// or the spec function in case of root specs.
+	Location *Location
+
+	// Heap allocations during the execution of a leaf spec, when
+	// measured with Runner.SetMeasureAllocations, otherwise nil.
+	Allocations *Allocations
+
+	// How long the task of a leaf spec took, including the parent specs
+	// which were executed together with it. Zero for the other specs.
+	Duration time.Duration
+
+	// The sum of the durations of the leaf specs under the spec, or for
+	// a leaf spec its own duration. When the specs are run many times,
+	// every leaf is counted once, with the duration of its latest run.
+	TotalDuration time.Duration
+
+	// The expectations which the spec evaluated, both passing and failing,
+	// in the order they were evaluated, when they were recorded with
+	// Runner.RecordExpectations, otherwise nil.
+	Expectations []*Expectation
+
+	// Number of the direct children of the spec.
+	ChildCount int
+
+	// The metadata recorded with Context.Meta by the spec and its parents.
+	Metadata map[string]string
+
+	// Why the spec was not executed, when it was declared with
+	// Context.SpecifyIf and its condition was false, otherwise "".
+	SkipReason string
+}
+
+type Allocations struct {
+	Count uint64
+	Bytes uint64
+}
+
+// One evaluated expectation or assumption.
+type Expectation struct {
+	Passed bool
+
+	// The actual value and what the matcher expected from it, for example
+	// "“1” equals “2”", or the error of the matcher.
+	Description string
+
+	Location *Location
+}
+
+// ResultVisitors may implement also this interface, to be told about the
+// errors which concern the whole run instead of any single spec. They are
+// visited after all the specs and before VisitEnd.
+type RunErrorVisitor interface {
+	VisitRunErrors(errors []*Error)
+}
+
+// ResultVisitors may implement also this interface, to be told about the
+// quarantined specs separately from the other specs. Then the quarantined
+// specs are not visited with VisitSpec. They are visited after all the other
+// specs, in the same order, and the names are the full names from the root
+// spec to the quarantined spec.
+type QuarantineVisitor interface {
+	// Called for every failed spec which is quarantined.
+	VisitQuarantinedFailure(names []string, errors []*Error)
+
+	// Called for every spec which was declared with QuarantineSpecify,
+	// when it and all its children passed.
+	VisitQuarantinedPass(names []string)
+}
+
 func (r *ResultCollector) Visit(visitor ResultVisitor) {
-	r.resetSpecCount()
-	r.visitAll(func(spec *specResult) {
-		r.incrementSpecCount(spec)
-		visitor.VisitSpec(len(spec.path), spec.name, listToErrorArray(spec.errors))
-	})
+	r.visitSpecs(visitor, r.sortedRootNames())
+	r.visitEnd(visitor)
+}
+
+// Visits the specs of the given root specs, but not the quarantined
+// specs, the run errors or the end.
+func (r *ResultCollector) visitSpecs(visitor ResultVisitor, rootNames []string) {
+	_, visitsQuarantine := visitor.(QuarantineVisitor)
+	for _, name := range rootNames {
+		r.rootsByName[name].visitAll(func(spec *specResult) {
+			if spec.quarantined && visitsQuarantine {
+				return
+			}
+			if v, ok := visitor.(DetailedResultVisitor); ok {
+				details := spec.details()
+				details.Name = r.nameFormatter(details.Name)
+				v.VisitSpecDetails(len(spec.path), details)
+			} else {
+				visitor.VisitSpec(len(spec.path), r.nameFormatter(spec.name), listToErrorArray(spec.errors))
+			}
+		})
+	}
+}
+
+func (r *ResultCollector) visitEnd(visitor ResultVisitor) {
+	if v, ok := visitor.(QuarantineVisitor); ok {
+		r.visitQuarantined(v)
+	}
+	if v, ok := visitor.(RunErrorVisitor); ok && r.runErrors.Len() > 0 {
+		v.VisitRunErrors(listToErrorArray(r.runErrors))
+	}
+	r.calculateSpecCount()
 	visitor.VisitEnd(r.passCount, r.failCount)
 }
 
@@ -107,12 +294,78 @@ func listToErrorArray(list *list.List) []*Error {
 	return arr
 }
 
+func (r *ResultCollector) visitQuarantined(visitor QuarantineVisitor) {
+	r.visitAllWithNames(func(names []string, spec *specResult) {
+		if !spec.quarantineRoot {
+			return
+		}
+		passed := true
+		spec.visitAllWithNames(names[:len(names)-1], func(names []string, spec *specResult) {
+			if spec.isFailed() {
+				passed = false
+				visitor.VisitQuarantinedFailure(r.formatNames(names), listToErrorArray(spec.errors))
+			}
+		})
+		if passed {
+			visitor.VisitQuarantinedPass(r.formatNames(names))
+		}
+	})
+}
+
+// Prints the 'n' leaf specs which are nested most deeply, together with
+// their nesting level, to help find specs which have grown hard to read.
+// Specs which are nested equally deeply are sorted by their names.
+// Nothing is printed when 'n' is not positive.
+func (r *ResultCollector) PrintDeepest(out io.Writer, n int) {
+	if n <= 0 {
+		return
+	}
+	type leaf struct {
+		depth int
+		name  string
+	}
+	leaves := make([]leaf, 0)
+	r.visitAllWithNames(func(names []string, spec *specResult) {
+		if spec.children.Len() == 0 {
+			leaves = append(leaves, leaf{len(spec.path), strings.Join(r.formatNames(names), " > ")})
+		}
+	})
+	sort.Slice(leaves, func(i, j int) bool {
+		if leaves[i].depth != leaves[j].depth {
+			return leaves[i].depth > leaves[j].depth
+		}
+		return leaves[i].name < leaves[j].name
+	})
+	if len(leaves) > n {
+		leaves = leaves[:n]
+	}
+
+	fmt.Fprintf(out, "\nDeepest specs:\n")
+	for i, leaf := range leaves {
+		fmt.Fprintf(out, "  %v. %v (nesting level %v)\n", i+1, leaf.name, leaf.depth)
+	}
+}
+
+func (r *ResultCollector) formatNames(names []string) []string {
+	formatted := make([]string, len(names))
+	for i, name := range names {
+		formatted[i] = r.nameFormatter(name)
+	}
+	return formatted
+}
+
 func (r *ResultCollector) visitAll(visitor func(*specResult)) {
 	for root := range r.sortedRoots() {
 		root.visitAll(visitor)
 	}
 }
 
+func (r *ResultCollector) visitAllWithNames(visitor func([]string, *specResult)) {
+	for root := range r.sortedRoots() {
+		root.visitAllWithNames([]string{}, visitor)
+	}
+}
+
 func (r *ResultCollector) sortedRoots() <-chan *specResult {
 	iter := make(chan *specResult)
 	go func() {
@@ -124,35 +377,94 @@ func (r *ResultCollector) sortedRoots() <-chan *specResult {
 	return iter
 }
 
+// Reports the root specs in the given order instead of sorting them by name.
+// Root specs which are not in the list are reported after them, sorted by name.
+func (r *ResultCollector) orderRoots(names []string) {
+	r.rootOrder = names
+}
+
 func (r *ResultCollector) sortedRootNames() []string {
-	names := make([]string, len(r.rootsByName))
-	i := 0
+	names := make([]string, 0, len(r.rootsByName))
+	ordered := make(map[string]bool)
+	for _, name := range r.rootOrder {
+		if _, exists := r.rootsByName[name]; exists && !ordered[name] {
+			names = append(names, name)
+			ordered[name] = true
+		}
+	}
+	rest := make([]string, 0, len(r.rootsByName)-len(names))
 	for name := range r.rootsByName {
-		names[i] = name
-		i++
+		if !ordered[name] {
+			rest = append(rest, name)
+		}
 	}
-	sort.Strings(names)
-	return names
+	sort.Strings(rest)
+	return append(names, rest...)
 }
 
 // Collects test results for one spec and its children in a reporting friendly format.
 type specResult struct {
-	name     string
-	path     path
-	children *list.List
-	errors   *list.List
+	name         string
+	path         path
+	location     *Location
+	children     *list.List
+	errors       *list.List
+	allocations  *Allocations
+	duration     time.Duration
+	expectations []*Expectation
+	metadata     map[string]string
+	skipReason   string
+
+	// 'quarantined' is true also for the children of
+	// the spec which was declared as quarantined.
+	quarantined    bool
+	quarantineRoot bool
 }
 
 func newSpecResult(spec *specRun) *specResult {
-	// 'children' and 'errors' will be populated by update()
+	// 'children', 'errors', 'allocations', 'duration' and 'metadata' will be populated by update()
 	return &specResult{
 		spec.name,
 		spec.path,
+		spec.location,
 		list.New(),
 		list.New(),
+		nil,
+		0,
+		nil,
+		make(map[string]string),
+		spec.skipReason,
+		spec.isQuarantined(),
+		spec.quarantined && !spec.parent.isQuarantined(),
+	}
+}
+
+func (this *specResult) details() *SpecDetails {
+	return &SpecDetails{
+		Name:          this.name,
+		Errors:        listToErrorArray(this.errors),
+		Location:      this.location,
+		Allocations:   this.allocations,
+		Duration:      this.duration,
+		TotalDuration: this.totalDuration(),
+		Expectations:  this.expectations,
+		ChildCount:    this.children.Len(),
+		Metadata:      this.metadata,
+		SkipReason:    this.skipReason,
 	}
 }
 
+func (this *specResult) totalDuration() time.Duration {
+	if this.children.Len() == 0 {
+		return this.duration
+	}
+	var total time.Duration
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		total += e.Value.(*specResult).totalDuration()
+	}
+	return total
+}
+
 func (this *specResult) isFailed() bool {
 	return this.errors.Len() > 0
 }
@@ -165,6 +477,17 @@ func (this *specResult) visitAll(visitor func(*specResult)) {
 	}
 }
 
+func (this *specResult) visitAllWithNames(parents []string, visitor func([]string, *specResult)) {
+	names := make([]string, len(parents), len(parents)+1)
+	copy(names, parents)
+	names = append(names, this.name)
+	visitor(names, this)
+	for e := this.children.Front(); e != nil; e = e.Next() {
+		child := e.Value.(*specResult)
+		child.visitAllWithNames(names, visitor)
+	}
+}
+
 func (this *specResult) update(spec *specRun) {
 	isMe := this.path.isEqual(spec.path)
 	isMyChild := this.path.isOn(spec.path) && !isMe
@@ -172,6 +495,20 @@ func (this *specResult) update(spec *specRun) {
 
 	if isMe {
 		this.mergeErrors(spec.errors)
+		if spec.allocations != nil {
+			this.allocations = spec.allocations
+		}
+		if spec.duration != 0 {
+			this.duration = spec.duration
+		}
+		if this.expectations == nil {
+			// Parent specs are executed once for each of their leaf specs,
+			// and the first execution is reported.
+			this.expectations = spec.expectations
+		}
+		for key, value := range spec.mergedMetadata() {
+			this.metadata[key] = value
+		}
 	}
 	if isMyDirectChild {
 		if !this.isRegisteredChild(spec) {
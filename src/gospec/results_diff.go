@@ -0,0 +1,161 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The differences between the results of two runs. The specs are identified
+// by their full names, from the root spec to the spec, which are joined with
+// " > ". Renaming or moving a spec shows it as removed and added.
+type ResultDiff struct {
+	// Specs which fail now, but did not fail in the baseline.
+	// Also the added specs which fail are newly failing.
+	NewlyFailing []string
+
+	// Specs which failed in the baseline, but pass now.
+	NewlyPassing []string
+
+	// Specs which are not in the baseline.
+	Added []string
+
+	// Specs which are in the baseline, but not anymore.
+	Removed []string
+}
+
+// Compares the results of a run with the results of an earlier run,
+// for example to find out whether a change broke any specs which were
+// passing before. Failures of quarantined specs are not counted as
+// failures. The specs are listed in the order of the reports.
+func DiffResults(baseline *ResultCollector, current *ResultCollector) *ResultDiff {
+	return diffFailures(baseline.failuresByName(), current.failuresByName())
+}
+
+// Compares the results of a run with the results of an earlier run which
+// were saved with CsvPrintFormat, so that the baseline can come from another
+// process, for example from the last build of the main branch. Only the leaf
+// specs are compared, because the CSV has only them, and a leaf fails also
+// when one of its parents failed. Unlike with DiffResults, the failures of
+// quarantined specs are counted as failures, because the CSV does not tell
+// which specs are quarantined. The errors of the whole run are not compared.
+func DiffResultsWithCsv(baseline io.Reader, current *ResultCollector) (*ResultDiff, error) {
+	before, err := readCsvFailures(baseline)
+	if err != nil {
+		return nil, err
+	}
+	// The current results are compared the same way as they would
+	// be saved, so that both sides have the same specs.
+	report := new(bytes.Buffer)
+	current.Visit(CsvPrintFormat(report))
+	after, err := readCsvFailures(report)
+	if err != nil {
+		return nil, err
+	}
+	return diffFailures(before, after), nil
+}
+
+func diffFailures(before *specFailures, after *specFailures) *ResultDiff {
+	diff := &ResultDiff{[]string{}, []string{}, []string{}, []string{}}
+	for _, name := range after.names {
+		failed, existed := before.failed[name]
+		if !existed {
+			diff.Added = append(diff.Added, name)
+		}
+		switch {
+		case !failed && after.failed[name]:
+			diff.NewlyFailing = append(diff.NewlyFailing, name)
+		case failed && !after.failed[name]:
+			diff.NewlyPassing = append(diff.NewlyPassing, name)
+		}
+	}
+	for _, name := range before.names {
+		if _, exists := after.failed[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	return diff
+}
+
+// Whether each spec failed, by the full names of the specs,
+// and the names in the order of the report.
+type specFailures struct {
+	names  []string
+	failed map[string]bool
+}
+
+func newSpecFailures() *specFailures {
+	return &specFailures{[]string{}, make(map[string]bool)}
+}
+
+func (this *specFailures) add(name string, failed bool) {
+	this.names = append(this.names, name)
+	this.failed[name] = failed
+}
+
+func (r *ResultCollector) failuresByName() *specFailures {
+	failures := newSpecFailures()
+	r.visitAllWithNames(func(names []string, spec *specResult) {
+		failures.add(strings.Join(names, " > "), spec.isFailed() && !spec.quarantined)
+	})
+	return failures
+}
+
+func readCsvFailures(in io.Reader) (*specFailures, error) {
+	rows, err := csv.NewReader(in).ReadAll()
+	if err != nil {
+		return nil, Errorf("cannot read the CSV report: %v", err)
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != "spec,status,duration,message,location" {
+		return nil, Errorf("not a CSV report of CsvPrintFormat: the header is missing")
+	}
+	failures := newSpecFailures()
+	for _, row := range rows[1:] {
+		name, status, duration := row[0], row[1], row[2]
+		if duration == "" {
+			// The errors of the whole run have no duration.
+			continue
+		}
+		failures.add(name, status == "failed")
+	}
+	return failures, nil
+}
+
+// Whether some spec fails now which did not fail in the baseline.
+func (d *ResultDiff) HasNewFailures() bool {
+	return len(d.NewlyFailing) > 0
+}
+
+// Prints the kinds of differences which there were, and under each of them
+// the specs which differed that way.
+func (d *ResultDiff) Print(out io.Writer) {
+	sections := []struct {
+		title string
+		names []string
+	}{
+		{"Newly failing", d.NewlyFailing},
+		{"Newly passing", d.NewlyPassing},
+		{"Added", d.Added},
+		{"Removed", d.Removed},
+	}
+	printed := false
+	for _, section := range sections {
+		if len(section.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n%v (%v):\n", section.title, len(section.names))
+		for _, name := range section.names {
+			fmt.Fprintf(out, "  - %v\n", name)
+		}
+		printed = true
+	}
+	if !printed {
+		fmt.Fprintf(out, "\nNo differences to the baseline\n")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func ResultDiffSpec(c nanospec.Context) {
+	run := func(spec func(c Context)) *ResultCollector {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", spec)
+		r.Run()
+		return r.Results()
+	}
+	baseline := run(func(c Context) {
+		c.Specify("Breaks", func() {})
+		c.Specify("Gets fixed", func() {
+			c.Expect(1, Equals, 2)
+		})
+		c.Specify("Stays the same", func() {})
+		c.Specify("Gets removed", func() {})
+	})
+	current := run(func(c Context) {
+		c.Specify("Breaks", func() {
+			c.Expect(1, Equals, 2)
+		})
+		c.Specify("Gets fixed", func() {})
+		c.Specify("Stays the same", func() {})
+		c.Specify("Gets added", func() {
+			c.Expect(1, Equals, 2)
+		})
+	})
+
+	c.Specify("The differences are reported by the full names of the specs", func() {
+		diff := DiffResults(baseline, current)
+		c.Expect(strings.Join(diff.NewlyFailing, ",")).Equals("RootSpec > Breaks,RootSpec > Gets added")
+		c.Expect(strings.Join(diff.NewlyPassing, ",")).Equals("RootSpec > Gets fixed")
+		c.Expect(strings.Join(diff.Added, ",")).Equals("RootSpec > Gets added")
+		c.Expect(strings.Join(diff.Removed, ",")).Equals("RootSpec > Gets removed")
+		c.Expect(diff.HasNewFailures()).IsTrue()
+	})
+	c.Specify("Only specs which did not fail in the baseline are newly failing", func() {
+		diff := DiffResults(current, baseline)
+		c.Expect(strings.Join(diff.NewlyFailing, ",")).Equals("RootSpec > Gets fixed")
+		c.Expect(diff.HasNewFailures()).IsTrue()
+		c.Expect(DiffResults(current, current).HasNewFailures()).IsFalse()
+	})
+	c.Specify("The differences are printed by their kind", func() {
+		out := new(bytes.Buffer)
+		DiffResults(baseline, current).Print(out)
+		c.Expect(out.String()).Equals("" +
+			"\nNewly failing (2):\n" +
+			"  - RootSpec > Breaks\n" +
+			"  - RootSpec > Gets added\n" +
+			"\nNewly passing (1):\n" +
+			"  - RootSpec > Gets fixed\n" +
+			"\nAdded (1):\n" +
+			"  - RootSpec > Gets added\n" +
+			"\nRemoved (1):\n" +
+			"  - RootSpec > Gets removed\n")
+	})
+	c.Specify("The baseline can be read from a CSV report", func() {
+		saved := new(bytes.Buffer)
+		baseline.Visit(CsvPrintFormat(saved))
+		diff, err := DiffResultsWithCsv(saved, current)
+		c.Expect(err).Equals(nil)
+		c.Expect(strings.Join(diff.NewlyFailing, ",")).Equals("RootSpec > Breaks,RootSpec > Gets added")
+		c.Expect(strings.Join(diff.NewlyPassing, ",")).Equals("RootSpec > Gets fixed")
+		c.Expect(strings.Join(diff.Added, ",")).Equals("RootSpec > Gets added")
+		c.Expect(strings.Join(diff.Removed, ",")).Equals("RootSpec > Gets removed")
+	})
+	c.Specify("A baseline which is not a CSV report is an error", func() {
+		_, err := DiffResultsWithCsv(strings.NewReader("RootSpec > Breaks\n"), current)
+		c.Expect(err.Error()).Equals("not a CSV report of CsvPrintFormat: the header is missing")
+	})
+	c.Specify("Identical results have no differences", func() {
+		out := new(bytes.Buffer)
+		DiffResults(baseline, baseline).Print(out)
+		c.Expect(out.String()).Equals("\nNo differences to the baseline\n")
+	})
+}
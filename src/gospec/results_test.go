@@ -8,6 +8,9 @@ import (
 	"bytes"
 	"errors"
 	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -306,6 +309,528 @@ func ResultsSpec(c nanospec.Context) {
 `))
 		})
 	})
+
+	c.Specify("When fewer specs were executed than were required", func() {
+		runner := NewRunner()
+		runner.RequireMinSpecs(3)
+		runner.AddSpec(DummySpecWithTwoChildren)
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the run fails", func() {
+			c.Expect(results.hasFailures()).IsTrue()
+			c.Expect(results).Matches(ReportIs(`
+- gospec.DummySpecWithTwoChildren
+  - Child A
+  - Child B
+- Run [FAIL]
+*** expected at least 3 specs, ran 2
+
+3 specs, 0 failures, 1 run errors
+`))
+		})
+	})
+	c.Specify("When enough specs were executed", func() {
+		runner := NewRunner()
+		runner.RequireMinSpecs(2)
+		runner.AddSpec(DummySpecWithTwoChildren)
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the run passes", func() {
+			c.Expect(results.hasFailures()).IsFalse()
+			c.Expect(len(results.RunErrors())).Equals(0)
+		})
+	})
+	c.Specify("By default there is no minimum number of required specs", func() {
+		runner := NewRunner()
+		runner.Run()
+		c.Expect(runner.Results().hasFailures()).IsFalse()
+	})
+
+	c.Specify("When a sub-runner is added", func() {
+		sub := NewRunner()
+		sub.AddSpec(DummySpecWithTwoChildren)
+		sub.AddSpec(DummySpecWithOneChild)
+		sub.AddNamedSpec("FailingSpec", func(c Context) {
+			c.Expect(1, Equals, 2)
+		})
+		runner := NewRunner()
+		runner.AddSubRunner("SubSuite", sub)
+		runner.AddSpec(DummySpecWithNoChildren)
+		runner.Run()
+
+		c.Specify("then its root specs are reported under one root spec", func() {
+			c.Expect(runner.Results()).Matches(ReportIs(`
+- SubSuite
+  - FailingSpec [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at results_test.go
+  - gospec.DummySpecWithOneChild
+    - Child A
+  - gospec.DummySpecWithTwoChildren
+    - Child A
+    - Child B
+- gospec.DummySpecWithNoChildren
+
+8 specs, 1 failures
+`))
+		})
+		c.Specify("then its specs are located where they were declared", func() {
+			locations := make(locationsByName)
+			runner.Results().Visit(locations)
+			c.Expect(locations["gospec.DummySpecWithOneChild"].FileName()).Equals("common_utils_test.go")
+			c.Expect(locations["gospec.DummySpecWithOneChild"].Line()).Equals(44)
+			c.Expect(locations["Child A"].FileName()).Equals("common_utils_test.go")
+		})
+	})
+
+	c.Specify("When a root spec is added for many implementations", func() {
+		runner := NewRunner()
+		impls := map[string]interface{}{"list": 2, "array": 1}
+		runner.AddSpecFor("ContractSpec", impls, func(c Context, impl interface{}) {
+			c.Specify("Child A", func() {
+				c.Expect(impl, Equals, 1)
+			})
+		})
+		runner.Run()
+
+		c.Specify("then every implementation is reported as its own root spec", func() {
+			c.Expect(runner.Results()).Matches(ReportIs(`
+- ContractSpec [array]
+  - Child A
+- ContractSpec [list]
+  - Child A [FAIL]
+*** Expected: equals “1”
+         got: “2”
+    at results_test.go
+
+4 specs, 1 failures
+`))
+		})
+		c.Specify("then the root specs are added in the order of the keys", func() {
+			c.Expect(len(runner.rootNames)).Equals(2)
+			c.Expect(runner.rootNames[0]).Equals("ContractSpec [array]")
+			c.Expect(runner.rootNames[1]).Equals("ContractSpec [list]")
+		})
+		c.Specify("then the root specs are declared where the body is", func() {
+			c.Expect(runner.roots[0].location.FileName()).Equals("results_test.go")
+		})
+	})
+
+	c.Specify("When measuring allocations", func() {
+		runner := NewRunner()
+		runner.SetMeasureAllocations(true)
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				allocationSink = make([]byte, 1000)
+			})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the allocations of leaf specs are recorded", func() {
+			root := results.rootsByName["RootSpec"]
+			leaf := root.children.Front().Value.(*specResult)
+			c.Expect(root.allocations == nil).IsTrue()
+			c.Expect(leaf.allocations.Count > 0).IsTrue()
+			c.Expect(leaf.allocations.Bytes >= 1000).IsTrue()
+		})
+	})
+	c.Specify("By default allocations are not measured", func() {
+		runner := NewRunner()
+		runner.AddSpec(DummySpecWithOneChild)
+		runner.Run()
+		root := runner.Results().rootsByName["gospec.DummySpecWithOneChild"]
+		leaf := root.children.Front().Value.(*specResult)
+		c.Expect(leaf.allocations == nil).IsTrue()
+	})
+
+	c.Specify("The locations where the specs were declared are recorded", func() {
+		runner := NewRunner()
+		runner.AddSpec(DummySpecWithOneChild)
+		runner.Run()
+		root := runner.Results().rootsByName["gospec.DummySpecWithOneChild"]
+		child := root.children.Front().Value.(*specResult)
+
+		c.Expect(root.location.FileName()).Equals("common_utils_test.go")
+		c.Expect(root.location.Line()).Equals(44)
+		c.Expect(child.location.FileName()).Equals("common_utils_test.go")
+		c.Expect(child.location.Line()).Equals(46)
+	})
+
+	c.Specify("When a root spec has a nil body", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("NilSpec", nil)
+		runner.AddSpec(DummySpecWithOneChild)
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then it is reported as a failure", func() {
+			c.Expect(results).Matches(ReportIs(`
+- NilSpec [FAIL]
+*** spec 'NilSpec' has a nil body
+    at results_test.go
+- gospec.DummySpecWithOneChild
+  - Child A
+
+3 specs, 1 failures
+`))
+		})
+		c.Specify("then the failure points to where the spec was added", func() {
+			err := results.rootsByName["NilSpec"].errors.Front().Value.(*Error)
+			c.Expect(err.StackTrace[0].FileName()).Equals("results_test.go")
+		})
+		c.Specify("then the other specs are still executed", func() {
+			c.Expect(results.PassCount()).Equals(2)
+			c.Expect(results.FailCount()).Equals(1)
+		})
+	})
+	c.Specify("When a nested spec has a nil body", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", nil)
+			c.Specify("Child B", func() {})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then it is reported as a failure", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Child A [FAIL]
+*** spec 'Child A' has a nil body
+    at results_test.go
+  - Child B
+
+3 specs, 1 failures
+`))
+		})
+	})
+
+	c.Specify("When specs are quarantined", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.QuarantineSpecify("Known bug", func() {
+				c.Expect(1, Equals, 2)
+				c.Specify("Child of known bug", func() {
+					c.Expect(3, Equals, 4)
+				})
+			})
+			c.QuarantineSpecify("Fixed bug", func() {
+				c.Specify("Child of fixed bug", func() {})
+			})
+			c.Specify("Normal spec", func() {})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then their failures are reported separately", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Normal spec
+- Quarantined: RootSpec > Known bug [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at results_test.go
+- Quarantined: RootSpec > Known bug > Child of known bug [FAIL]
+*** Expected: equals “4”
+         got: “3”
+    at results_test.go
+- Quarantined but passing: RootSpec > Fixed bug
+
+4 specs, 0 failures
+`))
+		})
+		c.Specify("then their failures are not counted as failures", func() {
+			c.Expect(results.FailCount()).Equals(0)
+			c.Expect(results.QuarantinedCount()).Equals(2)
+			c.Expect(results.hasFailures()).IsFalse()
+		})
+	})
+
+	c.Specify("The most deeply nested leaf specs can be printed", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child B", func() {
+				c.Specify("Child BA", func() {})
+			})
+			c.Specify("Child A", func() {
+				c.Specify("Child AA", func() {
+					c.Specify("Child AAA", func() {})
+				})
+				c.Specify("Child AB", func() {})
+			})
+			c.Specify("Child C", func() {})
+		})
+		runner.Run()
+		out := new(bytes.Buffer)
+		runner.Results().PrintDeepest(out, 3)
+
+		c.Expect(out.String()).Equals("" +
+			"\nDeepest specs:\n" +
+			"  1. RootSpec > Child A > Child AA > Child AAA (nesting level 3)\n" +
+			"  2. RootSpec > Child A > Child AB (nesting level 2)\n" +
+			"  3. RootSpec > Child B > Child BA (nesting level 2)\n")
+
+		c.Specify("and nothing is printed when no specs are asked for", func() {
+			out := new(bytes.Buffer)
+			runner.Results().PrintDeepest(out, 0)
+			runner.Results().PrintDeepest(out, -1)
+			c.Expect(out.String()).Equals("")
+		})
+	})
+
+	c.Specify("When specs are declared with a condition", func() {
+		executed := false
+		rootExecutions := 0
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			rootExecutions++
+			c.SpecifyIf(true, "", "Included spec", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.SpecifyIf(false, "", "Skipped spec", func() {
+				executed = true
+			})
+			c.SpecifyIf(false, "search is disabled", "Skipped spec with a reason", func() {
+				executed = true
+			})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the skipped specs are reported with the reason, without executing them", func() {
+			c.Expect(results).Matches(ReportIs(`
+- RootSpec
+  - Included spec [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at results_test.go
+  - Skipped spec [SKIPPED: condition not met]
+  - Skipped spec with a reason [SKIPPED: search is disabled]
+
+2 specs, 1 failures
+`))
+			c.Expect(executed).IsFalse()
+		})
+		c.Specify("then the skipped specs are counted separately", func() {
+			c.Expect(results.SkippedCount()).Equals(2)
+			c.Expect(results.TotalCount()).Equals(2)
+		})
+		c.Specify("then the skipped specs get no tasks which would execute their parents again", func() {
+			c.Expect(rootExecutions).Equals(1)
+		})
+	})
+
+	c.Specify("When expectations are recorded", func() {
+		runner := NewRunner()
+		runner.RecordExpectations()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 1)
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Child B", func() {
+				c.Assume(1, IsWithin(0.1), "x")
+			})
+		})
+		runner.Run()
+		expectations := make(map[string][]*Expectation)
+		runner.Results().visitAll(func(spec *specResult) {
+			expectations[spec.name] = spec.expectations
+		})
+
+		c.Specify("then both the passing and the failing expectations are recorded", func() {
+			c.Expect(len(expectations["RootSpec"])).Equals(1)
+			c.Expect(*expectations["RootSpec"][0]).Equals(Expectation{true, "“1” equals “1”", expectations["RootSpec"][0].Location})
+			c.Expect(len(expectations["Child A"])).Equals(1)
+			c.Expect(expectations["Child A"][0].Passed).IsFalse()
+			c.Expect(expectations["Child A"][0].Description).Equals("“1” equals “2”")
+			c.Expect(expectations["Child A"][0].Location.FileName()).Equals("results_test.go")
+		})
+		c.Specify("then the errors of the matchers are recorded as failing", func() {
+			c.Expect(len(expectations["Child B"])).Equals(1)
+			c.Expect(expectations["Child B"][0].Passed).IsFalse()
+			c.Expect(expectations["Child B"][0].Description).Equals("type error: expected a float, but was “1” of type “int”")
+		})
+	})
+	c.Specify("When expectations are recorded, the matchers need no message for passing", func() {
+		isPositive := func(actual interface{}, _ interface{}) (match bool, pos Message, neg Message, err error) {
+			return actual.(int) > 0, nil, nil, nil
+		}
+		runner := NewRunner()
+		runner.RecordExpectations()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, isPositive)
+		})
+		runner.Run()
+
+		c.Expect(runner.Results()).Matches(ReportContains("1 specs, 0 failures"))
+		runner.Results().visitAll(func(spec *specResult) {
+			c.Expect(len(spec.expectations)).Equals(1)
+			c.Expect(spec.expectations[0].Description).Equals("“1” passed")
+		})
+	})
+	c.Specify("By default the expectations are not recorded", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 1)
+		})
+		runner.Run()
+		runner.Results().visitAll(func(spec *specResult) {
+			c.Expect(spec.expectations == nil).IsTrue()
+		})
+	})
+
+	c.Specify("When more specs were skipped than are allowed", func() {
+		runner := NewRunner()
+		runner.SetMaxSkipped(1)
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.SpecifyIf(false, "", "Skipped A", func() {})
+			c.Specify("Child", func() {
+				c.SpecifyIf(false, "", "Skipped B", func() {})
+			})
+		})
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the run fails and lists the skipped specs", func() {
+			c.Expect(results.hasFailures()).IsTrue()
+			c.Expect(results).Matches(ReportContains("" +
+				"- Run [FAIL]\n" +
+				"*** expected at most 1 skipped specs, skipped 2:\n" +
+				"  - RootSpec > Skipped A\n" +
+				"  - RootSpec > Child > Skipped B\n"))
+		})
+	})
+	c.Specify("When no more specs were skipped than are allowed", func() {
+		runner := NewRunner()
+		runner.SetMaxSkipped(1)
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.SpecifyIf(false, "", "Skipped A", func() {})
+		})
+		runner.Run()
+		c.Expect(len(runner.Results().RunErrors())).Equals(0)
+	})
+	c.Specify("By default any number of specs may be skipped", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.SpecifyIf(false, "", "Skipped A", func() {})
+			c.SpecifyIf(false, "", "Skipped B", func() {})
+		})
+		runner.Run()
+		c.Expect(runner.Results().hasFailures()).IsFalse()
+	})
+
+	c.Specify("When root specs are reported in declaration order", func() {
+		runner := NewRunner()
+		runner.SetRootOrder(DeclarationOrder)
+		runner.AddNamedSpec("Zebra", DummySpecWithTwoChildren)
+		runner.AddNamedSpec("Aardvark", DummySpecWithOneChild)
+		runner.Run()
+		results := runner.Results()
+
+		c.Specify("then the roots are in the order they were added", func() {
+			c.Expect(results).Matches(ReportIs(`
+- Zebra
+  - Child A
+  - Child B
+- Aardvark
+  - Child A
+
+5 specs, 0 failures
+`))
+		})
+		c.Specify("then the paths of the specs are the same as in alphabetical order", func() {
+			zebra := results.rootsByName["Zebra"]
+			childB := zebra.children.Back().Value.(*specResult)
+			c.Expect(childB.path.isEqual(path{1})).IsTrue()
+		})
+		c.Specify("then the order is part of the configuration", func() {
+			out := new(bytes.Buffer)
+			results.PrintConfig(out)
+			c.Expect(strings.Contains(out.String(), "root order: declaration\n")).IsTrue()
+		})
+	})
+
+	c.Specify("When specs have metadata", func() {
+		runner := NewRunner()
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Meta("owner", "team-a")
+			c.Meta("category", "slow")
+			c.Specify("Child A", func() {
+				c.Meta("owner", "team-b")
+			})
+			c.Specify("Child B", func() {})
+		})
+		runner.Run()
+		spy := &detailsSpy{make(map[string]*SpecDetails)}
+		runner.Results().Visit(spy)
+
+		c.Specify("then the metadata is merged from the parents", func() {
+			c.Expect(spy.details["Child A"].Metadata["owner"]).Equals("team-b")
+			c.Expect(spy.details["Child A"].Metadata["category"]).Equals("slow")
+			c.Expect(spy.details["Child B"].Metadata["owner"]).Equals("team-a")
+			c.Expect(spy.details["RootSpec"].Metadata["owner"]).Equals("team-a")
+		})
+		c.Specify("then the metadata is not printed", func() {
+			c.Expect(runner.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A
+  - Child B
+
+3 specs, 0 failures
+`))
+		})
+	})
+
+	c.Specify("When a name formatter is used", func() {
+		dir, _ := ioutil.TempDir("", "gospec")
+		defer os.RemoveAll(dir)
+		failures := filepath.Join(dir, "failures.txt")
+
+		runner := NewRunner()
+		runner.SetNameFormatter(strings.ToUpper)
+		runner.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 2)
+			})
+			c.Specify("Child B", func() {})
+		})
+		runner.Run()
+		runner.WriteFailuresFile(failures)
+		results := runner.Results()
+
+		c.Specify("then the reported names are formatted", func() {
+			c.Expect(results).Matches(ReportIs(`
+- ROOTSPEC
+  - CHILD A [FAIL]
+*** Expected: equals “2”
+         got: “1”
+    at results_test.go
+  - CHILD B
+
+3 specs, 1 failures
+`))
+		})
+		c.Specify("then the specs are still identified by their original names", func() {
+			data, _ := ioutil.ReadFile(failures)
+			c.Expect(string(data)).Equals("RootSpec > Child A\n")
+		})
+	})
+}
+
+// Records the SpecDetails of every visited spec by its name.
+type detailsSpy struct {
+	details map[string]*SpecDetails
+}
+
+func (this *detailsSpy) VisitSpec(nestingLevel int, name string, errors []*Error) {}
+func (this *detailsSpy) VisitEnd(passCount int, failCount int)                    {}
+func (this *detailsSpy) VisitSpecDetails(nestingLevel int, spec *SpecDetails) {
+	this.details[spec.Name] = spec
 }
 
 func ReportIs(expected string) nanospec.Matcher {
@@ -348,3 +873,14 @@ func resultToString(result *ResultCollector) string {
 	result.Visit(NewPrinter(SimplePrintFormat(out)))
 	return out.String()
 }
+
+var allocationSink []byte
+
+// Collects the declaration locations of the specs.
+type locationsByName map[string]*Location
+
+func (this locationsByName) VisitSpec(nestingLevel int, name string, errors []*Error) {}
+func (this locationsByName) VisitEnd(passCount int, failCount int)                    {}
+func (this locationsByName) VisitSpecDetails(nestingLevel int, spec *SpecDetails) {
+	this[spec.Name] = spec.Location
+}
@@ -4,16 +4,88 @@
 
 package gospec
 
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
 const (
 	channelBufferSize = 10
+
+	// High enough for any hand-written specs, but low enough
+	// to stop runaway recursion in spec generators.
+	defaultMaxNestingDepth = 1000
 )
 
 // Runner executes the specs and collects their results.
 type Runner struct {
-	runningTasks int
-	results      chan *taskResult
-	executed     []*specRun
-	scheduled    []*scheduledTask
+	runningTasks  int
+	finishedTasks int
+	results       chan *taskResult
+	executed      []*specRun
+	scheduled     []*scheduledTask
+	listeners     []runListener
+	minSpecs      int
+	maxSkipped    int
+	updateGolden  bool
+	serial        bool
+	measureAllocs bool
+	scheduler     Scheduler
+	rootOrder     RootOrder
+	rootNames     []string
+	randomSeed    int64
+	usedRandom    bool
+	filter        *specFilter
+	order         *executionOrderRecorder
+	nameFormatter func(name string) string
+	fixtures      *fixtureCache
+	runErrors     []*Error
+	deadline      time.Duration
+	expectations  bool
+	executing     *executingTasks
+	maxFailures   int
+	failures      int
+	maxDepth      int
+	stream        *reportStreamer
+	runCount      int
+	repetition    int
+	flakiness     *flakinessRecorder
+	duration      time.Duration
+	roots         []*scheduledTask
+	failOnIssues  bool
+	serialRoots   map[string]bool
+	runningSerial bool
+	runContext    context.Context
+	failOnLog     bool
+	failLogLevel  slog.Level
+}
+
+// The order in which the root specs are reported.
+type RootOrder int
+
+const (
+	// Sorted by the names of the root specs. This is the default.
+	AlphabeticalOrder RootOrder = iota
+
+	// The same order as in which the root specs were added to the Runner.
+	DeclarationOrder
+)
+
+func (order RootOrder) String() string {
+	switch order {
+	case AlphabeticalOrder:
+		return "alphabetical"
+	case DeclarationOrder:
+		return "declaration"
+	}
+	return fmt.Sprintf("RootOrder(%d)", int(order))
 }
 
 func NewRunner() *Runner {
@@ -22,41 +94,472 @@ func NewRunner() *Runner {
 	r.results = make(chan *taskResult, channelBufferSize)
 	r.executed = make([]*specRun, 0)
 	r.scheduled = make([]*scheduledTask, 0)
+	r.listeners = make([]runListener, 0)
+	r.scheduler = parallelScheduler{}
+	r.rootOrder = AlphabeticalOrder
+	r.rootNames = make([]string, 0)
+	r.randomSeed = time.Now().UnixNano()
+	r.nameFormatter = IdentityNameFormatter
+	r.fixtures = newFixtureCache()
+	r.runErrors = make([]*Error, 0)
+	r.maxDepth = defaultMaxNestingDepth
+	r.runCount = 1
+	r.maxSkipped = -1
+	r.executing = newExecutingTasks()
+	r.serialRoots = make(map[string]bool)
+	r.failLogLevel = slog.LevelError
 	return r
 }
 
+// Sets the seed from which the random sources of the specs are seeded,
+// so that the randomness of a failed run can be repeated. By default the
+// seed is based on the current time. See Context.Rand.
+func (r *Runner) SetRandomSeed(seed int64) {
+	r.randomSeed = seed
+}
+
+// Sets the order in which the root specs are reported. The default
+// is AlphabeticalOrder. The children of the root specs are always
+// reported in the order in which they were declared.
+func (r *Runner) SetRootOrder(order RootOrder) {
+	r.rootOrder = order
+}
+
+// Sets the function which transforms the names of the specs in the report,
+// for example to make code-friendly names more readable. The default is
+// IdentityNameFormatter. Only the reported names are transformed; the specs
+// are still identified by their original names, for example in the failures
+// file (see WriteFailuresFile).
+func (r *Runner) SetNameFormatter(formatter func(name string) string) {
+	r.nameFormatter = formatter
+}
+
+// Reports the names of the specs as they were declared.
+func IdentityNameFormatter(name string) string {
+	return name
+}
+
+// Replaces the default Scheduler, which executes every spec task right away
+// in its own goroutine. See Scheduler for the contract which it must uphold.
+func (r *Runner) SetScheduler(scheduler Scheduler) {
+	r.scheduler = scheduler
+}
+
+// Prints to 'out' how many of the specs have been executed and how long
+// the remaining specs are estimated to take. The progress is printed at
+// most once per 'interval'. Nothing is printed if 'out' is not a terminal,
+// so that the progress lines will not clutter logs.
+func (r *Runner) ShowProgress(out io.Writer, interval time.Duration) {
+	if isTerminal(out) {
+		r.addListener(newProgressReporter(out, interval, time.Now))
+	}
+}
+
+// Adds the specs of another Runner as the children of one root spec with
+// the given name, so that suites can be composed of smaller suites. The specs
+// of the sub-runner are executed by this Runner in the same way as all other
+// specs; the sub-runner's own settings are not used and it does not need to
+// be run separately.
+func (r *Runner) AddSubRunner(name string, sub *Runner) {
+	tasks := make([]*scheduledTask, len(sub.scheduled))
+	copy(tasks, sub.scheduled)
+	sort.Sort(byTaskName(tasks))
+
+	r.AddNamedSpec(name, func(c Context) {
+		// The specs are reported where they were declared, not here.
+		for _, task := range tasks {
+			c.(*taskContext).specifyAt(task.location, task.name, rootSpecBody(task.closure, c))
+		}
+	})
+}
+
+// When true, only one spec is executed at a time. Useful for specs which
+// are not safe to execute in parallel and for measurements which would be
+// disturbed by other specs executing at the same time.
+func (r *Runner) SetSerial(serial bool) {
+	r.serial = serial
+}
+
+// When true, the number of heap allocations is measured for every leaf spec.
+// The measurement includes also the allocations of the leaf's parent specs,
+// because they are executed together with the leaf. The numbers are only
+// approximate, because they include also the allocations of all other
+// goroutines, such as other specs and the garbage collector, so for more
+// accurate numbers the specs should be executed serially (see SetSerial).
+func (r *Runner) SetMeasureAllocations(measure bool) {
+	r.measureAllocs = measure
+}
+
+// Writes every failure to 'out' as soon as it happens, with the names of the
+// failing spec and its parents, in addition to the report which is produced
+// after all specs have been executed. Useful when a run might be killed before
+// it finishes. The failures are written from the goroutine which called Run,
+// so there will be no concurrent writes to 'out'.
+func (r *Runner) SetFailureStream(out io.Writer) {
+	r.addListener(newFailureStreamer(out))
+}
+
+// When true, the MatchesGolden matcher will write the actual values to
+// the golden files instead of comparing them, so that the golden files
+// can be created and updated. The setting affects only the specs of this
+// Runner.
+func (r *Runner) SetUpdateGolden(update bool) {
+	r.updateGolden = update
+}
+
+// Stops the run when it has taken longer than 'd', so that a run which
+// hangs will not block the build forever. Then no more specs are started
+// and the specs which are still executing are abandoned. The report contains
+// the results of the specs which finished, and the run fails with an error
+// which tells how many specs were not executed, and for every abandoned spec
+// an error with the stack of the goroutine which was executing it, to show
+// where it was stuck. The root specs which were not started at all are
+// reported as skipped. The default is 0, which means that there is no deadline.
+//
+// The abandoned specs cannot be stopped, so they keep executing in the
+// background. The fixtures of Context.Once are closed only after they have
+// finished, and then the errors of closing them are not reported. If
+// an abandoned spec is stuck while it has changed the environment with
+// Context.Setenv or captures the logs with Context.CaptureSlog, the specs of
+// the later runs which do the same will wait until it is no longer stuck,
+// because only one spec at a time can do those.
+func (r *Runner) SetDeadline(d time.Duration) {
+	r.deadline = d
+}
+
+// Stops the run when 'k' leaf specs have failed, so that a change which
+// breaks everything will not waste time on executing hundreds of failing
+// specs. A leaf spec fails also when one of its parents fails, because they
+// are executed together; failures of quarantined specs are not counted.
+// When the limit is reached, no more specs are started, but the specs which
+// are already executing in parallel are allowed to finish and are reported,
+// so the report may contain more than 'k' failures. The run fails with an
+// error which tells how many specs were not executed. The default is 0,
+// which means that there is no limit.
+func (r *Runner) SetMaxFailures(k int) {
+	r.maxFailures = k
+}
+
+// Sets how deeply the specs may be nested. A spec which is nested deeper than
+// 'depth' levels below its root spec fails without being executed, so that
+// a spec generator which recurses endlessly will not exhaust the memory.
+// The default is 1000.
+func (r *Runner) SetMaxNestingDepth(depth int) {
+	r.maxDepth = depth
+}
+
+// Executes all the specs 'n' times, to find flaky specs which fail only in
+// some of the runs. Every run gets a different random seed for Context.Rand,
+// derived from the seed of the whole run (see SetRandomSeed). The report
+// contains the failures of all the runs, and ResultCollector.PrintFlakiness
+// tells for every leaf spec in how many runs it failed. When the report is
+// streamed (see StreamReport), only the first run is streamed. The default
+// is 1. A count less than 1 is reported as an error of the run, and the
+// specs are run once.
+func (r *Runner) SetRunCount(n int) {
+	if n < 1 {
+		message := fmt.Sprintf("the run count must be at least 1, but was %v", n)
+		r.runErrors = append(r.runErrors, newError(OtherError, message, "", []*Location{}))
+		return
+	}
+	r.runCount = n
+	if n > 1 && r.flakiness == nil {
+		r.flakiness = newFlakinessRecorder()
+		r.addListener(r.flakiness)
+	}
+}
+
+// Makes the run fail if fewer than 'n' leaf specs were executed. This guards
+// against misconfigured suites, where no specs have been added and the run
+// would otherwise pass silently. The default is 0, which disables the check.
+func (r *Runner) RequireMinSpecs(n int) {
+	r.minSpecs = n
+}
+
+// Makes the run fail if more than 'n' specs were skipped, because they were
+// declared with Context.SpecifyIf and their condition was false, so that
+// the specs which have been disabled will not silently accumulate. The
+// failure lists the skipped specs. The default is -1, which means that
+// there is no limit.
+func (r *Runner) SetMaxSkipped(n int) {
+	r.maxSkipped = n
+}
+
+// Records every expectation and assumption which the specs evaluate, also
+// the passing ones, so that they can be printed with Printer.ShowExpectations
+// to see what the specs check. By default only the failures are recorded,
+// because recording everything makes the specs slower and the results
+// bigger.
+func (r *Runner) RecordExpectations() {
+	r.expectations = true
+}
+
+// When true, a leaf spec fails if it or its parents captured the log records
+// with Context.CaptureSlog and an error was logged while they were captured,
+// so that errors which the code under test only logs are not missed. The
+// failure tells the record and where it was logged. By default the records
+// with level slog.LevelError and above are errors, see SetFailingLogLevel.
+func (r *Runner) SetFailOnErrorLog(fail bool) {
+	r.failOnLog = fail
+}
+
+// Sets the lowest level of the log records which fail the specs when
+// SetFailOnErrorLog is true. The default is slog.LevelError. For example
+// slog.LevelWarn fails the specs also because of warnings.
+func (r *Runner) SetFailingLogLevel(level slog.Level) {
+	r.failLogLevel = level
+}
+
+// Records the order in which the leaf specs are executed and how long each
+// of them took, so that the order can be printed after the run with
+// ResultCollector.PrintExecutionOrder. Useful for debugging specs which
+// fail only when executed in some particular order.
+func (r *Runner) RecordExecutionOrder() {
+	if r.order == nil {
+		r.order = new(executionOrderRecorder)
+		r.addListener(r.order)
+	}
+}
+
+// Visits the results with 'visitor' already during Run, instead of after it
+// with Results().Visit, so that the report of a long run can be followed
+// as it progresses. The report is in the same order as when it is visited
+// after the run: a root spec is visited when all of its specs have been
+// executed and all the root specs before it in the report have been visited.
+// The quarantined specs, the run errors and the end are visited at the end
+// of Run. By default nothing is visited during Run.
+func (r *Runner) StreamReport(visitor ResultVisitor) {
+	r.stream = newReportStreamer(visitor)
+	r.addListener(r.stream)
+}
+
+func (r *Runner) addListener(listener runListener) {
+	r.listeners = append(r.listeners, listener)
+}
+
 // Adds a spec for later execution. Example:
 //     r.AddSpec(SomeSpec);
 func (r *Runner) AddSpec(closure func(Context)) {
-	r.AddNamedSpec(functionName(closure), closure)
+	r.addSpec(callerLocation(), functionName(closure), closure)
 }
 
 // Adds a spec for later execution. Uses the provided name instead of
 // retrieving the name of the spec function with reflection.
 func (r *Runner) AddNamedSpec(name string, closure func(Context)) {
-	task := newScheduledTask(name, closure, newInitialContext())
+	r.addSpec(callerLocation(), name, closure)
+}
+
+// Adds a root spec which is executed in isolation, while the other specs are
+// executed in parallel. None of its specs are started while any other spec
+// is executing, and no other spec is started while any of its specs is
+// executing, so also its own specs are executed one at a time. Useful for
+// specs which use some shared external resource. See also SetSerial.
+func (r *Runner) AddSerialSpec(name string, closure func(Context)) {
+	r.addSpec(callerLocation(), name, closure)
+	r.serialRoots[name] = true
+}
+
+// Adds one root spec for every implementation of a contract, so that the same
+// spec can be executed against all of them. The root specs are named
+// "name [key]", in the order of the keys, and the implementation is passed
+// to the body. Each root spec is executed and reported like any other root
+// spec. The same implementation is passed to every execution of the body,
+// so it must not keep state between the specs; to get a new one for every
+// spec, pass constructors as the implementations. Example:
+//    r.AddSpecFor("StackSpec", map[string]interface{}{
+//        "array": NewArrayStack,
+//        "list":  NewListStack,
+//    }, func(c gospec.Context, impl interface{}) {
+//        StackContract(c, impl.(func() Stack)())
+//    })
+func (r *Runner) AddSpecFor(name string, impls map[string]interface{}, body func(Context, interface{})) {
+	// Like for addSpec, a nil body is reported where it was added.
+	location := callerLocation()
+	if body != nil {
+		location = functionLocation(body)
+	}
+	keys := make([]string, 0, len(impls))
+	for key := range impls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var closure specRoot
+		if body != nil {
+			impl := impls[key]
+			closure = func(c Context) { body(c, impl) }
+		}
+		r.addSpecAt(location, fmt.Sprintf("%v [%v]", name, key), closure)
+	}
+}
+
+func (r *Runner) addSpec(registeredAt *Location, name string, closure specRoot) {
+	// A nil spec has no declaration, so it is reported where it was added.
+	location := registeredAt
+	if closure != nil {
+		location = functionLocation(closure)
+	}
+	r.addSpecAt(location, name, closure)
+}
+
+func (r *Runner) addSpecAt(location *Location, name string, closure specRoot) {
+	task := newScheduledTask(name, closure, location, newInitialContext())
 	r.scheduled = append(r.scheduled, task)
+	r.rootNames = append(r.rootNames, name)
 }
 
 // Executes all the specs which have been added with AddSpec. The specs
 // are executed using as many goroutines as possible, so that even individual
 // spec methods are executed in multiple goroutines.
 func (r *Runner) Run() {
-	r.startAllScheduledTasks()
-	r.startNewTasksAndWaitUntilFinished()
+	start := time.Now()
+	// The contexts of the specs are derived from the context of the run,
+	// and the same context tells when the deadline is reached, so that they
+	// are cancelled with context.DeadlineExceeded. The contexts are cancelled
+	// also when the run finishes, also those which are abandoned.
+	var deadline <-chan struct{}
+	var cancel context.CancelFunc
+	if r.deadline > 0 {
+		r.runContext, cancel = context.WithDeadline(context.Background(), start.Add(r.deadline))
+		deadline = r.runContext.Done()
+	} else {
+		r.runContext, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	r.removeFilteredTasks()
+	if r.stream != nil {
+		r.stream.start(r.scheduled, r.rootOrder, r.nameFormatter)
+	}
+	roots := make([]*scheduledTask, len(r.scheduled))
+	copy(roots, r.scheduled)
+	r.roots = roots
+	for r.repetition = 0; r.repetition < r.runCount; r.repetition++ {
+		if r.repetition > 0 {
+			r.scheduleAgain(roots)
+		}
+		r.startAllScheduledTasks()
+		if !r.startNewTasksAndWaitUntilFinished(deadline) {
+			r.abandonUnfinishedTasks(time.Since(start))
+			break
+		}
+		if r.tooManyFailures() {
+			r.abortScheduledTasks(len(roots))
+			break
+		}
+	}
+	r.runErrors = append(r.runErrors, r.fixtures.close()...)
+	if r.failOnIssues {
+		r.addValidationIssues()
+	}
+	r.duration = time.Since(start)
+	if r.stream != nil {
+		r.stream.finish(r.Results())
+	}
+}
+
+func (r *Runner) scheduleAgain(roots []*scheduledTask) {
+	for _, task := range roots {
+		r.scheduled = append(r.scheduled, newScheduledTask(task.name, task.closure, task.location, newInitialContext()))
+	}
+}
+
+func (r *Runner) removeFilteredTasks() {
+	allowed := make([]*scheduledTask, 0, len(r.scheduled))
+	for _, task := range r.scheduled {
+		if r.filter.allowsNames([]string{task.name}) {
+			allowed = append(allowed, task)
+		}
+	}
+	r.scheduled = allowed
 }
 
 func (r *Runner) startAllScheduledTasks() {
-	for r.hasScheduledTasks() {
+	for r.canStartNextTask() {
 		r.startNextScheduledTask()
 	}
 }
 
-func (r *Runner) startNewTasksAndWaitUntilFinished() {
+func (r *Runner) canStartNextTask() bool {
+	if !r.hasScheduledTasks() || r.tooManyFailures() {
+		return false
+	}
+	if !r.hasRunningTasks() {
+		return true
+	}
+	// The next task waits for the running tasks to finish,
+	// so that the serial specs are not starved.
+	return !r.serial && !r.runningSerial && !r.serialRoots[r.scheduled[len(r.scheduled)-1].name]
+}
+
+// Returns false if the deadline was reached before all tasks finished.
+func (r *Runner) startNewTasksAndWaitUntilFinished(deadline <-chan struct{}) bool {
 	for r.hasRunningTasks() {
-		r.processNextFinishedTask()
+		select {
+		case result := <-r.results:
+			r.processFinishedTask(result)
+		case <-deadline:
+			return false
+		}
 		r.startAllScheduledTasks()
 	}
+	return true
+}
+
+func (r *Runner) abandonUnfinishedTasks(elapsed time.Duration) {
+	// Every task executes at least one leaf spec, and the scheduled tasks
+	// may discover more of them, so this is the minimum number of specs.
+	notExecuted := r.runningTasks + len(r.scheduled)
+	message := fmt.Sprintf("the run was stopped after %v because of the deadline of %v, at least %v specs were not executed",
+		elapsed, r.deadline, notExecuted)
+	r.runErrors = append(r.runErrors, newError(OtherError, message, "", []*Location{}))
+	r.runErrors = append(r.runErrors, r.executing.stuckErrors()...)
+	if r.repetition == 0 {
+		r.skipNotStartedRoots("not executed because of the deadline")
+	}
+	r.runningTasks = 0
+	r.runningSerial = false
+	r.scheduled = r.scheduled[:0]
+	// The abandoned tasks must not be mixed with the tasks of a later run,
+	// and their fixtures must not be closed while they may still use them.
+	r.results = make(chan *taskResult, channelBufferSize)
+	executing, fixtures := r.executing, r.fixtures
+	go func() {
+		executing.wait()
+		fixtures.close()
+	}()
+	r.executing = newExecutingTasks()
+	r.fixtures = newFixtureCache()
+}
+
+// Reports the root specs, none of whose specs were started, as skipped.
+// The other scheduled tasks would execute the remaining children of the root
+// specs which have already been reported, so they are only counted.
+func (r *Runner) skipNotStartedRoots(reason string) {
+	for i := len(r.scheduled) - 1; i >= 0; i-- {
+		task := r.scheduled[i]
+		if task.context.targetPath.isRoot() {
+			spec := newSpecRun(task.name, nil, nil, rootPath())
+			spec.location = task.location
+			spec.skipReason = reason
+			r.executed = append(r.executed, spec)
+		}
+	}
+}
+
+func (r *Runner) tooManyFailures() bool {
+	return r.maxFailures > 0 && r.failures >= r.maxFailures
+}
+
+func (r *Runner) abortScheduledTasks(rootCount int) {
+	// Like with the deadline, this is the minimum number of specs. Also
+	// the later runs of SetRunCount will not be started at all.
+	notExecuted := len(r.scheduled) + (r.runCount-1-r.repetition)*rootCount
+	if notExecuted > 0 {
+		message := fmt.Sprintf("the run was aborted after %v failures, at least %v specs were not executed",
+			r.failures, notExecuted)
+		r.runErrors = append(r.runErrors, newError(OtherError, message, "", []*Location{}))
+	}
+	r.scheduled = r.scheduled[:0]
 }
 
 // For testing purposes, so that the specs can be executed deterministically.
@@ -67,16 +570,59 @@ func (r *Runner) executeNextScheduledTask() {
 
 func (r *Runner) startNextScheduledTask() {
 	task := r.nextScheduledTask()
-	go func() {
-		r.results <- r.execute(task.name, task.closure, task.context)
-	}()
+	path := make([]int, len(task.context.targetPath))
+	copy(path, task.context.targetPath)
 	r.runningTasks++
+	r.runningSerial = r.serialRoots[task.name]
+	task.context.repetition = r.repetition
+	// These are replaced when the tasks are abandoned at the deadline,
+	// so they are read by this goroutine, and not by the task.
+	task.context.runContext = r.runContext
+	task.context.fixtures = r.fixtures
+	task.context.executing = r.executing
+	executing := r.executing
+	executing.running.Add(1)
+	results := r.results
+	r.scheduler.Schedule(&SpecTask{task.name, path, func() {
+		defer executing.running.Done()
+		sendResult(results, r.execute(task.name, task.closure, task.location, task.context))
+	}})
+}
+
+func sendResult(results chan<- *taskResult, result *taskResult) {
+	select {
+	case results <- result:
+	default:
+		// Schedulers may execute the tasks in the same goroutine
+		// which calls Schedule, so this must not block.
+		go func() { results <- result }()
+	}
 }
 
 func (r *Runner) processNextFinishedTask() {
-	result := <-r.results
+	r.processFinishedTask(<-r.results)
+}
+
+func (r *Runner) processFinishedTask(result *taskResult) {
 	r.runningTasks--
+	if r.runningTasks == 0 {
+		r.runningSerial = false
+	}
+	r.finishedTasks++
 	r.saveResult(result)
+	if result.failed() {
+		r.failures++
+	}
+	r.notifyTaskFinished(result)
+}
+
+func (r *Runner) notifyTaskFinished(result *taskResult) {
+	// Every task executes exactly one leaf spec, so the number of all tasks
+	// is the same as the number of all specs which have been discovered so far.
+	total := r.finishedTasks + r.runningTasks + len(r.scheduled)
+	for _, listener := range r.listeners {
+		listener.taskFinished(result, r.finishedTasks, total)
+	}
 }
 
 func (r *Runner) hasRunningTasks() bool   { return r.runningTasks > 0 }
@@ -88,22 +634,66 @@ func (r *Runner) nextScheduledTask() *scheduledTask {
 	return popped
 }
 
-func (r *Runner) execute(name string, closure specRoot, c *taskContext) *taskResult {
-	c.Specify(name, func() { closure(c) })
+func (r *Runner) execute(name string, closure specRoot, location *Location, c *taskContext) *taskResult {
+	var before runtime.MemStats
+	if r.measureAllocs {
+		runtime.ReadMemStats(&before)
+	}
+	goroutine := c.executing.started(name)
+	defer c.executing.finished(goroutine)
+	c.rootName = name
+	c.randomSeed = r.randomSeed + int64(c.repetition)
+	c.filter = r.filter
+	c.maxDepth = r.maxDepth
+	c.recordExpectations = r.expectations
+	c.failOnLog = r.failOnLog
+	c.failingLogLevel = r.failLogLevel
+	c.updateGolden = r.updateGolden
+	start := time.Now()
+	c.specifyAt(location, name, rootSpecBody(closure, c))
+	c.runCleanups()
+	c.cancel()
+	duration := time.Since(start)
+	leaf := c.executedSpecs.Back().Value.(*specRun)
+	leaf.duration = duration
+	if r.measureAllocs {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		leaf.allocations = &Allocations{after.Mallocs - before.Mallocs, after.TotalAlloc - before.TotalAlloc}
+	}
 	return &taskResult{
 		name,
 		closure,
+		location,
 		asSpecArray(c.executedSpecs),
 		asSpecArray(c.postponedSpecs),
+		c.usedRandom(),
+		duration,
+		asSpecArray(c.skippedSpecs),
 	}
 }
 
+// The path of a spec stays the same when other specs are added to the
+// filter or removed from it, so seeding the spec's random source from its
+// path makes the specs repeatable.
+func specSeed(runSeed int64, rootName string, specPath path) int64 {
+	h := fnv.New64a()
+	fmt.Fprint(h, runSeed, rootName, specPath)
+	return int64(h.Sum64())
+}
+
 func (r *Runner) saveResult(result *taskResult) {
+	if result.usedRandom {
+		r.usedRandom = true
+	}
 	for _, spec := range result.executedSpecs {
 		r.executed = append(r.executed, spec)
 	}
+	for _, spec := range result.skippedSpecs {
+		r.executed = append(r.executed, spec)
+	}
 	for _, spec := range result.postponedSpecs {
-		task := newScheduledTask(result.name, result.closure, newExplicitContext(spec.path))
+		task := newScheduledTask(result.name, result.closure, result.location, newExplicitContext(spec.path))
 		r.scheduled = append(r.scheduled, task)
 	}
 }
@@ -115,30 +705,201 @@ func (r *Runner) Results() *ResultCollector {
 	// get ready (the channel should be buffered). When all is done, the runner
 	// will get the result collector from a result channel.
 
+	// The specs are given to the result collector in the order in which
+	// they would be executed by a single goroutine, so that the errors of
+	// parent specs (which are executed by multiple tasks) will be reported
+	// in the same order regardless of the order in which the tasks finished.
+	executed := make([]*specRun, len(r.executed))
+	copy(executed, r.executed)
+	sort.Stable(byExecutionOrder(executed))
+
 	results := newResultCollector()
-	for _, spec := range r.executed {
+	results.nameFormatter = r.nameFormatter
+	for _, spec := range executed {
 		results.Update(spec)
 	}
+	if r.rootOrder == DeclarationOrder {
+		results.orderRoots(r.rootNames)
+	}
+	if r.usedRandom {
+		results.recordRandomSeed(r.randomSeed)
+	}
+	results.recordConfig(r.config())
+	results.recordDuration(r.duration)
+	if r.order != nil {
+		results.recordExecutionOrder(r.order.leaves)
+	}
+	if r.flakiness != nil {
+		results.recordFlakiness(r.runCount, r.flakiness.sortedLeaves())
+	}
+	for _, error := range r.runErrors {
+		results.addRunError(error)
+	}
+	r.checkMinSpecs(results)
+	r.checkMaxSkipped(results)
 	return results
 }
 
+func (r *Runner) checkMinSpecs(results *ResultCollector) {
+	// Every task executes exactly one leaf spec.
+	leafCount := r.finishedTasks
+	if r.runCount > 1 {
+		leafCount /= r.runCount
+	}
+	if leafCount < r.minSpecs {
+		message := fmt.Sprintf("expected at least %v specs, ran %v", r.minSpecs, leafCount)
+		results.addRunError(newError(OtherError, message, "", []*Location{}))
+	}
+}
+
+func (r *Runner) checkMaxSkipped(results *ResultCollector) {
+	if r.maxSkipped < 0 || results.SkippedCount() <= r.maxSkipped {
+		return
+	}
+	message := fmt.Sprintf("expected at most %v skipped specs, skipped %v:", r.maxSkipped, results.SkippedCount())
+	results.visitAllWithNames(func(names []string, spec *specResult) {
+		if spec.skipReason != "" {
+			message += "\n  - " + strings.Join(results.formatNames(names), " > ")
+		}
+	})
+	results.addRunError(newError(OtherError, message, "", []*Location{}))
+}
+
+func (r *Runner) config() []configEntry {
+	config := []configEntry{
+		{"parallelism", runtime.GOMAXPROCS(0)},
+	}
+	if r.minSpecs > 0 {
+		config = append(config, configEntry{"min specs", r.minSpecs})
+	}
+	if r.maxSkipped >= 0 {
+		config = append(config, configEntry{"max skipped", r.maxSkipped})
+	}
+	if r.updateGolden {
+		config = append(config, configEntry{"update golden", r.updateGolden})
+	}
+	if r.serial {
+		config = append(config, configEntry{"serial", r.serial})
+	}
+	if len(r.serialRoots) > 0 {
+		names := make([]string, 0, len(r.serialRoots))
+		for name := range r.serialRoots {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		config = append(config, configEntry{"serial specs", strings.Join(names, ", ")})
+	}
+	if r.measureAllocs {
+		config = append(config, configEntry{"measure allocations", r.measureAllocs})
+	}
+	if r.expectations {
+		config = append(config, configEntry{"record expectations", r.expectations})
+	}
+	if r.failOnIssues {
+		config = append(config, configEntry{"fail on validation issues", r.failOnIssues})
+	}
+	if r.failOnLog {
+		config = append(config, configEntry{"failing log level", r.failLogLevel})
+	}
+	if r.usedRandom {
+		config = append(config, configEntry{"random seed", r.randomSeed})
+	}
+	if r.deadline > 0 {
+		config = append(config, configEntry{"deadline", r.deadline})
+	}
+	if r.runCount != 1 {
+		config = append(config, configEntry{"runs", r.runCount})
+	}
+	if r.maxFailures > 0 {
+		config = append(config, configEntry{"max failures", r.maxFailures})
+	}
+	if r.maxDepth != defaultMaxNestingDepth {
+		config = append(config, configEntry{"max nesting depth", r.maxDepth})
+	}
+	if r.filter != nil {
+		config = append(config, configEntry{"filter", strings.Join(r.filter.specs, ", ")})
+	}
+	if r.rootOrder != AlphabeticalOrder {
+		config = append(config, configEntry{"root order", r.rootOrder})
+	}
+	if _, isDefault := r.scheduler.(parallelScheduler); !isDefault {
+		config = append(config, configEntry{"scheduler", fmt.Sprintf("%T", r.scheduler)})
+	}
+	return config
+}
+
+// Gets notified about the progress of a run.
+type runListener interface {
+	taskFinished(result *taskResult, finished int, total int)
+}
+
 // Scheduled spec execution.
 type scheduledTask struct {
-	name    string
-	closure specRoot
-	context *taskContext
+	name     string
+	closure  specRoot
+	location *Location
+	context  *taskContext
 }
 
 type specRoot func(Context)
 
-func newScheduledTask(name string, closure specRoot, context *taskContext) *scheduledTask {
-	return &scheduledTask{name, closure, context}
+// Wraps the root spec into a closure which takes no parameters. A nil root
+// spec stays nil, so that it will be reported the same way as nil nested specs.
+func rootSpecBody(closure specRoot, c Context) func() {
+	if closure == nil {
+		return nil
+	}
+	return func() { closure(c) }
+}
+
+// Sorts the tasks the same way as the ResultCollector sorts the root specs.
+type byTaskName []*scheduledTask
+
+func (a byTaskName) Len() int           { return len(a) }
+func (a byTaskName) Less(i, j int) bool { return a[i].name < a[j].name }
+func (a byTaskName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// Sorts the specs by the order in which their tasks would be executed
+// when there is only one goroutine. The specs of one task must already
+// be in the order in which they were executed.
+type byExecutionOrder []*specRun
+
+func (a byExecutionOrder) Len() int      { return len(a) }
+func (a byExecutionOrder) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byExecutionOrder) Less(i, j int) bool {
+	rootA, rootB := a[i].rootParent().name, a[j].rootParent().name
+	if rootA != rootB {
+		return rootA < rootB
+	}
+	return a[i].targetPath.isBefore(a[j].targetPath)
+}
+
+func newScheduledTask(name string, closure specRoot, location *Location, context *taskContext) *scheduledTask {
+	return &scheduledTask{name, closure, location, context}
 }
 
 // Results of a spec execution.
 type taskResult struct {
 	name           string
 	closure        specRoot
+	location       *Location
 	executedSpecs  []*specRun
 	postponedSpecs []*specRun
+	usedRandom     bool
+	duration       time.Duration
+
+	// The skipped specs which were found by the task, other than its leaf.
+	// They need no task of their own, because they are not executed.
+	skippedSpecs []*specRun
+}
+
+// Whether the leaf spec or any of its parents failed. Failures of
+// quarantined specs are not counted.
+func (result *taskResult) failed() bool {
+	for _, spec := range result.executedSpecs {
+		if spec.errors.Len() > 0 && !spec.isQuarantined() {
+			return true
+		}
+	}
+	return false
 }
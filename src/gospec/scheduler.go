@@ -0,0 +1,44 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+// Scheduler decides how the Runner executes the spec tasks. Each task
+// executes one root spec along one path in its tree of child specs, so that
+// one leaf spec and all of its parents are executed. The other child specs
+// which are found on the way are scheduled as new tasks when the task
+// has finished, until all leaf specs have been executed.
+//
+// For the results to be collected correctly, the scheduler must call Run()
+// exactly once for every task which it is given. Run() may be called in any
+// goroutine and concurrently with other tasks, either right away or later,
+// but the Runner will wait until all tasks have been run. The tasks are
+// isolated from each other only as far as the specs themselves are isolated,
+// so executing one task at a time is always safe.
+type Scheduler interface {
+	Schedule(task *SpecTask)
+}
+
+// One execution of a root spec, for Schedulers.
+type SpecTask struct {
+	// Name of the root spec.
+	RootName string
+	// Indices of the child specs on the path which will be executed.
+	// For a root spec's first execution the path is empty, in which case
+	// the first child spec on each level will be executed.
+	Path []int
+	run  func()
+}
+
+// Executes the task and reports its results to the Runner.
+func (this *SpecTask) Run() {
+	this.run()
+}
+
+// The default Scheduler, which executes every task right away in its own goroutine.
+type parallelScheduler struct{}
+
+func (this parallelScheduler) Schedule(task *SpecTask) {
+	go task.Run()
+}
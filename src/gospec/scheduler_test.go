@@ -0,0 +1,53 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sort"
+)
+
+func SchedulerSpec(c nanospec.Context) {
+	scheduler := new(recordingScheduler)
+	r := NewRunner()
+	r.SetScheduler(scheduler)
+	r.AddSpec(DummySpecWithMultipleNestedChildren)
+	r.Run()
+
+	c.Specify("The scheduler is given every task", func() {
+		sort.Strings(scheduler.tasks)
+		c.Expect(fmt.Sprint(scheduler.tasks)).Equals("[" +
+			"gospec.DummySpecWithMultipleNestedChildren [0 1] " +
+			"gospec.DummySpecWithMultipleNestedChildren [1 1] " +
+			"gospec.DummySpecWithMultipleNestedChildren [1 2] " +
+			"gospec.DummySpecWithMultipleNestedChildren [1] " +
+			"gospec.DummySpecWithMultipleNestedChildren []]")
+	})
+	c.Specify("Tasks may be executed in the goroutine which schedules them", func() {
+		c.Expect(r.Results()).Matches(ReportIs(`
+- gospec.DummySpecWithMultipleNestedChildren
+  - Child A
+    - Child AA
+    - Child AB
+  - Child B
+    - Child BA
+    - Child BB
+    - Child BC
+
+8 specs, 0 failures
+`))
+	})
+}
+
+// Executes the tasks one at a time right away, and records them
+type recordingScheduler struct {
+	tasks []string
+}
+
+func (this *recordingScheduler) Schedule(task *SpecTask) {
+	this.tasks = append(this.tasks, fmt.Sprint(task.RootName, " ", task.Path))
+	task.Run()
+}
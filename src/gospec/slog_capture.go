@@ -0,0 +1,190 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// The log records which were logged with the default slog.Logger
+// while it was replaced by Context.CaptureSlog.
+type LogCapture struct {
+	lock    sync.Mutex
+	records []LogRecord
+
+	// Where each record was logged, or nil if it is not known.
+	locations []*Location
+}
+
+// One captured log record. The attributes of groups
+// are named as "group.name".
+type LogRecord struct {
+	Level   slog.Level
+	Message string
+	Attrs   map[string]interface{}
+}
+
+func (record LogRecord) String() string {
+	s := record.Level.String() + " " + record.Message
+	keys := make([]string, 0, len(record.Attrs))
+	for key := range record.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s += fmt.Sprintf(" %v=%v", key, record.Attrs[key])
+	}
+	return s
+}
+
+// The records which have been captured so far, in the order they were logged.
+func (capture *LogCapture) Records() []LogRecord {
+	capture.lock.Lock()
+	defer capture.lock.Unlock()
+	records := make([]LogRecord, len(capture.records))
+	copy(records, capture.records)
+	return records
+}
+
+func (capture *LogCapture) add(record LogRecord) {
+	capture.addAt(record, nil)
+}
+
+func (capture *LogCapture) addAt(record LogRecord, location *Location) {
+	capture.lock.Lock()
+	defer capture.lock.Unlock()
+	capture.records = append(capture.records, record)
+	capture.locations = append(capture.locations, location)
+}
+
+// An error for every record whose level is at least 'level',
+// see Runner.SetFailOnErrorLog.
+func (capture *LogCapture) errorsAtLevel(level slog.Level) []*Error {
+	capture.lock.Lock()
+	defer capture.lock.Unlock()
+	errors := make([]*Error, 0)
+	for i, record := range capture.records {
+		if record.Level >= level {
+			message := fmt.Sprintf("logged “%v”, which fails the spec because its level is at least %v", record, level)
+			errors = append(errors, newError(OtherError, message, "", toStackTrace(capture.locations[i])))
+		}
+	}
+	return errors
+}
+
+// Captures the records of all levels.
+type captureHandler struct {
+	capture *LogCapture
+	attrs   map[string]interface{}
+	group   string
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]interface{})
+	for key, value := range h.attrs {
+		attrs[key] = value
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		addLogAttr(attrs, h.group, attr)
+		return true
+	})
+	var location *Location
+	if r.PC != 0 {
+		location = locationForPC(r.PC)
+	}
+	h.capture.addAt(LogRecord{r.Level, r.Message, attrs}, location)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	combined := make(map[string]interface{})
+	for key, value := range h.attrs {
+		combined[key] = value
+	}
+	for _, attr := range attrs {
+		addLogAttr(combined, h.group, attr)
+	}
+	return &captureHandler{h.capture, combined, h.group}
+}
+
+func (h *captureHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &captureHandler{h.capture, h.attrs, h.group + name + "."}
+}
+
+func addLogAttr(attrs map[string]interface{}, group string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		prefix := group
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			addLogAttr(attrs, prefix, member)
+		}
+		return
+	}
+	if attr.Key != "" {
+		attrs[group+attr.Key] = value.Any()
+	}
+}
+
+// The actual value must be a *LogCapture to which a record has been logged
+// that has the same level as the expected LogRecord, the same message unless
+// the expected message is empty, and all of the expected attributes, but it
+// may have also other attributes. Example:
+//    logs := c.CaptureSlog()
+//    c.Expect(logs, LoggedRecord, LogRecord{Level: slog.LevelWarn, Message: "retrying", Attrs: map[string]interface{}{"attempt": 2}})
+func LoggedRecord(actual_ interface{}, expected_ interface{}) (match bool, pos Message, neg Message, err error) {
+	capture, ok := actual_.(*LogCapture)
+	if !ok {
+		err = Errorf("type error: expected a *LogCapture, but was “%v” of type “%T”", actual_, actual_)
+		return
+	}
+	expected, ok := expected_.(LogRecord)
+	if !ok {
+		err = Errorf("type error: expected a LogRecord, but was “%v” of type “%T”", expected_, expected_)
+		return
+	}
+
+	records := capture.Records()
+	for _, record := range records {
+		if record.matches(expected) {
+			match = true
+			break
+		}
+	}
+	pos = Messagef(records, "logged a record “%v”", expected)
+	neg = Messagef(records, "did NOT log a record “%v”", expected)
+	return
+}
+
+func (record LogRecord) matches(expected LogRecord) bool {
+	if record.Level != expected.Level {
+		return false
+	}
+	if expected.Message != "" && record.Message != expected.Message {
+		return false
+	}
+	for key, value := range expected.Attrs {
+		actual, found := record.Attrs[key]
+		// The same conversion as for the logged values, so that for
+		// example an int is equal to the int64 which slog stores.
+		if !found || !areEqual(actual, slog.AnyValue(value).Any()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"log"
+	"log/slog"
+)
+
+func SlogCaptureSpec(c nanospec.Context) {
+	c.Specify("The records logged with the default logger are captured", func() {
+		var records []LogRecord
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			logs := c.CaptureSlog()
+			slog.Info("started", "port", 8080)
+			slog.With("request", "r1").WithGroup("db").Debug("query", slog.Group("stats", "rows", 3))
+			records = logs.Records()
+		})
+		r.Run()
+
+		c.Expect(len(records)).Equals(2)
+		c.Expect(records[0].String()).Equals("INFO started port=8080")
+		c.Expect(records[1].String()).Equals("DEBUG query db.stats.rows=3 request=r1")
+	})
+	c.Specify("The previous default logger is restored after the leaf spec", func() {
+		before := slog.Default()
+		var during *slog.Logger
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.CaptureSlog()
+			during = slog.Default()
+		})
+		r.Run()
+
+		c.Expect(during == before).IsFalse()
+		c.Expect(slog.Default() == before).IsTrue()
+	})
+	c.Specify("The output of the log package is restored after the leaf spec", func() {
+		writer, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+		defer func() {
+			log.SetOutput(writer)
+			log.SetFlags(flags)
+			log.SetPrefix(prefix)
+		}()
+		out := new(bytes.Buffer)
+		log.SetOutput(out)
+		log.SetFlags(log.Lmsgprefix)
+		log.SetPrefix("app: ")
+		var captured []LogRecord
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			logs := c.CaptureSlog()
+			log.Print("during")
+			captured = logs.Records()
+		})
+		r.Run()
+		log.Print("after")
+
+		c.Expect(len(captured)).Equals(1)
+		c.Expect(captured[0].String()).Equals("INFO app: during")
+		c.Expect(out.String()).Equals("app: after\n")
+		c.Expect(log.Flags()).Equals(log.Lmsgprefix)
+	})
+
+	c.Specify("When errors in the logs fail the specs", func() {
+		r := NewRunner()
+		r.SetFailOnErrorLog(true)
+
+		c.Specify("a leaf spec fails if an error was logged while capturing", func() {
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.CaptureSlog()
+				c.Specify("Child A", func() {
+					slog.Warn("slow", "ms", 300)
+				})
+				c.Specify("Child B", func() {
+					slog.Error("cannot save", "id", 3)
+				})
+			})
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportIs(`
+- RootSpec
+  - Child A
+  - Child B [FAIL]
+*** logged “ERROR cannot save id=3”, which fails the spec because its level is at least ERROR
+    at slog_capture_test.go
+
+3 specs, 1 failures
+`))
+		})
+		c.Specify("the failing level can be configured", func() {
+			r.SetFailingLogLevel(slog.LevelWarn)
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.CaptureSlog()
+				slog.Info("started")
+				slog.Warn("slow", "ms", 300)
+			})
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportContains("*** logged “WARN slow ms=300”, which fails the spec because its level is at least WARN\n"))
+			c.Expect(r.Results()).Matches(ReportContains("1 specs, 1 failures"))
+		})
+		c.Specify("the records logged by the cleanups are also checked", func() {
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.CaptureSlog()
+				c.Cleanup(func() {
+					slog.Error("cannot close")
+				})
+			})
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportContains("*** logged “ERROR cannot close”"))
+		})
+		c.Specify("the records are not checked unless asked", func() {
+			r.SetFailOnErrorLog(false)
+			r.AddNamedSpec("RootSpec", func(c Context) {
+				c.CaptureSlog()
+				slog.Error("cannot save")
+			})
+			r.Run()
+			c.Expect(r.Results()).Matches(ReportContains("1 specs, 0 failures"))
+		})
+	})
+
+	c.Specify("Matcher: LoggedRecord", func() {
+		capture := new(LogCapture)
+		capture.add(LogRecord{slog.LevelInfo, "started", map[string]interface{}{"port": int64(8080)}})
+		capture.add(LogRecord{slog.LevelWarn, "retrying", map[string]interface{}{"attempt": int64(2), "delay": "1s"}})
+
+		c.Expect(E(capture, LoggedRecord, LogRecord{Level: slog.LevelWarn, Message: "retrying"})).Matches(Passes)
+		c.Expect(E(capture, LoggedRecord, LogRecord{Level: slog.LevelWarn, Attrs: map[string]interface{}{"attempt": 2}})).Matches(Passes)
+		c.Expect(E(capture, LoggedRecord, LogRecord{Level: slog.LevelError, Message: "retrying"})).Matches(Fails)
+		c.Expect(E(capture, LoggedRecord, LogRecord{Level: slog.LevelInfo, Message: "started", Attrs: map[string]interface{}{"port": 80}})).Matches(FailsWithMessage(
+			"logged a record “INFO started port=80”",
+			"did NOT log a record “INFO started port=80”"))
+
+		c.Specify("cannot check other values", func() {
+			c.Expect(E(1, LoggedRecord, LogRecord{})).Matches(GivesError("type error: expected a *LogCapture, but was “1” of type “int”"))
+			c.Expect(E(capture, LoggedRecord, "started")).Matches(GivesError("type error: expected a LogRecord, but was “started” of type “string”"))
+		})
+	})
+}
@@ -7,6 +7,8 @@ package gospec
 import (
 	"container/list"
 	"fmt"
+	"math/rand"
+	"time"
 )
 
 // Represents a spec in a tree of specs.
@@ -19,6 +21,15 @@ type specRun struct {
 	targetPath       path
 	errors           *list.List
 	hasFatalErrors   bool
+	allocations      *Allocations
+	duration         time.Duration
+	expectations     []*Expectation
+	evaluated        int
+	location         *Location
+	quarantined      bool
+	metadata         map[string]string
+	skipReason       string
+	random           *rand.Rand
 }
 
 func newSpecRun(name string, closure func(), parent *specRun, targetPath path) *specRun {
@@ -28,7 +39,7 @@ func newSpecRun(name string, closure func(), parent *specRun, targetPath path) *
 		path = parent.path.append(currentIndex)
 		parent.numberOfChildren++
 	}
-	return &specRun{name, closure, parent, 0, path, targetPath, list.New(), false}
+	return &specRun{name, closure, parent, 0, path, targetPath, list.New(), false, nil, 0, nil, 0, nil, false, nil, "", nil}
 }
 
 func (spec *specRun) isOnTargetPath() bool { return spec.path.isOn(spec.targetPath) }
@@ -36,6 +47,10 @@ func (spec *specRun) isUnseen() bool       { return spec.path.isBeyond(spec.targ
 func (spec *specRun) isFirstChild() bool   { return spec.path.lastIndex() == 0 }
 
 func (spec *specRun) execute() {
+	if spec.closure == nil {
+		spec.AddFatalError(spec.nilBodyError())
+		return
+	}
 	exception := recoverOnPanic(spec.closure)
 	if exception != nil {
 		spec.fixupStackTraceForRootSpec(exception)
@@ -53,6 +68,19 @@ func (spec *specRun) fixupStackTraceForRootSpec(e *exception) {
 	}
 }
 
+func (spec *specRun) nilBodyError() *Error {
+	stacktrace := []*Location{}
+	if spec.location != nil {
+		stacktrace = append(stacktrace, spec.location)
+	}
+	message := fmt.Sprintf("spec '%v' has a nil body", spec.name)
+	return newError(OtherError, message, "", stacktrace)
+}
+
+func (spec *specRun) recordExpectation(expectation *Expectation) {
+	spec.expectations = append(spec.expectations, expectation)
+}
+
 func (spec *specRun) AddError(error *Error) {
 	spec.errors.PushBack(error)
 }
@@ -62,6 +90,26 @@ func (spec *specRun) AddFatalError(error *Error) {
 	spec.hasFatalErrors = true
 }
 
+func (spec *specRun) setMeta(key string, value string) {
+	if spec.metadata == nil {
+		spec.metadata = make(map[string]string)
+	}
+	spec.metadata[key] = value
+}
+
+// The metadata of this spec and its parents. The metadata
+// of a spec overrides the metadata of its parents.
+func (spec *specRun) mergedMetadata() map[string]string {
+	merged := make(map[string]string)
+	if spec.parent != nil {
+		merged = spec.parent.mergedMetadata()
+	}
+	for key, value := range spec.metadata {
+		merged[key] = value
+	}
+	return merged
+}
+
 func (spec *specRun) rootParent() *specRun {
 	root := spec
 	for root.parent != nil {
@@ -70,6 +118,25 @@ func (spec *specRun) rootParent() *specRun {
 	return root
 }
 
+// Names of the specs from the root spec to this spec.
+// Whether this spec or any of its parents was declared with
+// Context.QuarantineSpecify.
+func (spec *specRun) isQuarantined() bool {
+	for s := spec; s != nil; s = s.parent {
+		if s.quarantined {
+			return true
+		}
+	}
+	return false
+}
+
+func (spec *specRun) namePath() []string {
+	if spec.parent == nil {
+		return []string{spec.name}
+	}
+	return append(spec.parent.namePath(), spec.name)
+}
+
 func (spec *specRun) String() string {
 	return fmt.Sprintf("%T{%v @ %v}", spec, spec.name, spec.path)
 }
@@ -113,6 +180,15 @@ func (current path) isBeyond(target path) bool {
 	return target.isOn(current) && len(current) > len(target)
 }
 
+// Whether the path comes before the target path when the specs are executed.
+func (current path) isBefore(target path) bool {
+	common := commonPrefixLength(current, target)
+	if common == len(current) || common == len(target) {
+		return len(current) < len(target)
+	}
+	return current[common] < target[common]
+}
+
 func commonPrefixLength(a path, b path) int {
 	length := 0
 	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
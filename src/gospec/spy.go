@@ -0,0 +1,62 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Spy records the calls which are made to it, so that fakes can report
+// how they were used. It is safe to call from multiple goroutines.
+// The zero value is a Spy without calls, so a Spy can be created with
+// new(Spy) and its calls checked with the WasCalled matcher.
+type Spy struct {
+	lock  sync.Mutex
+	calls [][]interface{}
+}
+
+// Records a call with the given arguments.
+func (this *Spy) Call(args ...interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.calls = append(this.calls, args)
+}
+
+// The arguments of every call, in the order in which the calls were made.
+func (this *Spy) Calls() [][]interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	calls := make([][]interface{}, len(this.calls))
+	copy(calls, this.calls)
+	return calls
+}
+
+func (this *Spy) CallCount() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return len(this.calls)
+}
+
+func (this *Spy) String() string {
+	calls := this.Calls()
+	if len(calls) == 0 {
+		return "Spy with no calls"
+	}
+	args := make([]string, len(calls))
+	for i, call := range calls {
+		args[i] = formatArgs(call)
+	}
+	return fmt.Sprintf("Spy with %v calls: %v", len(calls), strings.Join(args, ", "))
+}
+
+func formatArgs(args []interface{}) string {
+	s := make([]string, len(args))
+	for i, arg := range args {
+		s[i] = fmt.Sprintf("%#v", arg)
+	}
+	return "(" + strings.Join(s, ", ") + ")"
+}
@@ -0,0 +1,40 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"sync"
+)
+
+func SpySpec(c nanospec.Context) {
+	spy := new(Spy)
+
+	c.Specify("A new spy has no calls", func() {
+		c.Expect(spy.CallCount()).Equals(0)
+		c.Expect(len(spy.Calls())).Equals(0)
+		c.Expect(spy.String()).Equals("Spy with no calls")
+	})
+	c.Specify("The spy records the arguments of every call", func() {
+		spy.Call(1, "a")
+		spy.Call()
+		c.Expect(spy.CallCount()).Equals(2)
+		c.Expect(fmt.Sprint(spy.Calls())).Equals("[[1 a] []]")
+		c.Expect(spy.String()).Equals(`Spy with 2 calls: (1, "a"), ()`)
+	})
+	c.Specify("The spy can be called from multiple goroutines", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				spy.Call()
+			}()
+		}
+		wg.Wait()
+		c.Expect(spy.CallCount()).Equals(10)
+	})
+}
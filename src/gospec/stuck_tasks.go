@@ -0,0 +1,124 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	// The stacks are captured only on a best-effort basis, so that
+	// a run with very many goroutines will not produce a huge report.
+	maxGoroutineDumpSize = 1 << 20
+	maxStuckStackSize    = 4096
+)
+
+// The goroutines which are executing the tasks, so that the stacks of
+// the tasks which are still executing at the deadline can be reported.
+type executingTasks struct {
+	lock       sync.Mutex
+	goroutines map[int64]string
+
+	// The tasks which have been given to the scheduler and have not finished.
+	running sync.WaitGroup
+}
+
+func newExecutingTasks() *executingTasks {
+	return &executingTasks{goroutines: make(map[int64]string)}
+}
+
+// Called by the goroutine which executes the task of the root spec 'name'.
+func (this *executingTasks) started(name string) (goroutine int64) {
+	goroutine = currentGoroutine()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.goroutines[goroutine] = name
+	return
+}
+
+func (this *executingTasks) finished(goroutine int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.goroutines, goroutine)
+}
+
+// Waits until all tasks which have been given to the scheduler have finished.
+func (this *executingTasks) wait() {
+	this.running.Wait()
+}
+
+// Returns an error for every task which is still executing, with the stack
+// of the goroutine which executes it, sorted by the names of the root specs.
+func (this *executingTasks) stuckErrors() []*Error {
+	this.lock.Lock()
+	goroutines := make(map[int64]string, len(this.goroutines))
+	for goroutine, name := range this.goroutines {
+		goroutines[goroutine] = name
+	}
+	this.lock.Unlock()
+	if len(goroutines) == 0 {
+		return []*Error{}
+	}
+
+	buf := make([]byte, maxGoroutineDumpSize)
+	stacks := goroutineStacks(buf[:runtime.Stack(buf, true)])
+	ids := make([]int64, 0, len(goroutines))
+	for goroutine := range goroutines {
+		ids = append(ids, goroutine)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if goroutines[ids[i]] != goroutines[ids[j]] {
+			return goroutines[ids[i]] < goroutines[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	errors := make([]*Error, 0, len(ids))
+	for _, goroutine := range ids {
+		stack, found := stacks[goroutine]
+		if !found {
+			stack = fmt.Sprintf("goroutine %v: the stack was not captured", goroutine)
+		}
+		if len(stack) > maxStuckStackSize {
+			stack = stack[:maxStuckStackSize] + "\n..."
+		}
+		message := fmt.Sprintf("a spec of %v was still executing when the run was stopped:\n%v", goroutines[goroutine], stack)
+		errors = append(errors, newError(OtherError, message, "", []*Location{}))
+	}
+	return errors
+}
+
+func currentGoroutine() int64 {
+	buf := make([]byte, 64)
+	id, _ := parseGoroutineHeader(buf[:runtime.Stack(buf, false)])
+	return id
+}
+
+// Splits the output of runtime.Stack into the stacks of each goroutine.
+// The last stack may be truncated, if the output did not fit the buffer.
+func goroutineStacks(dump []byte) map[int64]string {
+	stacks := make(map[int64]string)
+	for _, stack := range bytes.Split(dump, []byte("\n\n")) {
+		if id, ok := parseGoroutineHeader(stack); ok {
+			stacks[id] = string(bytes.TrimSpace(stack))
+		}
+	}
+	return stacks
+}
+
+// Parses the id from a header such as "goroutine 42 [chan receive]:".
+func parseGoroutineHeader(stack []byte) (id int64, ok bool) {
+	fields := bytes.Fields(stack)
+	if len(fields) < 2 || string(fields[0]) != "goroutine" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(string(fields[1]), 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,51 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"strings"
+)
+
+func StuckTasksSpec(c nanospec.Context) {
+	c.Specify("The stacks of the goroutines are split by their ids", func() {
+		stacks := goroutineStacks([]byte("" +
+			"goroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x1d\n\n" +
+			"goroutine 42 [chan receive]:\nmain.worker()\n\t/src/main.go:9 +0x2a\ncreated by main.main\n"))
+		c.Expect(len(stacks)).Equals(2)
+		c.Expect(stacks[1]).Equals("goroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x1d")
+		c.Expect(stacks[42]).Equals("goroutine 42 [chan receive]:\nmain.worker()\n\t/src/main.go:9 +0x2a\ncreated by main.main")
+	})
+	c.Specify("The current goroutine is identified", func() {
+		c.Expect(currentGoroutine() > 0).IsTrue()
+	})
+	c.Specify("Only the tasks which are still executing are reported", func() {
+		tasks := newExecutingTasks()
+		c.Expect(len(tasks.stuckErrors())).Equals(0)
+
+		tasks.finished(tasks.started("Finished"))
+		tasks.started("Stuck")
+		errors := tasks.stuckErrors()
+		c.Expect(len(errors)).Equals(1)
+		c.Expect(errors[0].Message).Satisfies(strings.HasPrefix(errors[0].Message, "a spec of Stuck was still executing when the run was stopped:\ngoroutine "))
+	})
+	c.Specify("Very long stacks are truncated", func() {
+		tasks := newExecutingTasks()
+		tasks.started("Deep")
+		deepRecursion(200, func() {
+			message := tasks.stuckErrors()[0].Message
+			c.Expect(len(message) < maxStuckStackSize+200).IsTrue()
+			c.Expect(message).Satisfies(strings.HasSuffix(message, "\n..."))
+		})
+	})
+}
+
+func deepRecursion(depth int, f func()) {
+	if depth == 0 {
+		f()
+		return
+	}
+	deepRecursion(depth-1, f)
+}
@@ -0,0 +1,60 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// The numbers of the specs by their status, and how long the run took.
+type Summary struct {
+	// All the specs, which is the sum of the passed, failed and skipped specs.
+	Specs int
+
+	Passed int
+	Failed int
+
+	// Specs which were not executed, because they were declared with
+	// Context.SpecifyIf and their condition was false.
+	Skipped int
+
+	// Failed specs which are quarantined. They are not included in Specs.
+	Quarantined int
+
+	Duration time.Duration
+}
+
+// Formats the summary as key=value pairs on one line, for example
+// "specs=8 passed=5 failed=2 skipped=1 quarantined=0 duration=1.2s",
+// so that it is easy for log processors to parse.
+func (s Summary) String() string {
+	return fmt.Sprintf("specs=%v passed=%v failed=%v skipped=%v quarantined=%v duration=%v",
+		s.Specs, s.Passed, s.Failed, s.Skipped, s.Quarantined, s.Duration.Round(time.Millisecond))
+}
+
+func (r *ResultCollector) recordDuration(duration time.Duration) {
+	r.duration = duration
+}
+
+// Returns the numbers of the specs by their status. The duration is
+// the duration of Runner.Run.
+func (r *ResultCollector) SummaryLine() Summary {
+	return Summary{
+		Specs:       r.TotalCount() + r.SkippedCount(),
+		Passed:      r.PassCount(),
+		Failed:      r.FailCount(),
+		Skipped:     r.SkippedCount(),
+		Quarantined: r.QuarantinedCount(),
+		Duration:    r.duration,
+	}
+}
+
+// Prints the summary of SummaryLine on one line. The report has also its
+// own summary for humans, but this line is meant for log processors.
+func (r *ResultCollector) PrintSummaryLine(out io.Writer) {
+	fmt.Fprintf(out, "\n%v\n", r.SummaryLine())
+}
@@ -0,0 +1,42 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+	"time"
+)
+
+func SummarySpec(c nanospec.Context) {
+	c.Specify("The summary is formatted as key=value pairs", func() {
+		summary := Summary{Specs: 8, Passed: 5, Failed: 2, Skipped: 1, Duration: 1234567 * time.Microsecond}
+		c.Expect(summary.String()).Equals("specs=8 passed=5 failed=2 skipped=1 quarantined=0 duration=1.235s")
+	})
+	c.Specify("The specs are counted by their status", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Passing", func() {})
+			c.Specify("Failing", func() { c.Expect(1, Equals, 2) })
+			c.SpecifyIf(false, "", "Skipped", func() {})
+			c.QuarantineSpecify("Quarantined", func() { c.Expect(1, Equals, 2) })
+		})
+		r.Run()
+		summary := r.Results().SummaryLine()
+
+		c.Expect(summary.Passed).Equals(2) // the root spec and its passing child
+		c.Expect(summary.Failed).Equals(1)
+		c.Expect(summary.Skipped).Equals(1)
+		c.Expect(summary.Quarantined).Equals(1)
+		c.Expect(summary.Specs).Equals(4)
+		c.Expect(summary.Duration > 0).IsTrue()
+	})
+	c.Specify("The summary is printed on one line", func() {
+		out := new(bytes.Buffer)
+		results := newResultCollector()
+		results.PrintSummaryLine(out)
+		c.Expect(out.String()).Equals("\nspecs=0 passed=0 failed=0 skipped=0 quarantined=0 duration=0s\n")
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A mistake in how the specs were written, which does not necessarily make
+// any spec fail, for example two sibling specs with the same name, whose
+// results cannot be told apart in the report. See Runner.Validate.
+type ValidationIssue struct {
+	// The names of the spec and its parents, from the root spec to the spec.
+	Names    []string
+	Message  string
+	Location *Location
+}
+
+func (issue ValidationIssue) String() string {
+	return strings.Join(issue.Names, " > ") + ": " + issue.Message
+}
+
+// Checks the specs for mistakes in how they were written. The child specs
+// are declared by executing the bodies of their parents, so most problems
+// can be found only by executing the specs:
+//
+// Before Run, only the root specs are checked, without executing them:
+//   - root specs with a nil body
+//   - root specs with the same name, which are merged in the report
+//
+// After Run, also the specs which were executed are checked:
+//   - child specs with a nil body
+//   - sibling specs with the same name, which are merged in the report
+//   - leaf specs which evaluated no expectations, which usually means that
+//     they are empty or that their children were not declared
+//
+// GoSpec has no focused specs, so skipping specs with SpecifyIf cannot
+// conflict with focusing. The skipped specs evaluate no expectations,
+// but they are not reported as issues. See also SetFailOnValidationIssues.
+func (r *Runner) Validate() []ValidationIssue {
+	issues := r.validateRoots()
+	issues = append(issues, r.validateExecuted()...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return strings.Join(issues[i].Names, " > ") < strings.Join(issues[j].Names, " > ")
+	})
+	return issues
+}
+
+// When true, Run adds every validation issue as an error of the run, so that
+// the run fails when the specs have mistakes. By default the issues are only
+// returned by Validate.
+func (r *Runner) SetFailOnValidationIssues(fail bool) {
+	r.failOnIssues = fail
+}
+
+func (r *Runner) validateRoots() []ValidationIssue {
+	roots := r.roots
+	if roots == nil {
+		roots = r.scheduled
+	}
+	issues := make([]ValidationIssue, 0)
+	declared := make(map[string][]*scheduledTask)
+	names := make([]string, 0)
+	for _, task := range roots {
+		if task.closure == nil {
+			issues = append(issues, ValidationIssue{r.formatNames([]string{task.name}), "the spec has a nil body", task.location})
+		}
+		if _, seen := declared[task.name]; !seen {
+			names = append(names, task.name)
+		}
+		declared[task.name] = append(declared[task.name], task)
+	}
+	for _, name := range names {
+		if tasks := declared[name]; len(tasks) > 1 {
+			message := fmt.Sprintf("%v root specs have the same name, so they are merged in the report", len(tasks))
+			issues = append(issues, ValidationIssue{r.formatNames([]string{name}), message, tasks[1].location})
+		}
+	}
+	return issues
+}
+
+func (r *Runner) validateExecuted() []ValidationIssue {
+	issues := make([]ValidationIssue, 0)
+	found := make(map[string]bool)
+	add := func(spec *specRun, message string) {
+		key := fmt.Sprint(spec.path, message)
+		if !found[key] {
+			found[key] = true
+			issues = append(issues, ValidationIssue{r.formatNames(spec.namePath()), message, spec.location})
+		}
+	}
+
+	// The parents are executed by many tasks, so the same spec is seen
+	// many times, but the siblings with the same name have different paths.
+	executed := make([]*specRun, len(r.executed))
+	copy(executed, r.executed)
+	sort.Stable(byExecutionOrder(executed))
+	firstSibling := make(map[string]int)
+	for _, spec := range executed {
+		if spec.closure == nil && !spec.path.isRoot() {
+			add(spec, "the spec has a nil body")
+		}
+		if spec.numberOfChildren == 0 && spec.evaluated == 0 && spec.errors.Len() == 0 &&
+			spec.closure != nil && spec.skipReason == "" {
+			add(spec, "the spec has no child specs and evaluated no expectations")
+		}
+		if spec.parent != nil {
+			key := fmt.Sprint(spec.parent.path, spec.name)
+			if first, seen := firstSibling[key]; !seen {
+				firstSibling[key] = spec.path.lastIndex()
+			} else if first != spec.path.lastIndex() {
+				add(spec, "an earlier sibling spec has the same name, so they are merged in the report")
+			}
+		}
+	}
+	return issues
+}
+
+func (r *Runner) formatNames(names []string) []string {
+	formatted := make([]string, len(names))
+	for i, name := range names {
+		formatted[i] = r.nameFormatter(name)
+	}
+	return formatted
+}
+
+func (r *Runner) addValidationIssues() {
+	for _, issue := range r.Validate() {
+		r.runErrors = append(r.runErrors, newError(OtherError, "validation issue: "+issue.String(), "", toStackTrace(issue.Location)))
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func ValidationSpec(c nanospec.Context) {
+	r := NewRunner()
+
+	issueStrings := func() []string {
+		strings := make([]string, 0)
+		for _, issue := range r.Validate() {
+			strings = append(strings, issue.String())
+		}
+		return strings
+	}
+
+	c.Specify("Specs without mistakes have no issues", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 1)
+			})
+			c.SpecifyIf(false, "", "Skipped", func() {})
+		})
+		r.Run()
+		c.Expect(len(r.Validate())).Equals(0)
+	})
+
+	c.Specify("Before Run, the root specs are checked without executing them", func() {
+		executed := false
+		r.AddNamedSpec("RootSpec", func(c Context) { executed = true })
+		r.AddNamedSpec("RootSpec", func(c Context) { executed = true })
+		r.AddNamedSpec("NilSpec", nil)
+		c.Expect(issueStrings()).Equals([]string{
+			"NilSpec: the spec has a nil body",
+			"RootSpec: 2 root specs have the same name, so they are merged in the report",
+		})
+		c.Expect(executed).IsFalse()
+
+		c.Specify("and they are still checked after Run", func() {
+			r.Run()
+			c.Expect(issueStrings()).Equals([]string{
+				"NilSpec: the spec has a nil body",
+				"RootSpec: 2 root specs have the same name, so they are merged in the report",
+				"RootSpec: the spec has no child specs and evaluated no expectations",
+			})
+		})
+	})
+
+	c.Specify("After Run, also the executed specs are checked", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Expect(1, Equals, 1)
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 1)
+			})
+			c.Specify("Child A", func() {
+				c.Expect(1, Equals, 1)
+			})
+			c.Specify("Empty", func() {})
+			c.Specify("Nil", nil)
+		})
+		r.Run()
+		c.Expect(issueStrings()).Equals([]string{
+			"RootSpec > Child A: an earlier sibling spec has the same name, so they are merged in the report",
+			"RootSpec > Empty: the spec has no child specs and evaluated no expectations",
+			"RootSpec > Nil: the spec has a nil body",
+		})
+
+		c.Specify("with the locations of the specs", func() {
+			issue := r.Validate()[1]
+			c.Expect(issue.Location.FileName()).Equals("validation_test.go")
+		})
+	})
+
+	c.Specify("The issues can be made to fail the run", func() {
+		r.SetFailOnValidationIssues(true)
+		r.AddNamedSpec("RootSpec", func(c Context) {})
+		r.Run()
+		errors := r.Results().RunErrors()
+		c.Expect(len(errors)).Equals(1)
+		c.Expect(errors[0].Message).Equals("validation issue: RootSpec: the spec has no child specs and evaluated no expectations")
+	})
+	c.Specify("By default the issues do not fail the run", func() {
+		r.AddNamedSpec("RootSpec", func(c Context) {})
+		r.Run()
+		c.Expect(len(r.Results().RunErrors())).Equals(0)
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorReset  = "\x1b[0m"
+)
+
+// PrintFormat for running the specs again and again, for example by a file
+// watcher after every change. Instead of the report, only a status with the
+// number of failures and the first failure is printed, for example
+// "FAIL 2/8 — RootSpec/Child A: Expected: equals “2”" and on the next line
+// where it failed, or "PASS 8/8" when all specs passed. When 'out' is
+// a terminal, the screen is cleared before the status and the status
+// is colored, so that it is always at the top of the screen.
+func WatchPrintFormat(out io.Writer) PrintFormat {
+	return &watchPrintFormat{out: out, terminal: isTerminal(out)}
+}
+
+type watchPrintFormat struct {
+	out      io.Writer
+	terminal bool
+
+	// The names of the parents of the next spec,
+	// and the first failure of the report.
+	names        []string
+	firstFailure string
+	firstError   *Error
+}
+
+func (this *watchPrintFormat) PrintPassing(nestingLevel int, name string) {
+	this.enter(nestingLevel, name)
+}
+
+func (this *watchPrintFormat) PrintFailing(nestingLevel int, name string, errors []*Error) {
+	this.enter(nestingLevel, name)
+	if this.firstError == nil && len(errors) > 0 {
+		this.firstFailure = strings.Join(this.names, "/")
+		this.firstError = errors[0]
+	}
+}
+
+func (this *watchPrintFormat) enter(nestingLevel int, name string) {
+	if nestingLevel > len(this.names) {
+		nestingLevel = len(this.names)
+	}
+	this.names = append(this.names[:nestingLevel], name)
+}
+
+func (this *watchPrintFormat) PrintSummary(passCount int, failCount int) {
+	this.printSummary(passCount, failCount, failCount > 0)
+}
+
+// The errors of the whole run fail the run also when no spec failed.
+func (this *watchPrintFormat) PrintSummaryWithRunErrors(passCount int, failCount int, runErrorCount int) {
+	this.printSummary(passCount, failCount, true)
+}
+
+func (this *watchPrintFormat) printSummary(passCount int, failCount int, failed bool) {
+	totalCount := passCount + failCount
+	if this.terminal {
+		fmt.Fprint(this.out, clearScreen)
+	}
+	if !failed || this.firstError == nil {
+		fmt.Fprintf(this.out, "%v %v/%v\n", this.colored(colorGreen, "PASS"), passCount, totalCount)
+	} else {
+		message := strings.TrimPrefix(firstLine(formatErrorMessage(this.firstError)), "*** ")
+		fmt.Fprintf(this.out, "%v %v/%v — %v: %v\n", this.colored(colorRed, "FAIL"), failCount, totalCount, this.firstFailure, message)
+		if len(this.firstError.StackTrace) > 0 {
+			loc := this.firstError.StackTrace[0]
+			fmt.Fprintf(this.out, "    at %v:%v\n", loc.File(), loc.Line())
+		}
+	}
+	this.names = nil
+	this.firstFailure = ""
+	this.firstError = nil
+}
+
+func (this *watchPrintFormat) colored(color string, s string) string {
+	if !this.terminal {
+		return s
+	}
+	return color + s + colorReset
+}
@@ -0,0 +1,68 @@
+// Copyright © 2009-2011 Esko Luontola <www.orfjackal.net>
+// This software is released under the Apache License 2.0.
+// The license text is at http://www.apache.org/licenses/LICENSE-2.0
+
+package gospec
+
+import (
+	"bytes"
+	"github.com/orfjackal/nanospec.go/src/nanospec"
+)
+
+func WatchFormatSpec(c nanospec.Context) {
+	out := new(bytes.Buffer)
+	format := WatchPrintFormat(out)
+	location := &Location{"gospec.SomeSpec", "/work/src/some_test.go", 12}
+	failure := newError(ExpectFailed, "equals “2”", "1", []*Location{location})
+
+	c.Specify("When all specs pass, only the number of specs is printed", func() {
+		format.PrintPassing(0, "RootSpec")
+		format.PrintPassing(1, "Child A")
+		format.PrintSummary(2, 0)
+		c.Expect(out.String()).Equals("PASS 2/2\n")
+	})
+	c.Specify("When some specs fail, the first failure is printed with its location", func() {
+		format.PrintPassing(0, "RootSpec")
+		format.PrintPassing(1, "Child A")
+		format.PrintFailing(2, "Child AA", []*Error{failure})
+		format.PrintFailing(1, "Child B", []*Error{failure})
+		format.PrintSummary(2, 2)
+		c.Expect(out.String()).Equals("" +
+			"FAIL 2/4 — RootSpec/Child A/Child AA: Expected: equals “2”\n" +
+			"    at /work/src/some_test.go:12\n")
+	})
+	c.Specify("Errors without a stack trace are printed on one line", func() {
+		format.PrintFailing(0, "Run", []*Error{newError(OtherError, "too few specs\nmore details", "", []*Location{})})
+		format.PrintSummary(0, 1)
+		c.Expect(out.String()).Equals("FAIL 1/1 — Run: too few specs\n")
+	})
+	c.Specify("When only the run has errors, the run fails", func() {
+		format.PrintPassing(0, "RootSpec")
+		format.PrintFailing(0, "Run", []*Error{newError(OtherError, "too few specs", "", []*Location{})})
+		format.(RunErrorSummaryFormat).PrintSummaryWithRunErrors(1, 0, 1)
+		c.Expect(out.String()).Equals("FAIL 0/1 — Run: too few specs\n")
+	})
+	c.Specify("Every report starts from scratch", func() {
+		format.PrintFailing(0, "RootSpec", []*Error{failure})
+		format.PrintSummary(0, 1)
+		out.Reset()
+		format.PrintPassing(0, "OtherSpec")
+		format.PrintSummary(1, 0)
+		c.Expect(out.String()).Equals("PASS 1/1\n")
+	})
+	c.Specify("The report of a run is printed with a Printer", func() {
+		r := NewRunner()
+		r.AddNamedSpec("RootSpec", func(c Context) {
+			c.Specify("Child A", func() {})
+			c.Specify("Child B", func() {
+				c.Expect(1, Equals, 2)
+			})
+		})
+		r.Run()
+		printer := NewPrinter(format)
+		printer.ShowOnlyFailing()
+		printer.ShowSummary()
+		r.Results().Visit(printer)
+		c.Expect(out.String()).Satisfies(bytes.HasPrefix(out.Bytes(), []byte("FAIL 1/3 — RootSpec/Child B: Expected: equals “2”\n    at ")))
+	})
+}